@@ -64,6 +64,11 @@ type NodeLocator struct {
 	numReps int
 	// the format used to name the nodes in Ketama, either SpyMemcached or LibMemcached
 	nodeKeyFormatter *KetamaNodeKeyFormatter
+
+	// consistent hashing with bounded loads, disabled if loadFactor is not greater than 1
+	// see https://arxiv.org/abs/1608.01350
+	loadFactor float64
+	loadByNode map[string]int64 // <Node.String(), load>
 }
 
 // New creates a hash ring of n replicas for each entry.
@@ -75,6 +80,7 @@ func New(opts ...NodeLocatorOption) *NodeLocator {
 		weightByNode:     make(map[Node]int),
 		numReps:          defaultNumReps,
 		nodeKeyFormatter: NewKetamaNodeKeyFormatter(SpyMemcached),
+		loadByNode:       make(map[string]int64),
 	}
 	r.ApplyOptions(opts...)
 	return r
@@ -345,6 +351,10 @@ func (c *NodeLocator) Get(name string) (Node, bool) {
 	if len(c.nodeByKey) == 0 {
 		return nil, false
 	}
+	if c.isBounded() {
+		nodes := c.getBoundedN(name, 1)
+		return nodes[0], true
+	}
 	return c.GetPrimaryNode(name)
 }
 
@@ -353,6 +363,13 @@ func (c *NodeLocator) GetTwo(name string) (Node, Node, bool) {
 	if len(c.getNodeByKey()) == 0 {
 		return nil, nil, false
 	}
+	if c.isBounded() {
+		nodes := c.getBoundedN(name, 2)
+		if len(nodes) == 1 {
+			return nodes[0], nil, true
+		}
+		return nodes[0], nodes[1], true
+	}
 	key := c.getHashKey(name)
 	firstKey, found := c.tailSearch(key)
 	if !found {
@@ -383,6 +400,9 @@ func (c *NodeLocator) GetN(name string, n int) ([]Node, bool) {
 	if len(c.getNodeByKey()) == 0 {
 		return nil, false
 	}
+	if c.isBounded() {
+		return c.getBoundedN(name, n), true
+	}
 
 	if len(c.getNodeByKey()) < n {
 		n = len(c.getNodeByKey())
@@ -28,3 +28,13 @@ func WithWeights(weights map[Node]int) NodeLocatorOption {
 		l.isWeighted = len(weights) > 0
 	})
 }
+
+// WithBoundedLoad enables consistent hashing with bounded loads, see https://arxiv.org/abs/1608.01350
+// Get, GetTwo and GetN skip nodes whose load, reported by AddLoad or SetLoad, would exceed
+// ceil(factor * average load) once the key is placed.
+// factor must be greater than 1, such as 1.25; bounded loads are disabled otherwise.
+func WithBoundedLoad(factor float64) NodeLocatorOption {
+	return KetamaNodeLocatorOptionFunc(func(l *NodeLocator) {
+		l.loadFactor = factor
+	})
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hashring
+
+import "math"
+
+// AddLoad adds delta to the load of node, such as 1 when a request is dispatched to node,
+// and -1 when the request is done.
+// Loads are taken into account by Get, GetTwo and GetN if WithBoundedLoad is set.
+func (c *NodeLocator) AddLoad(node Node, delta int64) {
+	c.SetLoad(node, c.GetLoad(node)+delta)
+}
+
+// SetLoad sets the load of node, such as connections or requests in flight.
+// Loads are taken into account by Get, GetTwo and GetN if WithBoundedLoad is set.
+func (c *NodeLocator) SetLoad(node Node, load int64) {
+	if load < 0 {
+		load = 0
+	}
+	if load == 0 {
+		delete(c.loadByNode, node.String())
+		return
+	}
+	c.loadByNode[node.String()] = load
+}
+
+// GetLoad returns the load of node.
+func (c *NodeLocator) GetLoad(node Node) int64 {
+	return c.loadByNode[node.String()]
+}
+
+// GetLoads returns the load of all nodes with load, keyed by Node.String().
+func (c *NodeLocator) GetLoads() map[string]int64 {
+	loads := make(map[string]int64, len(c.loadByNode))
+	for node, load := range c.loadByNode {
+		loads[node] = load
+	}
+	return loads
+}
+
+// MaxLoad returns the maximum load a node can take before being skipped, that is
+// ceil(factor * (total load + 1) / nodes), the one is for the key to be placed.
+// MaxLoad returns 0 if bounded loads are disabled or there are no nodes.
+func (c *NodeLocator) MaxLoad() int64 {
+	if !c.isBounded() || len(c.allNodes) == 0 {
+		return 0
+	}
+	var total int64
+	// loads of removed nodes are not counted
+	for node := range c.allNodes {
+		total += c.loadByNode[node.String()]
+	}
+	avg := float64(total+1) / float64(len(c.allNodes))
+	return int64(math.Ceil(avg * c.loadFactor))
+}
+
+func (c *NodeLocator) isBounded() bool {
+	return c.loadFactor > 1
+}
+
+// getBoundedN returns the N closest distinct nodes to the name input, walking the continuum clockwise
+// and skipping nodes over MaxLoad. If less than n nodes are under MaxLoad, the skipped nodes
+// are appended in continuum order.
+func (c *NodeLocator) getBoundedN(name string, n int) []Node {
+	if n > len(c.allNodes) {
+		n = len(c.allNodes)
+	}
+	maxLoad := c.MaxLoad()
+
+	firstKey, found := c.tailSearch(c.getHashKey(name))
+	if !found {
+		firstKey = 0
+	}
+
+	nodes := make([]Node, 0, n)
+	var overloaded []Node
+	seen := make(map[string]struct{}, n)
+	for i := 0; i < len(c.sortedKeys) && len(nodes) < n; i++ {
+		node := c.getNodeByKey()[c.sortedKeys[(firstKey+i)%len(c.sortedKeys)]]
+		if _, has := seen[node.String()]; has {
+			continue
+		}
+		seen[node.String()] = struct{}{}
+		if c.loadByNode[node.String()]+1 > maxLoad {
+			overloaded = append(overloaded, node)
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	for _, node := range overloaded {
+		if len(nodes) >= n {
+			break
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
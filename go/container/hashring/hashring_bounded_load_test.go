@@ -0,0 +1,68 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hashring
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestBoundedLoad(t *testing.T) {
+	x := New(WithBoundedLoad(1.25))
+	nodes := []Node{StringNode("a"), StringNode("b"), StringNode("c"), StringNode("d")}
+	x.AddNodes(nodes...)
+
+	// every key is placed and never released, as hot keys do
+	for i := 0; i < 1000; i++ {
+		node, ok := x.Get("hot-" + strconv.Itoa(i%7))
+		if !ok {
+			t.Fatalf("Get() = _, false")
+		}
+		maxLoad := x.MaxLoad()
+		if load := x.GetLoad(node) + 1; load > maxLoad {
+			t.Fatalf("#%d: load of %s = %d, want <= %d", i, node, load, maxLoad)
+		}
+		x.AddLoad(node, 1)
+	}
+	for _, node := range nodes {
+		if load := x.GetLoad(node); load > 313 {
+			t.Errorf("load of %s = %d, want <= %d", node, load, 313)
+		}
+	}
+}
+
+func TestBoundedLoad_SkipOverloaded(t *testing.T) {
+	x := New(WithBoundedLoad(1.25))
+	x.AddNodes(StringNode("a"), StringNode("b"), StringNode("c"))
+	unbounded := New()
+	unbounded.AddNodes(StringNode("a"), StringNode("b"), StringNode("c"))
+
+	name := "Alice"
+	primary, _ := unbounded.Get(name)
+	if node, _ := x.Get(name); node.String() != primary.String() {
+		t.Fatalf("Get() without load = %s, want %s", node, primary)
+	}
+	x.SetLoad(primary, 10)
+	node, _ := x.Get(name)
+	if node.String() == primary.String() {
+		t.Fatalf("Get() = %s, overloaded node is not skipped", node)
+	}
+	if second, _, _ := unbounded.GetTwo(name); second.String() == node.String() {
+		t.Fatalf("Get() = %s, want %s, the next node in continuum", node, second)
+	}
+
+	nodes, ok := x.GetN(name, 3)
+	if !ok || len(nodes) != 3 {
+		t.Fatalf("GetN() = %v, %t, want 3 nodes", nodes, ok)
+	}
+	if nodes[2].String() != primary.String() {
+		t.Errorf("GetN()[2] = %s, overloaded node %s should be the last resort", nodes[2], primary)
+	}
+
+	x.AddLoad(primary, -10)
+	if node, _ := x.Get(name); node.String() != primary.String() {
+		t.Fatalf("Get() after load released = %s, want %s", node, primary)
+	}
+}
@@ -70,6 +70,21 @@ func (c *StringNodeLocator) GetN(name string, n int) ([]string, bool) {
 	return nodesToStrings(nodes...), has
 }
 
+// AddLoad adds delta to the load of node, see NodeLocator.AddLoad.
+func (c *StringNodeLocator) AddLoad(node string, delta int64) {
+	c.nl.AddLoad(StringNode(node), delta)
+}
+
+// SetLoad sets the load of node, see NodeLocator.SetLoad.
+func (c *StringNodeLocator) SetLoad(node string, load int64) {
+	c.nl.SetLoad(StringNode(node), load)
+}
+
+// GetLoad returns the load of node.
+func (c *StringNodeLocator) GetLoad(node string) int64 {
+	return c.nl.GetLoad(StringNode(node))
+}
+
 func stringsToNodes(nodes ...string) []Node {
 	var _nodes []Node
 	for _, node := range nodes {
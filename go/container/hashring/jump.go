@@ -0,0 +1,101 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hashring
+
+// JumpHash returns the bucket in [0, numBuckets) key is hashed to, -1 if numBuckets is not positive.
+// see "A Fast, Minimal Memory, Consistent Hash Algorithm", https://arxiv.org/abs/1406.2294
+func JumpHash(key uint64, numBuckets int) int {
+	if numBuckets <= 0 {
+		return -1
+	}
+	var b, j int64 = -1, 0
+	for j < int64(numBuckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// JumpNodeLocator locates nodes by Jump Consistent Hash.
+// It needs no memory for virtual nodes and spreads names evenly, but nodes are buckets numbered in order of addition,
+// so only appending or removing the last node moves the minimum of names;
+// removing a node in the middle renumbers the nodes after it.
+type JumpNodeLocator struct {
+	nodes []Node
+}
+
+// NewJumpNodeLocator returns a JumpNodeLocator with nodes as buckets.
+func NewJumpNodeLocator(nodes ...Node) *JumpNodeLocator {
+	c := &JumpNodeLocator{}
+	c.AddNodes(nodes...)
+	return c
+}
+
+// GetAllNodes returns all available nodes, in order of buckets
+func (c *JumpNodeLocator) GetAllNodes() []Node {
+	return append([]Node(nil), c.nodes...)
+}
+
+// SetNodes setups the JumpNodeLocator with the list of nodes it should use.
+// Existing nodes keep their buckets, nodes not present in nodes are removed, and new nodes are appended.
+func (c *JumpNodeLocator) SetNodes(nodes ...Node) {
+	var kept []Node
+	for _, node := range c.nodes {
+		if indexNode(nodes, node) >= 0 {
+			kept = append(kept, node)
+		}
+	}
+	c.nodes = kept
+	c.AddNodes(nodes...)
+}
+
+// AddNodes appends nodes as new buckets, nodes already present are ignored.
+func (c *JumpNodeLocator) AddNodes(nodes ...Node) {
+	for _, node := range nodes {
+		if indexNode(c.nodes, node) >= 0 {
+			continue
+		}
+		c.nodes = append(c.nodes, node)
+	}
+}
+
+// RemoveNodes removes nodes, the nodes after them are renumbered.
+func (c *JumpNodeLocator) RemoveNodes(nodes ...Node) {
+	for _, node := range nodes {
+		if i := indexNode(c.nodes, node); i >= 0 {
+			c.nodes = append(c.nodes[:i], c.nodes[i+1:]...)
+		}
+	}
+}
+
+// RemoveAllNodes removes all nodes.
+func (c *JumpNodeLocator) RemoveAllNodes() {
+	c.nodes = nil
+}
+
+// Get returns the node name hashes to.
+func (c *JumpNodeLocator) Get(name string) (Node, bool) {
+	if len(c.nodes) == 0 {
+		return nil, false
+	}
+	return c.nodes[JumpHash(hash64(name), len(c.nodes))], true
+}
+
+// GetN returns the node name hashes to, followed by the N-1 nodes of next buckets.
+func (c *JumpNodeLocator) GetN(name string, n int) ([]Node, bool) {
+	if len(c.nodes) == 0 {
+		return nil, false
+	}
+	if n > len(c.nodes) {
+		n = len(c.nodes)
+	}
+	first := JumpHash(hash64(name), len(c.nodes))
+	nodes := make([]Node, 0, n)
+	for i := 0; i < n; i++ {
+		nodes = append(nodes, c.nodes[(first+i)%len(c.nodes)])
+	}
+	return nodes, true
+}
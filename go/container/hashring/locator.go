@@ -0,0 +1,63 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hashring
+
+import "hash/fnv"
+
+// Locator locates nodes for names,
+// implemented by NodeLocator, JumpNodeLocator and RendezvousNodeLocator.
+type Locator interface {
+	// GetAllNodes returns all available nodes
+	GetAllNodes() []Node
+	// SetNodes setups the Locator with the list of nodes it should use.
+	SetNodes(nodes ...Node)
+	// AddNodes inserts nodes into the Locator.
+	AddNodes(nodes ...Node)
+	// RemoveNodes removes nodes from the Locator.
+	RemoveNodes(nodes ...Node)
+	// RemoveAllNodes removes all nodes from the Locator.
+	RemoveAllNodes()
+	// Get returns the node name is located to.
+	Get(name string) (Node, bool)
+	// GetN returns the N distinct nodes name is located to, in order of preference.
+	GetN(name string, n int) ([]Node, bool)
+}
+
+var (
+	_ Locator = (*NodeLocator)(nil)
+	_ Locator = (*JumpNodeLocator)(nil)
+	_ Locator = (*RendezvousNodeLocator)(nil)
+)
+
+// WeightedNode is a Node with a weight, honored by RendezvousNodeLocator.
+type WeightedNode interface {
+	Node
+	// Weight returns the relative weight of the node, nodes are weighted 1 by default.
+	Weight() int
+}
+
+// hash64 returns the 64-bit FNV-1a hash of k, avalanched by the finalizer of MurmurHash3,
+// as FNV alone spreads keys with common prefixes poorly.
+func hash64(k string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(k))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// indexNode returns the index of the node with the same String() as node in nodes, -1 if not found.
+func indexNode(nodes []Node, node Node) int {
+	for i, n := range nodes {
+		if n.String() == node.String() {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hashring_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/searKing/golang/go/container/hashring"
+)
+
+type weightedNode struct {
+	name   string
+	weight int
+}
+
+func (n weightedNode) String() string { return n.name }
+func (n weightedNode) Weight() int    { return n.weight }
+
+func TestJumpHash(t *testing.T) {
+	// vectors from the reference implementation
+	tests := []struct {
+		key     uint64
+		buckets int
+		want    int
+	}{
+		{0, 1, 0},
+		{1, 1, 0},
+		{0xDEAD10CC, 1, 0},
+		{0xDEAD10CC, 666, 361},
+		{256, 1024, 520},
+		{0, 0, -1},
+	}
+	for _, tt := range tests {
+		if got := hashring.JumpHash(tt.key, tt.buckets); got != tt.want {
+			t.Errorf("JumpHash(%d, %d) = %d, want %d", tt.key, tt.buckets, got, tt.want)
+		}
+	}
+}
+
+func testLocatorMinimalMove(t *testing.T, newLocator func(nodes ...hashring.Node) hashring.Locator) {
+	var nodes []hashring.Node
+	for i := 0; i < 10; i++ {
+		nodes = append(nodes, hashring.StringNode("node-"+strconv.Itoa(i)))
+	}
+	c := newLocator(nodes...)
+	before := make(map[string]string)
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		name := "key-" + strconv.Itoa(i)
+		node, ok := c.Get(name)
+		if !ok {
+			t.Fatalf("Get(%q) = _, false", name)
+		}
+		before[name] = node.String()
+		counts[node.String()]++
+	}
+	for node, count := range counts {
+		if count < 700 || count > 1300 {
+			t.Errorf("%s is located %d names, want about 1000", node, count)
+		}
+	}
+
+	c.AddNodes(hashring.StringNode("node-10"))
+	var moved int
+	for name, was := range before {
+		node, _ := c.Get(name)
+		if node.String() == was {
+			continue
+		}
+		moved++
+		if node.String() != "node-10" {
+			t.Fatalf("%s moved from %s to %s, want to the new node", name, was, node)
+		}
+	}
+	if moved < 500 || moved > 1400 {
+		t.Errorf("%d names moved, want about 10000/11", moved)
+	}
+
+	ns, ok := c.GetN("key-0", 3)
+	if !ok || len(ns) != 3 {
+		t.Fatalf("GetN() = %v, %t, want 3 nodes", ns, ok)
+	}
+	if first, _ := c.Get("key-0"); ns[0].String() != first.String() {
+		t.Errorf("GetN()[0] = %s, want %s", ns[0], first)
+	}
+	seen := map[string]bool{}
+	for _, n := range ns {
+		if seen[n.String()] {
+			t.Errorf("GetN() = %v, duplicated %s", ns, n)
+		}
+		seen[n.String()] = true
+	}
+
+	c.RemoveAllNodes()
+	if _, ok := c.Get("key-0"); ok {
+		t.Errorf("Get() on empty locator ok = true, want false")
+	}
+}
+
+func TestJumpNodeLocator(t *testing.T) {
+	testLocatorMinimalMove(t, func(nodes ...hashring.Node) hashring.Locator {
+		return hashring.NewJumpNodeLocator(nodes...)
+	})
+}
+
+func TestRendezvousNodeLocator(t *testing.T) {
+	testLocatorMinimalMove(t, func(nodes ...hashring.Node) hashring.Locator {
+		return hashring.NewRendezvousNodeLocator(nodes...)
+	})
+}
+
+func TestRendezvousNodeLocator_Weight(t *testing.T) {
+	c := hashring.NewRendezvousNodeLocator(weightedNode{"light", 1}, weightedNode{"heavy", 3})
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		node, _ := c.Get("key-" + strconv.Itoa(i))
+		counts[node.String()]++
+	}
+	if ratio := float64(counts["heavy"]) / float64(counts["light"]); ratio < 2.5 || ratio > 3.5 {
+		t.Errorf("heavy/light = %d/%d, want about 3", counts["heavy"], counts["light"])
+	}
+}
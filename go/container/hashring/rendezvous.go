@@ -0,0 +1,121 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hashring
+
+import (
+	"math"
+	"sort"
+)
+
+// RendezvousNodeLocator locates nodes by Rendezvous hashing, a.k.a. Highest Random Weight (HRW) hashing.
+// Every node is scored for a name, and the name is located to the node with the highest score,
+// so adding or removing a node only moves the names located to it.
+// Nodes implementing WeightedNode are weighted by the logarithmic method,
+// see https://en.wikipedia.org/wiki/Rendezvous_hashing#Weighted_rendezvous_hash
+//
+// Get and GetN cost O(nodes) per lookup.
+type RendezvousNodeLocator struct {
+	nodes []Node
+}
+
+// NewRendezvousNodeLocator returns a RendezvousNodeLocator with nodes.
+func NewRendezvousNodeLocator(nodes ...Node) *RendezvousNodeLocator {
+	c := &RendezvousNodeLocator{}
+	c.AddNodes(nodes...)
+	return c
+}
+
+// GetAllNodes returns all available nodes
+func (c *RendezvousNodeLocator) GetAllNodes() []Node {
+	return append([]Node(nil), c.nodes...)
+}
+
+// SetNodes setups the RendezvousNodeLocator with the list of nodes it should use.
+func (c *RendezvousNodeLocator) SetNodes(nodes ...Node) {
+	c.RemoveAllNodes()
+	c.AddNodes(nodes...)
+}
+
+// AddNodes inserts nodes, nodes already present are replaced, as weights may change.
+func (c *RendezvousNodeLocator) AddNodes(nodes ...Node) {
+	for _, node := range nodes {
+		if i := indexNode(c.nodes, node); i >= 0 {
+			c.nodes[i] = node
+			continue
+		}
+		c.nodes = append(c.nodes, node)
+	}
+}
+
+// RemoveNodes removes nodes.
+func (c *RendezvousNodeLocator) RemoveNodes(nodes ...Node) {
+	for _, node := range nodes {
+		if i := indexNode(c.nodes, node); i >= 0 {
+			c.nodes = append(c.nodes[:i], c.nodes[i+1:]...)
+		}
+	}
+}
+
+// RemoveAllNodes removes all nodes.
+func (c *RendezvousNodeLocator) RemoveAllNodes() {
+	c.nodes = nil
+}
+
+// Get returns the node with the highest score for name.
+func (c *RendezvousNodeLocator) Get(name string) (Node, bool) {
+	var best Node
+	var bestScore float64
+	for _, node := range c.nodes {
+		score := rendezvousScore(node, name)
+		if best == nil || score > bestScore || (score == bestScore && node.String() < best.String()) {
+			best, bestScore = node, score
+		}
+	}
+	return best, best != nil
+}
+
+// GetN returns the N nodes with the highest scores for name, in descending order of scores.
+func (c *RendezvousNodeLocator) GetN(name string, n int) ([]Node, bool) {
+	if len(c.nodes) == 0 {
+		return nil, false
+	}
+	type scoredNode struct {
+		node  Node
+		score float64
+	}
+	scored := make([]scoredNode, 0, len(c.nodes))
+	for _, node := range c.nodes {
+		scored = append(scored, scoredNode{node: node, score: rendezvousScore(node, name)})
+	}
+	sort.Slice(scored, func(i, j int) bool {
+		if scored[i].score != scored[j].score {
+			return scored[i].score > scored[j].score
+		}
+		return scored[i].node.String() < scored[j].node.String()
+	})
+	if n > len(scored) {
+		n = len(scored)
+	}
+	nodes := make([]Node, 0, n)
+	for _, s := range scored[:n] {
+		nodes = append(nodes, s.node)
+	}
+	return nodes, true
+}
+
+// rendezvousScore returns the score of node for name, -weight/ln(h) with h the hash mapped into (0, 1).
+func rendezvousScore(node Node, name string) float64 {
+	weight := 1
+	if w, ok := node.(WeightedNode); ok {
+		weight = w.Weight()
+	}
+	if weight <= 0 {
+		return math.Inf(-1)
+	}
+	h := hash64(node.String() + "-" + name)
+	// map h into (0, 1), 53 bits for the mantissa of float64
+	f := (float64(h>>11) + 0.5) / float64(uint64(1)<<53)
+	return -float64(weight) / math.Log(f)
+}
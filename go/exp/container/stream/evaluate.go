@@ -0,0 +1,95 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stream
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/searKing/golang/go/util/function/consumer"
+	"github.com/searKing/golang/go/util/spliterator"
+)
+
+// leafTargetPerProc is the default target of leaf tasks per processor for parallel decomposition.
+// To allow load balancing, we over-partition, currently to approximately
+// four tasks per processor, which enables others to help out
+// if leaf tasks are uneven or some processors are otherwise busy.
+const leafTargetPerProc = 4
+
+// evaluate runs the pipeline of s, accumulating the elements into containers made by supplier.
+// accumulator returns false to stop the traversal of the leaf it runs on.
+// Containers of leaves are merged by combiner in encounter order.
+func evaluate[T, A any](s *Stream[T], supplier func() A,
+	accumulator func(A, T) (A, bool), combiner func(A, A) A) A {
+	split := s.source()
+	if !s.parallel {
+		return evaluateLeaf(s, split, supplier, accumulator)
+	}
+
+	leaves := splitLeaves(split, targetSize(split))
+	results := make([]A, len(leaves))
+	panics := make([]any, len(leaves))
+	var wg sync.WaitGroup
+	for i, leaf := range leaves {
+		i, leaf := i, leaf
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { panics[i] = recover() }()
+			results[i] = evaluateLeaf(s, leaf, supplier, accumulator)
+		}()
+	}
+	wg.Wait()
+	for _, p := range panics {
+		if p != nil {
+			panic(p)
+		}
+	}
+
+	result := results[0]
+	for _, r := range results[1:] {
+		result = combiner(result, r)
+	}
+	return result
+}
+
+// evaluateLeaf runs the pipeline of s sequentially over the elements traversed by split.
+func evaluateLeaf[T, A any](s *Stream[T], split spliterator.Spliterator,
+	supplier func() A, accumulator func(A, T) (A, bool)) A {
+	acc := supplier()
+	more := true
+	sink := s.wrap(func(t T) bool {
+		acc, more = accumulator(acc, t)
+		return more
+	})
+	action := consumer.ConsumerFunc(func(v any) { more = sink(v) })
+	for more && split.TryAdvance(context.Background(), action) {
+	}
+	return acc
+}
+
+// targetSize returns the size of elements under which a spliterator is not split further.
+func targetSize(split spliterator.Spliterator) int {
+	size := split.EstimateSize() / (runtime.GOMAXPROCS(0) * leafTargetPerProc)
+	if size < 1 {
+		return 1
+	}
+	return size
+}
+
+// splitLeaves splits split recursively until leaves are no larger than targetSize,
+// leaves are returned in encounter order.
+func splitLeaves(split spliterator.Spliterator, targetSize int) []spliterator.Spliterator {
+	if split.EstimateSize() <= targetSize {
+		return []spliterator.Spliterator{split}
+	}
+	// split keeps the suffix, prefix is returned
+	prefix := split.TrySplit()
+	if prefix == nil {
+		return []spliterator.Spliterator{split}
+	}
+	return append(splitLeaves(prefix, targetSize), splitLeaves(split, targetSize)...)
+}
@@ -0,0 +1,42 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stream_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/searKing/golang/go/exp/container/stream"
+)
+
+func ExampleMap() {
+	words := stream.Of("go", "stream", "generic", "go", "pipeline").Parallel()
+	upper := stream.Map(stream.Distinct(words), strings.ToUpper).
+		Sorted(func(a, b string) bool { return a < b }).
+		ToSlice()
+	fmt.Println(upper)
+
+	// Output:
+	// [GENERIC GO PIPELINE STREAM]
+}
+
+func ExampleGroupBy() {
+	groups := stream.GroupBy(stream.Of(1, 2, 3, 4, 5, 6).Parallel(), func(i int) bool { return i%2 == 0 })
+	fmt.Println(groups[true], groups[false])
+
+	// Output:
+	// [2 4 6] [1 3 5]
+}
+
+func ExampleCollect() {
+	joined := stream.Collect(stream.Of("a", "b", "c").Parallel(),
+		func() []string { return nil },
+		func(s []string, t string) []string { return append(s, t) },
+		func(a, b []string) []string { return append(a, b...) })
+	fmt.Println(strings.Join(joined, ","))
+
+	// Output:
+	// a,b,c
+}
@@ -0,0 +1,235 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package stream provides a type-safe sequence of elements supporting sequential and parallel
+// aggregate operations, as github.com/searKing/golang/go/container/stream does for interface{}.
+//
+// A stream pipeline consists of a source, zero or more intermediate operations
+// (which transform a stream into another stream, such as Filter) and a terminal operation
+// (which produces a result or side effect, such as Reduce or Collect).
+// Streams are lazy; computation on the source data is only performed when the
+// terminal operation is initiated.
+//
+// A parallel stream splits its source via util/spliterator into leaves evaluated in their own goroutines,
+// leaf results are merged in encounter order, so that results are deterministic as long as
+// the functions passed in are associative and free of side effects.
+package stream
+
+import (
+	"sort"
+
+	"github.com/searKing/golang/go/util/spliterator"
+)
+
+// Stream is a lazy sequence of elements of type T.
+type Stream[T any] struct {
+	// source returns the spliterator of the boxed elements of the source.
+	source func() spliterator.Spliterator
+	// wrap adapts a sink of T to a sink of the boxed elements of the source.
+	// A sink returns false to stop the traversal.
+	wrap     func(sink func(T) bool) func(any) bool
+	parallel bool
+}
+
+// Of returns a sequential ordered stream whose elements are the specified values.
+func Of[T any](values ...T) *Stream[T] {
+	return &Stream[T]{
+		source: func() spliterator.Spliterator { return newSliceSpliterator(values) },
+		wrap:   identity[T],
+	}
+}
+
+// OfSpliterator returns a sequential stream whose elements are traversed by split, the elements must be of type T.
+// split is bound at the time the terminal operation commences.
+func OfSpliterator[T any](split spliterator.Spliterator) *Stream[T] {
+	return &Stream[T]{
+		source: func() spliterator.Spliterator { return split },
+		wrap:   identity[T],
+	}
+}
+
+// Parallel returns an equivalent stream that is parallel.
+// Stateful operations already chained, such as Sorted, Distinct, Limit and Skip, keep the mode they were chained with.
+func (s *Stream[T]) Parallel() *Stream[T] {
+	p := *s
+	p.parallel = true
+	return &p
+}
+
+// Sequential returns an equivalent stream that is sequential.
+func (s *Stream[T]) Sequential() *Stream[T] {
+	p := *s
+	p.parallel = false
+	return &p
+}
+
+// IsParallel returns whether this stream, if a terminal operation were to be executed, would execute in parallel.
+func (s *Stream[T]) IsParallel() bool {
+	return s.parallel
+}
+
+// Filter returns a stream consisting of the elements of this stream that match the given predicate.
+func (s *Stream[T]) Filter(predicate func(T) bool) *Stream[T] {
+	return derive(s, func(sink func(T) bool) func(T) bool {
+		return func(t T) bool {
+			if !predicate(t) {
+				return true
+			}
+			return sink(t)
+		}
+	})
+}
+
+// Peek returns a stream consisting of the elements of this stream, additionally performing
+// the provided action on each element as elements are consumed from the resulting stream.
+func (s *Stream[T]) Peek(action func(T)) *Stream[T] {
+	return derive(s, func(sink func(T) bool) func(T) bool {
+		return func(t T) bool {
+			action(t)
+			return sink(t)
+		}
+	})
+}
+
+// Map returns a stream consisting of the results of applying the given function to the elements of s.
+func Map[T, R any](s *Stream[T], mapper func(T) R) *Stream[R] {
+	return derive(s, func(sink func(R) bool) func(T) bool {
+		return func(t T) bool { return sink(mapper(t)) }
+	})
+}
+
+// FlatMap returns a stream consisting of the results of replacing each element of s
+// with the elements returned by mapper.
+func FlatMap[T, R any](s *Stream[T], mapper func(T) []R) *Stream[R] {
+	return derive(s, func(sink func(R) bool) func(T) bool {
+		return func(t T) bool {
+			for _, r := range mapper(t) {
+				if !sink(r) {
+					return false
+				}
+			}
+			return true
+		}
+	})
+}
+
+// Sorted returns a stream consisting of the elements of this stream, sorted according to less.
+// The sort is stable, equal elements keep their encounter order.
+func (s *Stream[T]) Sorted(less func(a, b T) bool) *Stream[T] {
+	return s.barrier(func(ts []T) []T {
+		sort.SliceStable(ts, func(i, j int) bool { return less(ts[i], ts[j]) })
+		return ts
+	})
+}
+
+// Distinct returns a stream consisting of the distinct elements of s,
+// the first occurrence in encounter order is preserved.
+func Distinct[T comparable](s *Stream[T]) *Stream[T] {
+	return DistinctFunc(s, func(t T) T { return t })
+}
+
+// DistinctFunc returns a stream consisting of the elements of s with distinct keys,
+// the first occurrence in encounter order is preserved.
+func DistinctFunc[T any, K comparable](s *Stream[T], key func(T) K) *Stream[T] {
+	return s.barrier(func(ts []T) []T {
+		seen := make(map[K]struct{}, len(ts))
+		var distinct []T
+		for _, t := range ts {
+			k := key(t)
+			if _, has := seen[k]; has {
+				continue
+			}
+			seen[k] = struct{}{}
+			distinct = append(distinct, t)
+		}
+		return distinct
+	})
+}
+
+// Limit returns a stream consisting of the elements of this stream, truncated to be no longer than maxSize in length.
+// A sequential stream stops traversing its source once maxSize elements are consumed.
+func (s *Stream[T]) Limit(maxSize int) *Stream[T] {
+	if maxSize < 0 {
+		panic("stream: negative Limit")
+	}
+	return s.materialize(func() []T {
+		if !s.parallel {
+			return s.limit(maxSize).ToSlice()
+		}
+		ts := s.ToSlice()
+		if len(ts) > maxSize {
+			ts = ts[:maxSize]
+		}
+		return ts
+	})
+}
+
+// Skip returns a stream consisting of the remaining elements of this stream
+// after discarding the first n elements of the stream.
+func (s *Stream[T]) Skip(n int) *Stream[T] {
+	if n < 0 {
+		panic("stream: negative Skip")
+	}
+	return s.barrier(func(ts []T) []T {
+		if len(ts) < n {
+			return nil
+		}
+		return ts[n:]
+	})
+}
+
+// limit is a sequential stream of the first maxSize elements of s, which stops the traversal of the source
+// as soon as maxSize elements are consumed.
+func (s *Stream[T]) limit(maxSize int) *Stream[T] {
+	p := s.Sequential()
+	p.wrap = func(sink func(T) bool) func(any) bool {
+		n := 0
+		return s.wrap(func(t T) bool {
+			if n >= maxSize {
+				return false
+			}
+			n++
+			return sink(t) && n < maxSize
+		})
+	}
+	return p
+}
+
+// barrier returns a stream whose elements are computed by f from all the elements of s in encounter order.
+func (s *Stream[T]) barrier(f func(ts []T) []T) *Stream[T] {
+	return s.materialize(func() []T { return f(s.ToSlice()) })
+}
+
+// materialize returns a stream whose elements are computed by f when the terminal operation commences.
+// Operations chained before keep the mode of s, operations chained after are fused into a new pipeline.
+func (s *Stream[T]) materialize(f func() []T) *Stream[T] {
+	return &Stream[T]{
+		source:   func() spliterator.Spliterator { return newSliceSpliterator(f()) },
+		wrap:     identity[T],
+		parallel: s.parallel,
+	}
+}
+
+// derive returns a stream of R whose elements are consumed by op as T, the elements of s.
+func derive[T, R any](s *Stream[T], op func(sink func(R) bool) func(T) bool) *Stream[R] {
+	return &Stream[R]{
+		source: s.source,
+		wrap: func(sink func(R) bool) func(any) bool {
+			return s.wrap(op(sink))
+		},
+		parallel: s.parallel,
+	}
+}
+
+func identity[T any](sink func(T) bool) func(any) bool {
+	return func(v any) bool { return sink(v.(T)) }
+}
+
+func newSliceSpliterator[T any](ts []T) spliterator.Spliterator {
+	boxed := make([]any, 0, len(ts))
+	for _, t := range ts {
+		boxed = append(boxed, t)
+	}
+	return spliterator.NewSliceSpliterator2(spliterator.CharacteristicImmutable, boxed...)
+}
@@ -0,0 +1,132 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stream_test
+
+import (
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/searKing/golang/go/exp/container/stream"
+)
+
+func ints(n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = i
+	}
+	return s
+}
+
+func TestStream(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		of := func(values ...int) *stream.Stream[int] {
+			s := stream.Of(values...)
+			if parallel {
+				s = s.Parallel()
+			}
+			return s
+		}
+		values := ints(1000)
+
+		if got, want := of(values...).Filter(func(i int) bool { return i%3 == 0 }).Count(), 334; got != want {
+			t.Errorf("parallel %t: Filter.Count() = %d, want %d", parallel, got, want)
+		}
+		if got, want := of(values...).Reduce(0, func(a, b int) int { return a + b }), 999*1000/2; got != want {
+			t.Errorf("parallel %t: Reduce() = %d, want %d", parallel, got, want)
+		}
+
+		got := stream.Map(of(values...), strconv.Itoa).ToSlice()
+		for i, s := range got {
+			if s != strconv.Itoa(i) {
+				t.Fatalf("parallel %t: Map()[%d] = %q, want %q", parallel, i, s, strconv.Itoa(i))
+			}
+		}
+
+		flat := stream.FlatMap(of(1, 2, 3), func(i int) []int { return []int{i, i * 10} }).ToSlice()
+		if want := []int{1, 10, 2, 20, 3, 30}; !reflect.DeepEqual(flat, want) {
+			t.Errorf("parallel %t: FlatMap() = %v, want %v", parallel, flat, want)
+		}
+
+		sorted := of(5, 3, 1, 4, 2).Sorted(func(a, b int) bool { return a < b }).ToSlice()
+		if want := []int{1, 2, 3, 4, 5}; !reflect.DeepEqual(sorted, want) {
+			t.Errorf("parallel %t: Sorted() = %v, want %v", parallel, sorted, want)
+		}
+
+		distinct := stream.Distinct(of(3, 1, 3, 2, 1, 4)).ToSlice()
+		if want := []int{3, 1, 2, 4}; !reflect.DeepEqual(distinct, want) {
+			t.Errorf("parallel %t: Distinct() = %v, want %v", parallel, distinct, want)
+		}
+
+		limited := of(values...).Filter(func(i int) bool { return i%2 == 1 }).Limit(3).ToSlice()
+		if want := []int{1, 3, 5}; !reflect.DeepEqual(limited, want) {
+			t.Errorf("parallel %t: Limit() = %v, want %v", parallel, limited, want)
+		}
+
+		skipped := of(values...).Skip(997).ToSlice()
+		if want := []int{997, 998, 999}; !reflect.DeepEqual(skipped, want) {
+			t.Errorf("parallel %t: Skip() = %v, want %v", parallel, skipped, want)
+		}
+
+		groups := stream.GroupBy(of(values...), func(i int) int { return i % 3 })
+		for k, g := range groups {
+			for j, v := range g {
+				if v != k+3*j {
+					t.Fatalf("parallel %t: GroupBy()[%d][%d] = %d, want %d", parallel, k, j, v, k+3*j)
+				}
+			}
+		}
+		if len(groups) != 3 {
+			t.Errorf("parallel %t: len(GroupBy()) = %d, want %d", parallel, len(groups), 3)
+		}
+
+		if first, ok := of(values...).Filter(func(i int) bool { return i > 500 }).FindFirst(); !ok || first != 501 {
+			t.Errorf("parallel %t: FindFirst() = %d, %t, want %d, true", parallel, first, ok, 501)
+		}
+		if _, ok := of().FindFirst(); ok {
+			t.Errorf("parallel %t: FindFirst() on empty stream ok = true, want false", parallel)
+		}
+		if !of(values...).AnyMatch(func(i int) bool { return i == 999 }) {
+			t.Errorf("parallel %t: AnyMatch() = false, want true", parallel)
+		}
+		if of(values...).AllMatch(func(i int) bool { return i < 999 }) {
+			t.Errorf("parallel %t: AllMatch() = true, want false", parallel)
+		}
+
+		var sum int64
+		of(values...).ForEach(func(i int) { atomic.AddInt64(&sum, int64(i)) })
+		if sum != 999*1000/2 {
+			t.Errorf("parallel %t: ForEach() sum = %d, want %d", parallel, sum, 999*1000/2)
+		}
+	}
+}
+
+func TestStream_Lazy(t *testing.T) {
+	var visited int
+	s := stream.Of(ints(100)...).Peek(func(int) { visited++ }).Limit(5)
+	if visited != 0 {
+		t.Fatalf("visited %d elements before the terminal operation", visited)
+	}
+	if got := s.Count(); got != 5 {
+		t.Fatalf("Count() = %d, want %d", got, 5)
+	}
+	if visited != 5 {
+		t.Fatalf("visited %d elements, want %d", visited, 5)
+	}
+}
+
+func TestStream_ParallelPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Fatalf("recover() = %v, want %v", r, "boom")
+		}
+	}()
+	stream.Of(ints(100)...).Parallel().ForEach(func(i int) {
+		if i == 42 {
+			panic("boom")
+		}
+	})
+}
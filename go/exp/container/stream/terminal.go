@@ -0,0 +1,99 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stream
+
+// ForEach performs action for each element of this stream.
+// For a parallel stream, action is called concurrently from several goroutines,
+// in encounter order within each leaf but without ordering across leaves.
+func (s *Stream[T]) ForEach(action func(T)) {
+	evaluate(s, func() struct{} { return struct{}{} },
+		func(a struct{}, t T) (struct{}, bool) {
+			action(t)
+			return a, true
+		},
+		func(a, b struct{}) struct{} { return a })
+}
+
+// ToSlice returns a slice containing the elements of this stream in encounter order.
+func (s *Stream[T]) ToSlice() []T {
+	return Collect(s, func() []T { return nil },
+		func(ts []T, t T) []T { return append(ts, t) },
+		func(a, b []T) []T { return append(a, b...) })
+}
+
+// Count returns the count of elements in this stream.
+func (s *Stream[T]) Count() int {
+	return Collect(s, func() int { return 0 },
+		func(n int, t T) int { return n + 1 },
+		func(a, b int) int { return a + b })
+}
+
+// Reduce performs a reduction on the elements of this stream, using the provided identity value
+// and an associative accumulation function, and returns the reduced value.
+// For a parallel stream, every leaf is reduced from identity and the partial results are reduced
+// in encounter order, hence identity must be an identity for op.
+func (s *Stream[T]) Reduce(identity T, op func(a, b T) T) T {
+	return Collect(s, func() T { return identity }, op, op)
+}
+
+// AnyMatch returns whether any elements of this stream match the provided predicate.
+// A sequential stream stops traversing its source once an element matched.
+func (s *Stream[T]) AnyMatch(predicate func(T) bool) bool {
+	return evaluate(s, func() bool { return false },
+		func(matched bool, t T) (bool, bool) {
+			matched = predicate(t)
+			return matched, !matched
+		},
+		func(a, b bool) bool { return a || b })
+}
+
+// AllMatch returns whether all elements of this stream match the provided predicate.
+// A sequential stream stops traversing its source once an element mismatched.
+func (s *Stream[T]) AllMatch(predicate func(T) bool) bool {
+	return !s.AnyMatch(func(t T) bool { return !predicate(t) })
+}
+
+// FindFirst returns the first element of this stream in encounter order, ok is false if the stream is empty.
+func (s *Stream[T]) FindFirst() (t T, ok bool) {
+	type found struct {
+		t  T
+		ok bool
+	}
+	f := evaluate(s, func() found { return found{} },
+		func(f found, t T) (found, bool) { return found{t: t, ok: true}, false },
+		func(a, b found) found {
+			if a.ok {
+				return a
+			}
+			return b
+		})
+	return f.t, f.ok
+}
+
+// Collect performs a mutable reduction operation on the elements of s.
+// supplier creates a new result container, accumulator incorporates an element into a result,
+// and combiner merges two results, the left one preceding the right one in encounter order.
+// For a parallel stream, every leaf is accumulated into a container of its own.
+func Collect[T, R any](s *Stream[T], supplier func() R, accumulator func(R, T) R, combiner func(R, R) R) R {
+	return evaluate(s, supplier,
+		func(r R, t T) (R, bool) { return accumulator(r, t), true },
+		combiner)
+}
+
+// GroupBy groups the elements of s by key, elements in each group are kept in encounter order.
+func GroupBy[T any, K comparable](s *Stream[T], key func(T) K) map[K][]T {
+	return Collect(s, func() map[K][]T { return make(map[K][]T) },
+		func(groups map[K][]T, t T) map[K][]T {
+			k := key(t)
+			groups[k] = append(groups[k], t)
+			return groups
+		},
+		func(a, b map[K][]T) map[K][]T {
+			for k, ts := range b {
+				a[k] = append(a[k], ts...)
+			}
+			return a
+		})
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package generator_test
+
+import (
+	"fmt"
+
+	"github.com/searKing/golang/go/exp/go/generator"
+)
+
+func ExampleGeneratorFunc() {
+	g := func(i int) *generator.Generator[int] {
+		return generator.GeneratorFunc(func(yield generator.Yield[int]) {
+			if !yield(i) {
+				return
+			}
+			if !yield(i + 10) {
+				return
+			}
+		})
+	}
+
+	gen := g(10)
+
+	for msg := range gen.C {
+		fmt.Println(msg)
+	}
+
+	// Output:
+	// 10
+	// 20
+}
+
+func ExampleGenerator_Next() {
+	g := func(i int) *generator.Generator[int] {
+		return generator.GeneratorFunc(func(yield generator.Yield[int]) {
+			if !yield(i) {
+				return
+			}
+			if !yield(i + 10) {
+				return
+			}
+		})
+	}
+
+	gen := g(10)
+
+	for {
+		msg, ok := gen.Next()
+		if !ok {
+			return
+		}
+		fmt.Println(msg)
+	}
+
+	// Output:
+	// 10
+	// 20
+}
+
+func ExampleGenerator_Seq() {
+	gen := generator.GeneratorFunc(func(yield generator.Yield[string]) {
+		for _, s := range []string{"a", "b", "c", "d"} {
+			if !yield(s) {
+				return
+			}
+		}
+	})
+
+	gen.Seq()(func(msg string) bool {
+		fmt.Println(msg)
+		return msg != "b"
+	})
+	fmt.Println(gen.Stopped())
+
+	// Output:
+	// a
+	// b
+	// true
+}
+
+func ExampleGeneratorSeq() {
+	seq := func(yield func(int) bool) {
+		for i := 0; i < 3; i++ {
+			if !yield(i * i) {
+				return
+			}
+		}
+	}
+
+	gen := generator.GeneratorSeq(seq)
+	for msg := range gen.C {
+		fmt.Println(msg)
+	}
+
+	// Output:
+	// 0
+	// 1
+	// 4
+}
+
+func ExampleGeneratorWithSupplier() {
+	supplierC := make(chan int)
+	go func() {
+		defer close(supplierC)
+		supplierC <- 10
+		supplierC <- 20
+	}()
+
+	g := generator.GeneratorWithSupplier(supplierC)
+	for msg := range g.C {
+		fmt.Println(msg)
+	}
+
+	// Output:
+	// 10
+	// 20
+}
@@ -0,0 +1,192 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package generator
+
+import (
+	"context"
+)
+
+// Yield delivers msg to the consumer of the generator.
+// ok returns true if msg sent; false if the generator is stopped.
+type Yield[T any] func(msg T) (ok bool)
+
+// Generator behaves like Generator in python or ES6
+// Generator function contains one or more yield statement.
+// Generator functions allow you to declare a function that behaves like an iterator, i.e. it can be used in a for loop.
+// Generator is a type-safe version of github.com/searKing/golang/go/go/generator.Generator,
+// messages are delivered on typed channels without reflection.
+// see https://wiki.python.org/moin/Generators
+// see https://www.programiz.com/python-programming/generator
+// see https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Statements/function*
+type Generator[T any] struct {
+	// Used by Next, to notify or deliver what is generated, as next in python or ES6
+	// C is closed when the generator is exhausted or stopped.
+	C <-chan T
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newGenerator[T any](c <-chan T) *Generator[T] {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Generator[T]{C: c, ctx: ctx, cancel: cancel}
+}
+
+// Stop prevents the Generator from firing.
+// It returns true if the call stops the generator, false if the generator has already
+// expired or been stopped.
+// Any Yield blocked or called later returns false, and C is closed once the supplier returns.
+func (g *Generator[T]) Stop() bool {
+	if g.ctx == nil || g.cancel == nil {
+		panic("generator: Stop called on uninitialized Generator")
+	}
+	select {
+	case <-g.ctx.Done():
+		return false
+	default:
+		g.cancel()
+		return true
+	}
+}
+
+// Stopped reports whether the generator has been stopped or exhausted.
+func (g *Generator[T]) Stopped() bool {
+	if g.ctx == nil || g.cancel == nil {
+		panic("generator: Stopped called on uninitialized Generator")
+	}
+	select {
+	case <-g.ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// Done returns a channel that's closed when the generator is stopped or exhausted.
+func (g *Generator[T]) Done() <-chan struct{} {
+	if g.ctx == nil || g.cancel == nil {
+		panic("generator: Done called on uninitialized Generator")
+	}
+	return g.ctx.Done()
+}
+
+// Next behaves like an iterator, i.e. it can be used in a for loop.
+// ok is false if the generator is exhausted or stopped.
+func (g *Generator[T]) Next() (msg T, ok bool) {
+	if g.Stopped() {
+		return msg, false
+	}
+	msg, ok = <-g.C
+	return
+}
+
+// Yield is a grammar sugar for data src of generator
+// ok returns true if msg sent; false if consume canceled
+// If a function contains at least one yield statement (it may contain other yield or return statements),
+// it becomes a generator function. Both yield and return will return some value from a function.
+// The difference is that, while a return statement terminates a function entirely,
+// yield statement pauses the function saving all its states and later continues from there on successive calls.
+func (g *Generator[T]) Yield(supplierC chan<- T) Yield[T] {
+	return func(msg T) (ok bool) {
+		select {
+		case <-g.Done():
+			return false
+		default:
+		}
+		select {
+		case <-g.Done():
+			return false
+		case supplierC <- msg:
+			return true
+		}
+	}
+}
+
+// Seq returns an iter-style push function, which calls yield with every message generated,
+// until the generator is exhausted or yield returns false.
+// The generator is stopped if yield returns false.
+func (g *Generator[T]) Seq() func(yield func(T) bool) {
+	return func(yield func(T) bool) {
+		for {
+			msg, ok := g.Next()
+			if !ok {
+				return
+			}
+			if !yield(msg) {
+				g.Stop()
+				return
+			}
+		}
+	}
+}
+
+// Simply speaking, a generator is a function that returns an object (iterator) which we can iterate over (one value at a time).
+
+// GeneratorFunc returns an object (iterator) which we can iterate over (one value at a time).
+// It returns a Generator that can be used to cancel the call using its Stop method.
+// Iterate will be stopped when f is return or Stop is called.
+func GeneratorFunc[T any](f func(yield Yield[T])) *Generator[T] {
+	c := make(chan T)
+	g := newGenerator[T](c)
+	go func() {
+		defer g.cancel()
+		defer close(c)
+		f(g.Yield(c))
+	}()
+	return g
+}
+
+// GeneratorSeq is like GeneratorFunc.
+// But it's data src is an iter-style push function, such as the one returned by Seq.
+func GeneratorSeq[T any](seq func(yield func(T) bool)) *Generator[T] {
+	return GeneratorFunc(func(yield Yield[T]) { seq(yield) })
+}
+
+// GeneratorWithSupplier is like GeneratorFunc.
+// But it's data src is from supplierC.
+// Iterate will be stopped when supplierC is closed or Stop is called.
+func GeneratorWithSupplier[T any](supplierC <-chan T) *Generator[T] {
+	c := make(chan T)
+	g := newGenerator[T](c)
+	go func() {
+		defer g.cancel()
+		defer close(c)
+		yield := g.Yield(c)
+		for {
+			select {
+			case <-g.ctx.Done():
+				return
+			case msg, ok := <-supplierC:
+				if !ok || !yield(msg) {
+					return
+				}
+			}
+		}
+	}()
+	return g
+}
+
+// GeneratorFuncWithSupplier waits for the supplierC to supply and then calls f
+// in its own goroutine every time. It returns a Generator that can
+// be used to cancel the call using its Stop method.
+// Consume will be stopped when supplierC is closed.
+func GeneratorFuncWithSupplier[T any](supplierC <-chan T, f func(msg T)) *Generator[T] {
+	g := newGenerator[T](nil)
+	go func() {
+		defer g.cancel()
+		for {
+			select {
+			case <-g.ctx.Done():
+				return
+			case msg, ok := <-supplierC:
+				if !ok {
+					return
+				}
+				go f(msg)
+			}
+		}
+	}()
+	return g
+}
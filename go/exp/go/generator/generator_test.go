@@ -0,0 +1,116 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package generator_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/exp/go/generator"
+)
+
+func TestGeneratorFuncWithSupplier(t *testing.T) {
+	n := 10
+	var j int32
+	c := make(chan bool)
+	supplierC := make(chan int)
+	f := func(msg int) {
+		if atomic.AddInt32(&j, 1) == int32(n) {
+			c <- true
+		}
+	}
+	go func() {
+		for i := 1; i <= n; i++ {
+			supplierC <- i
+		}
+	}()
+
+	generator.GeneratorFuncWithSupplier(supplierC, f)
+	<-c
+}
+
+func TestGenerator_Stop(t *testing.T) {
+	stopped := make(chan bool)
+	g := generator.GeneratorFunc(func(yield generator.Yield[int]) {
+		defer close(stopped)
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	})
+
+	for i := 0; i < 5; i++ {
+		msg, ok := g.Next()
+		if !ok || msg != i {
+			t.Fatalf("Next() = %d, %t, want %d, true", msg, ok, i)
+		}
+	}
+	if !g.Stop() {
+		t.Fatalf("Stop() = false, want true")
+	}
+	if g.Stop() {
+		t.Fatalf("Stop() twice = true, want false")
+	}
+	if !g.Stopped() {
+		t.Fatalf("Stopped() = false, want true")
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("generator func is not stopped")
+	}
+	if _, ok := g.Next(); ok {
+		t.Fatalf("Next() after Stop ok = true, want false")
+	}
+	if _, ok := <-g.C; ok {
+		t.Fatalf("C is not closed after Stop")
+	}
+}
+
+func TestGeneratorWithSupplier_Stop(t *testing.T) {
+	supplierC := make(chan int)
+	g := generator.GeneratorWithSupplier(supplierC)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case supplierC <- i:
+			case <-g.Done():
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 5; i++ {
+		msg, ok := g.Next()
+		if !ok || msg != i {
+			t.Fatalf("Next() = %d, %t, want %d, true", msg, ok, i)
+		}
+	}
+	g.Stop()
+	for range g.C {
+	}
+	if !g.Stopped() {
+		t.Fatalf("Stopped() = false, want true")
+	}
+}
+
+func TestGenerator_Exhausted(t *testing.T) {
+	g := generator.GeneratorSeq(func(yield func(string) bool) {
+		yield("a")
+	})
+	if msg, ok := g.Next(); !ok || msg != "a" {
+		t.Fatalf("Next() = %q, %t, want %q, true", msg, ok, "a")
+	}
+	if _, ok := g.Next(); ok {
+		t.Fatalf("Next() on exhausted generator ok = true, want false")
+	}
+	select {
+	case <-g.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("exhausted generator is not done")
+	}
+}
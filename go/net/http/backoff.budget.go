@@ -0,0 +1,58 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"math"
+	"sync"
+)
+
+// RetryBudget is a token bucket limiting retries to a ratio of total requests, shared across clients,
+// so that retries can not amplify the load of a dependency failing.
+// Every request deposits ratio tokens, and every retry or hedged request withdraws one token,
+// allowed only if one token left at least.
+// See: https://github.com/grpc/proposal/blob/master/A6-client-retries.md#throttling-retry-attempts-and-hedged-rpcs
+type RetryBudget struct {
+	ratio     float64
+	maxTokens float64
+
+	mu     sync.Mutex
+	tokens float64
+}
+
+// NewRetryBudget returns a RetryBudget allows retries of ratio of requests, as 0.1 for 10%,
+// with a capacity of maxTokens, full at first so that retries are allowed on cold start.
+func NewRetryBudget(ratio float64, maxTokens int) *RetryBudget {
+	return &RetryBudget{
+		ratio:     math.Max(ratio, 0),
+		maxTokens: float64(maxTokens),
+		tokens:    float64(maxTokens),
+	}
+}
+
+// Deposit deposits tokens of a request.
+func (b *RetryBudget) Deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = math.Min(b.tokens+b.ratio, b.maxTokens)
+}
+
+// Withdraw withdraws a token for a retry, reports whether the retry is allowed.
+func (b *RetryBudget) Withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Tokens returns tokens left.
+func (b *RetryBudget) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens
+}
@@ -40,6 +40,7 @@ var (
 // Don't retry if the error was due to an invalid protocol scheme.
 // Don't retry if the error was due to TLS cert verification failure.
 // Don't retry if the http's StatusCode is http.StatusNotImplemented.
+// Don't retry if the request was rejected by a circuit breaker.
 func RetryAfter(resp *http.Response, err error, defaultBackoff time.Duration) (backoff time.Duration, retry bool) {
 	backoff = defaultBackoff
 	if resp != nil {
@@ -51,6 +52,10 @@ func RetryAfter(resp *http.Response, err error, defaultBackoff time.Duration) (b
 	}
 
 	if err != nil {
+		// Don't retry if the request was rejected by a circuit breaker, which is open for a while.
+		if IsBreakerRejected(err) {
+			return backoff, false
+		}
 		if v, ok := err.(*url.Error); ok {
 			// Don't retry if the error was due to too many redirects.
 			if redirectsErrorRe.MatchString(v.Error()) {
@@ -181,6 +186,9 @@ type doWithBackoff struct {
 	ChainClientInterceptors  []ClientInterceptor
 	RetryAfter               RetryAfterHandler
 	ExponentialBackOffOption []time_.ExponentialBackOffOption
+	RetryBudget              *RetryBudget // limits retries and hedged requests, shared across clients
+	HedgeDelay               HedgeDelayer // sends hedged requests after delays if set
+	MaxHedged                int          // hedged requests sent at most for a request
 }
 
 func (o *doWithBackoff) SetDefault() {
@@ -222,6 +230,22 @@ func (o *doWithBackoff) Complete() {
 	o.clientInterceptor = chainedInt
 }
 
+// requestWithBodyRewindable makes the body of req rewindable, and readable concurrently if hedged.
+func (o *doWithBackoff) requestWithBodyRewindable(req *http.Request) error {
+	if o.HedgeDelay != nil && o.MaxHedged > 0 {
+		return RequestWithBodyConcurrentRewindable(req)
+	}
+	return RequestWithBodyRewindable(req)
+}
+
+// do sends req by httpDo, hedged if set and req is rewindable.
+func (o *doWithBackoff) do(httpDo ClientInvoker, req *http.Request, retry int, rewindable bool) (*http.Response, error) {
+	if !rewindable || o.HedgeDelay == nil || o.MaxHedged <= 0 {
+		return httpDo(req, retry)
+	}
+	return o.doHedged(httpDo, req, retry)
+}
+
 // DoWithBackoff will retry by exponential backoff if failed.
 // If request is not rewindable, retry wil be skipped.
 func DoWithBackoff(httpReq *http.Request, opts ...DoWithBackoffOption) (*http.Response, error) {
@@ -237,7 +261,10 @@ func DoWithBackoff(httpReq *http.Request, opts ...DoWithBackoffOption) (*http.Re
 	option = append(option, time_.WithExponentialBackOffOptionMaxElapsedCount(3))
 	option = append(option, opt.ExponentialBackOffOption...)
 	backoff := time_.NewDefaultExponentialBackOff(option...)
-	rewindableErr := RequestWithBodyRewindable(httpReq)
+	rewindableErr := opt.requestWithBodyRewindable(httpReq)
+	if opt.RetryBudget != nil {
+		opt.RetryBudget.Deposit()
+	}
 	var retries int
 	for {
 		if retries > 0 && httpReq.GetBody != nil {
@@ -254,7 +281,7 @@ func DoWithBackoff(httpReq *http.Request, opts ...DoWithBackoffOption) (*http.Re
 				return opt.clientInterceptor(req, retry, do, opts...)
 			}
 		}
-		resp, err := httpDo(httpReq, retries)
+		resp, err := opt.do(httpDo, httpReq, retries, rewindableErr == nil)
 
 		wait, ok := backoff.NextBackOff()
 		if !ok {
@@ -282,6 +309,14 @@ func DoWithBackoff(httpReq *http.Request, opts ...DoWithBackoffOption) (*http.Re
 			}
 		}
 
+		if opt.RetryBudget != nil && !opt.RetryBudget.Withdraw() {
+			if err != nil {
+				return nil, fmt.Errorf("http do reach retry budget limit after retries %d: %w", retries, err)
+			} else {
+				return resp, nil
+			}
+		}
+
 		timer := time.NewTimer(wait)
 		select {
 		case <-timer.C:
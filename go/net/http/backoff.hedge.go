@@ -0,0 +1,199 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HedgeDelayer returns the delay to send a hedged request, if no response yet,
+// and observes latencies of requests succeeded.
+type HedgeDelayer interface {
+	Delay() time.Duration
+	Observe(latency time.Duration)
+}
+
+// PercentileHedgeDelay is a HedgeDelayer delays by the percentile of latencies observed recently,
+// so that only the requests in the tail are hedged.
+type PercentileHedgeDelay struct {
+	percentile float64
+	fallback   time.Duration
+
+	mu        sync.Mutex
+	latencies []time.Duration // ring buffer of latencies observed
+	next      int
+	full      bool
+}
+
+// NewPercentileHedgeDelay returns a PercentileHedgeDelay delays by the percentile in [0, 100], as 95 for p95,
+// of the last samples latencies observed, or by fallback if no latency observed yet.
+func NewPercentileHedgeDelay(percentile float64, samples int, fallback time.Duration) *PercentileHedgeDelay {
+	if samples <= 0 {
+		samples = 1
+	}
+	return &PercentileHedgeDelay{
+		percentile: math.Min(math.Max(percentile, 0), 100),
+		fallback:   fallback,
+		latencies:  make([]time.Duration, samples),
+	}
+}
+
+// Delay returns the percentile of latencies observed.
+func (d *PercentileHedgeDelay) Delay() time.Duration {
+	d.mu.Lock()
+	n := d.next
+	if d.full {
+		n = len(d.latencies)
+	}
+	latencies := append([]time.Duration(nil), d.latencies[:n]...)
+	d.mu.Unlock()
+	if len(latencies) == 0 {
+		return d.fallback
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	i := int(math.Ceil(d.percentile/100*float64(len(latencies)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return latencies[i]
+}
+
+// Observe records a latency.
+func (d *PercentileHedgeDelay) Observe(latency time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.latencies[d.next] = latency
+	d.next++
+	if d.next == len(d.latencies) {
+		d.next = 0
+		d.full = true
+	}
+}
+
+type hedgeResult struct {
+	attempt int
+	resp    *http.Response
+	err     error
+	cancel  context.CancelFunc
+	cost    time.Duration
+}
+
+// doHedged sends req by do, and hedged duplicates of req after delays, MaxHedged at most, if no response yet,
+// returns the first success, and cancels the others.
+// All attempts fail, the last failure returned.
+func (o *doWithBackoff) doHedged(do ClientInvoker, req *http.Request, retry int) (*http.Response, error) {
+	results := make(chan hedgeResult, 1+o.MaxHedged)
+	var cancels []context.CancelFunc
+	launch := func(req *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		attempt := len(cancels)
+		cancels = append(cancels, cancel)
+		req = req.WithContext(ctx)
+		go func() {
+			start := time.Now()
+			resp, err := do(req, retry)
+			results <- hedgeResult{attempt: attempt, resp: resp, err: err, cancel: cancel, cost: time.Since(start)}
+		}()
+	}
+	launch(req)
+	inflight, hedged := 1, 0
+	timer := time.NewTimer(o.HedgeDelay.Delay())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			if hedged >= o.MaxHedged || (o.RetryBudget != nil && !o.RetryBudget.Withdraw()) {
+				continue
+			}
+			hedgeReq, err := cloneRequestWithBody(req)
+			if err != nil {
+				continue
+			}
+			launch(hedgeReq)
+			hedged++
+			inflight++
+			timer.Reset(o.HedgeDelay.Delay())
+		case res := <-results:
+			inflight--
+			_, retry := o.RetryAfter(res.resp, res.err, 0)
+			if res.err == nil && !retry {
+				o.HedgeDelay.Observe(res.cost)
+				if inflight > 0 {
+					// cancel the others, the winner's context is kept until its body closed.
+					for i, cancel := range cancels {
+						if i != res.attempt {
+							cancel()
+						}
+					}
+					go drainHedgeResults(results, inflight)
+				}
+				return withCancelOnClose(res), nil
+			}
+			if inflight == 0 {
+				return withCancelOnClose(res), res.err
+			}
+			// wait for the others in flight
+			if res.resp != nil {
+				res.resp.Body.Close()
+			}
+			res.cancel()
+		}
+	}
+}
+
+// drainHedgeResults cancels the attempts in flight, and closes the responses lost.
+func drainHedgeResults(results <-chan hedgeResult, inflight int) {
+	for ; inflight > 0; inflight-- {
+		res := <-results
+		if res.resp != nil {
+			res.resp.Body.Close()
+		}
+		res.cancel()
+	}
+}
+
+func cloneRequestWithBody(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return r, nil
+	}
+	if req.GetBody == nil {
+		return nil, ErrBodyNotRewindable
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = body
+	return r, nil
+}
+
+// withCancelOnClose returns the response of res, whose context is canceled once the body closed.
+func withCancelOnClose(res hedgeResult) *http.Response {
+	if res.resp == nil || res.resp.Body == nil {
+		res.cancel()
+		return res.resp
+	}
+	res.resp.Body = &cancelReadCloser{ReadCloser: res.resp.Body, cancel: res.cancel}
+	return res.resp
+}
+
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelReadCloser) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
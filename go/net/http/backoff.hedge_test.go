@@ -0,0 +1,125 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	http_ "github.com/searKing/golang/go/net/http"
+	time_ "github.com/searKing/golang/go/time"
+)
+
+type bodyOnlyReader struct{ io.Reader }
+
+func TestDoWithBackoff_Hedge(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit := atomic.AddInt32(&hits, 1)
+		body, _ := io.ReadAll(r.Body)
+		if hit == 1 {
+			// the first one is in the tail
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(2 * time.Second):
+			}
+		}
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+
+	// a body of type unknown, rewound by replaying
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, srv.URL,
+		bodyOnlyReader{strings.NewReader("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	delay := http_.NewPercentileHedgeDelay(95, 10, 20*time.Millisecond)
+	start := time.Now()
+	resp, err := http_.DoWithBackoff(req, http_.WithDoWithBackoffOptionHedge(delay, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(body), "hello"; got != want {
+		t.Errorf("body %q, want %q", got, want)
+	}
+	if cost := time.Since(start); cost > time.Second {
+		t.Errorf("hedged request cost %s, want less than %s", cost, time.Second)
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("server hit %d times, want %d", got, 2)
+	}
+	if got := delay.Delay(); got >= time.Second {
+		t.Errorf("delay %s observed, want the latency of the hedged one", got)
+	}
+}
+
+func TestDoWithBackoff_RetryBudget(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	budget := http_.NewRetryBudget(0.5, 1)
+	do := func() {
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http_.DoWithBackoff(req,
+			http_.WithDoWithBackoffOptionRetryBudget(budget),
+			http_.WithDoWithBackoffOptionExponentialBackOffOption(time_.WithExponentialBackOffOptionInitialInterval(1)),
+			http_.WithDoWithBackoffOptionMaxRetries(5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("status %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+		}
+	}
+
+	// a token at first, and 0.5 token deposited per request
+	do()
+	if got, want := atomic.LoadInt32(&hits), int32(2); got != want {
+		t.Errorf("server hit %d times, want %d", got, want)
+	}
+	do()
+	if got, want := atomic.LoadInt32(&hits), int32(3); got != want {
+		t.Errorf("server hit %d times, want %d", got, want)
+	}
+	do()
+	if got, want := atomic.LoadInt32(&hits), int32(5); got != want {
+		t.Errorf("server hit %d times, want %d", got, want)
+	}
+}
+
+func TestPercentileHedgeDelay(t *testing.T) {
+	d := http_.NewPercentileHedgeDelay(90, 10, time.Second)
+	if got := d.Delay(); got != time.Second {
+		t.Errorf("fallback %s, want %s", got, time.Second)
+	}
+	for i := 1; i <= 20; i++ {
+		d.Observe(time.Duration(i) * time.Millisecond)
+	}
+	// the last 10 samples are 11ms...20ms
+	if got, want := d.Delay(), 19*time.Millisecond; got != want {
+		t.Errorf("p90 %s, want %s", got, want)
+	}
+}
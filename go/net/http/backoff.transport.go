@@ -32,7 +32,10 @@ func RoundTripperWithBackoff(rt http.RoundTripper, opts ...DoWithBackoffOption)
 		option = append(option, time_.WithExponentialBackOffOptionMaxElapsedCount(3))
 		option = append(option, opt.ExponentialBackOffOption...)
 		backoff := time_.NewDefaultExponentialBackOff(option...)
-		rewindableErr := RequestWithBodyRewindable(req)
+		rewindableErr := opt.requestWithBodyRewindable(req)
+		if opt.RetryBudget != nil {
+			opt.RetryBudget.Deposit()
+		}
 		var retries int
 		for {
 			if retries > 0 && req.GetBody != nil {
@@ -49,7 +52,7 @@ func RoundTripperWithBackoff(rt http.RoundTripper, opts ...DoWithBackoffOption)
 					return opt.clientInterceptor(req, retry, do, opts...)
 				}
 			}
-			resp, err := httpDo(req, retries)
+			resp, err := opt.do(httpDo, req, retries, rewindableErr == nil)
 
 			wait, ok := backoff.NextBackOff()
 			if !ok {
@@ -77,6 +80,14 @@ func RoundTripperWithBackoff(rt http.RoundTripper, opts ...DoWithBackoffOption)
 				}
 			}
 
+			if opt.RetryBudget != nil && !opt.RetryBudget.Withdraw() {
+				if err != nil {
+					return nil, fmt.Errorf("http do reach retry budget limit after retries %d: %w", retries, err)
+				} else {
+					return resp, nil
+				}
+			}
+
 			timer := time.NewTimer(wait)
 			select {
 			case <-timer.C:
@@ -186,8 +186,9 @@ func parseContentRange(s string) (contentRange *httpContentRange, err error) {
 		return nil, errors.New("invalid range")
 	}
 
-	if r.firstBytePos >= 0 && r.lastBytePos >= 0 && r.completeLength >= 0 && (r.lastBytePos-r.firstBytePos+1 < r.completeLength) {
+	if r.lastBytePos >= 0 && r.completeLength >= 0 && r.completeLength <= r.lastBytePos {
 		// The specified ranges did not overlap with the content.
+		// See: https://www.rfc-editor.org/rfc/rfc9110#section-14.4
 		return nil, errNoOverlap
 	}
 	return &r, nil
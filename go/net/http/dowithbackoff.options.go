@@ -86,3 +86,22 @@ func WithDoWithBackoffOptionProxy(proxyUrl string, proxyTarget string) DoWithBac
 			return cli.Do(req)
 		})
 }
+
+// WithDoWithBackoffOptionRetryBudget returns a DoWithBackoffOption limits retries and hedged requests by budget,
+// which can be shared across clients.
+func WithDoWithBackoffOptionRetryBudget(budget *RetryBudget) DoWithBackoffOption {
+	return DoWithBackoffOptionFunc(func(o *doWithBackoff) {
+		o.RetryBudget = budget
+	})
+}
+
+// WithDoWithBackoffOptionHedge returns a DoWithBackoffOption sends a hedged duplicate request after a delay by delay,
+// if no response yet, maxHedged hedged requests at most, and takes the first success.
+// The request body is buffered in memory if not rewindable concurrently,
+// and hedged requests are not sent if the body can not be rewound.
+func WithDoWithBackoffOptionHedge(delay HedgeDelayer, maxHedged int) DoWithBackoffOption {
+	return DoWithBackoffOptionFunc(func(o *doWithBackoff) {
+		o.HedgeDelay = delay
+		o.MaxHedged = maxHedged
+	})
+}
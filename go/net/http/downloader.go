@@ -0,0 +1,402 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDownloadConcurrency is the number of chunks downloaded in parallel, if not set.
+	DefaultDownloadConcurrency = 4
+	// DefaultDownloadChunkSize is the size of a chunk, if not set.
+	DefaultDownloadChunkSize = 4 << 20
+	// DownloadStateSuffix is the suffix of the sidecar state file of a download in progress.
+	DownloadStateSuffix = ".download"
+)
+
+var (
+	// ErrDownloadChanged is returned when the resource changed during a download, by ETag or Last-Modified.
+	ErrDownloadChanged = errors.New("http: resource changed during download")
+)
+
+// Downloader downloads a URL into a file in parallel byte ranges, with chunks retried by DoWithBackoff.
+// The chunks downloaded are recorded in a sidecar state file, named with DownloadStateSuffix appended,
+// so that a download interrupted can be resumed by downloading the same URL into the same file again,
+// if the resource has not changed, by ETag, Last-Modified and size.
+// The resource is downloaded entirely at once if byte ranges are not supported by the server.
+type Downloader struct {
+	// Client sends requests, http.DefaultClient if nil.
+	Client *http.Client
+	// Concurrency is the number of chunks downloaded in parallel, DefaultDownloadConcurrency if not positive.
+	Concurrency int
+	// ChunkSize is the size of a chunk, DefaultDownloadChunkSize if not positive.
+	ChunkSize int64
+	// BackOffOptions are options of DoWithBackoff to retry every chunk.
+	BackOffOptions []DoWithBackoffOption
+}
+
+// downloadState is the content of the sidecar state file.
+type downloadState struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Size         int64  `json:"size"`
+	ChunkSize    int64  `json:"chunk_size"`
+	Done         []bool `json:"done"` // chunks downloaded
+}
+
+// matches reports whether s is the state of a download of the same resource.
+func (s *downloadState) matches(o *downloadState) bool {
+	return s.URL == o.URL && s.ETag == o.ETag && s.LastModified == o.LastModified &&
+		s.Size == o.Size && s.ChunkSize == o.ChunkSize && len(s.Done) == len(o.Done)
+}
+
+// chunk returns the byte range of the i-th chunk, inclusive.
+func (s *downloadState) chunk(i int) (first, last int64) {
+	first = int64(i) * s.ChunkSize
+	last = first + s.ChunkSize - 1
+	if last >= s.Size {
+		last = s.Size - 1
+	}
+	return first, last
+}
+
+// Download downloads url into the file name, resuming from the sidecar state file if any.
+func (d *Downloader) Download(ctx context.Context, url, name string) error {
+	probe, entire, err := d.probe(ctx, url)
+	if err != nil {
+		return err
+	}
+	if probe == nil {
+		return d.downloadEntirely(ctx, url, name, entire)
+	}
+
+	stateName := name + DownloadStateSuffix
+	state := probe
+	if old, err := readDownloadState(stateName); err == nil && old.matches(probe) {
+		state = old
+	}
+
+	flag := os.O_RDWR | os.O_CREATE
+	if state == probe {
+		// not resumable, start over
+		flag |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(name, flag, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Truncate(state.Size); err != nil {
+		return err
+	}
+	if err := writeDownloadState(stateName, state); err != nil {
+		return err
+	}
+
+	if err := d.downloadChunks(ctx, url, f, state, stateName); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return os.Remove(stateName)
+}
+
+// probe returns the state of a download from scratch, nil if byte ranges are not supported,
+// with the response of the entire resource replied to the probe if any, whose body is left to be read.
+func (d *Downloader) probe(ctx context.Context, url string) (state *downloadState, entire *http.Response, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := DoWithBackoff(req, d.backOffOptions(d.client().Do)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		// byte ranges are not supported, save the entire resource replied
+		return nil, resp, nil
+	}
+	defer resp.Body.Close()
+	var size int64
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		cr, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil || cr == nil || cr.completeLength <= 0 {
+			// size unknown
+			return nil, nil, nil
+		}
+		size = cr.completeLength
+	case http.StatusRequestedRangeNotSatisfiable:
+		// "bytes */0", the resource is empty
+		cr, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil || cr == nil || cr.completeLength != 0 {
+			return nil, nil, fmt.Errorf("http: download %s: unexpected status %s", url, resp.Status)
+		}
+	default:
+		if resp.StatusCode >= 300 {
+			return nil, nil, fmt.Errorf("http: download %s: unexpected status %s", url, resp.Status)
+		}
+		return nil, nil, nil
+	}
+	chunkSize := d.chunkSize()
+	return &downloadState{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Size:         size,
+		ChunkSize:    chunkSize,
+		Done:         make([]bool, (size+chunkSize-1)/chunkSize),
+	}, nil, nil
+}
+
+func (d *Downloader) downloadChunks(ctx context.Context, url string, f *os.File, state *downloadState, stateName string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var pending []int
+	for i, done := range state.Done {
+		if !done {
+			pending = append(pending, i)
+		}
+	}
+
+	chunks := make(chan int)
+	var mu sync.Mutex // guards state and firstErr
+	var firstErr error
+	var wg sync.WaitGroup
+	for i := 0; i < d.concurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range chunks {
+				err := d.downloadChunk(ctx, url, f, state, i)
+				mu.Lock()
+				if err == nil {
+					state.Done[i] = true
+					err = writeDownloadState(stateName, state)
+				}
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, i := range pending {
+		select {
+		case chunks <- i:
+			continue
+		case <-ctx.Done():
+		}
+		break
+	}
+	close(chunks)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// downloadChunk downloads the i-th chunk into f, retried by DoWithBackoff, even if failed in reading the body.
+func (d *Downloader) downloadChunk(ctx context.Context, url string, f *os.File, state *downloadState, i int) error {
+	first, last := state.chunk(i)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", first, last))
+	// a 200 with the entire resource is returned if changed
+	if etag := state.ETag; etag != "" && !strings.HasPrefix(etag, "W/") {
+		req.Header.Set("If-Range", etag)
+	} else if state.LastModified != "" {
+		req.Header.Set("If-Range", state.LastModified)
+	}
+
+	resp, err := DoWithBackoff(req, d.backOffOptions(func(req *http.Request) (*http.Response, error) {
+		resp, err := d.client().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusPartialContent {
+			// let RetryAfter tell whether to retry by the status code
+			return resp, nil
+		}
+		defer resp.Body.Close()
+		if err := verifyChunk(resp, state, first, last); err != nil {
+			return nil, err
+		}
+		n, err := io.Copy(io.NewOffsetWriter(f, first), io.LimitReader(resp.Body, last-first+1))
+		if err != nil {
+			return nil, err
+		}
+		if n != last-first+1 {
+			return nil, fmt.Errorf("http: download %s: chunk [%d, %d] truncated at %d: %w", url, first, last, first+n, io.ErrUnexpectedEOF)
+		}
+		return resp, nil
+	})...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK && req.Header.Get("If-Range") != "" {
+		return fmt.Errorf("http: download %s: %w", url, ErrDownloadChanged)
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("http: download %s: chunk [%d, %d]: unexpected status %s", url, first, last, resp.Status)
+	}
+	return nil
+}
+
+// verifyChunk verifies the chunk is the range requested of the same resource.
+func verifyChunk(resp *http.Response, state *downloadState, first, last int64) error {
+	if etag := resp.Header.Get("ETag"); state.ETag != "" && etag != state.ETag {
+		return fmt.Errorf("http: download %s: ETag %s, want %s: %w", state.URL, etag, state.ETag, ErrDownloadChanged)
+	}
+	if lm := resp.Header.Get("Last-Modified"); state.LastModified != "" && lm != state.LastModified {
+		return fmt.Errorf("http: download %s: Last-Modified %s, want %s: %w", state.URL, lm, state.LastModified, ErrDownloadChanged)
+	}
+	cr, err := parseContentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return err
+	}
+	if cr == nil || cr.firstBytePos != first || cr.lastBytePos != last || cr.completeLength != state.Size {
+		return fmt.Errorf("http: download %s: Content-Range %q, want bytes %d-%d/%d: %w",
+			state.URL, resp.Header.Get("Content-Range"), first, last, state.Size, ErrDownloadChanged)
+	}
+	return nil
+}
+
+// downloadEntirely downloads url into the file name at once, restarted from scratch by every retry.
+// The entire resource is saved from the body of resp first if not nil, downloaded again if failed in reading.
+func (d *Downloader) downloadEntirely(ctx context.Context, url, name string, resp *http.Response) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if resp != nil {
+		err := saveEntirely(f, resp)
+		_ = resp.Body.Close()
+		if err == nil {
+			_ = os.Remove(name + DownloadStateSuffix)
+			return f.Sync()
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err = DoWithBackoff(req, d.backOffOptions(func(req *http.Request) (*http.Response, error) {
+		resp, err := d.client().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return resp, nil
+		}
+		defer resp.Body.Close()
+		if err := saveEntirely(f, resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	})...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http: download %s: unexpected status %s", url, resp.Status)
+	}
+	_ = os.Remove(name + DownloadStateSuffix)
+	return f.Sync()
+}
+
+// saveEntirely writes the body of resp into f from scratch.
+func saveEntirely(f *os.File, resp *http.Response) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := io.Copy(io.NewOffsetWriter(f, 0), resp.Body)
+	return err
+}
+
+// backOffOptions returns options of DoWithBackoff sending requests by do, never retried if ErrDownloadChanged.
+func (d *Downloader) backOffOptions(do func(req *http.Request) (*http.Response, error)) []DoWithBackoffOption {
+	var opts []DoWithBackoffOption
+	opts = append(opts, WithDoWithBackoffOptionRetryAfter(func(resp *http.Response, err error, defaultBackoff time.Duration) (time.Duration, bool) {
+		if errors.Is(err, ErrDownloadChanged) {
+			return defaultBackoff, false
+		}
+		return RetryAfter(resp, err, defaultBackoff)
+	}))
+	opts = append(opts, d.BackOffOptions...)
+	opts = append(opts, WithDoWithBackoffOptionDoRetryHandler(func(req *http.Request, retry int) (*http.Response, error) {
+		return do(req)
+	}))
+	return opts
+}
+
+func (d *Downloader) client() *http.Client {
+	if d.Client != nil {
+		return d.Client
+	}
+	return http.DefaultClient
+}
+
+func (d *Downloader) concurrency() int {
+	if d.Concurrency > 0 {
+		return d.Concurrency
+	}
+	return DefaultDownloadConcurrency
+}
+
+func (d *Downloader) chunkSize() int64 {
+	if d.ChunkSize > 0 {
+		return d.ChunkSize
+	}
+	return DefaultDownloadChunkSize
+}
+
+func readDownloadState(name string) (*downloadState, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var state downloadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// writeDownloadState writes state to a temporary file renamed to name, so that a partial state is never read.
+func writeDownloadState(name string, state *downloadState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
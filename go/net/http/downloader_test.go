@@ -0,0 +1,195 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	http_ "github.com/searKing/golang/go/net/http"
+	time_ "github.com/searKing/golang/go/time"
+)
+
+func downloadContent(size int) []byte {
+	content := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(content)
+	return content
+}
+
+func serveContent(content []byte, etag string) http.HandlerFunc {
+	modtime := time.Unix(1e9, 0)
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "", modtime, bytes.NewReader(content))
+	}
+}
+
+func checkDownloaded(t *testing.T, name string, content []byte) {
+	t.Helper()
+	got, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatalf("downloaded %d bytes mismatched, want %d bytes", len(got), len(content))
+	}
+	if _, err := os.Stat(name + http_.DownloadStateSuffix); !os.IsNotExist(err) {
+		t.Errorf("state file left: %v", err)
+	}
+}
+
+func TestDownloader_Download(t *testing.T) {
+	content := downloadContent(100 << 10)
+	var ranges int32
+	handler := serveContent(content, `"v1"`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "bytes=0-0" {
+			atomic.AddInt32(&ranges, 1)
+		}
+		handler(w, r)
+	}))
+	defer srv.Close()
+
+	name := filepath.Join(t.TempDir(), "file")
+	d := &http_.Downloader{ChunkSize: 10 << 10}
+	if err := d.Download(context.Background(), srv.URL, name); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, name, content)
+	if got := atomic.LoadInt32(&ranges); got != 10 {
+		t.Errorf("requested %d chunks, want %d", got, 10)
+	}
+}
+
+func TestDownloader_Resume(t *testing.T) {
+	content := downloadContent(100 << 10)
+	var chunks, failAfter int32 = 0, 3
+	handler := serveContent(content, `"v1"`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "bytes=0-0" && atomic.AddInt32(&chunks, 1) > atomic.LoadInt32(&failAfter) {
+			http.NotFound(w, r)
+			return
+		}
+		handler(w, r)
+	}))
+	defer srv.Close()
+
+	name := filepath.Join(t.TempDir(), "file")
+	d := &http_.Downloader{ChunkSize: 10 << 10, Concurrency: 1}
+	if err := d.Download(context.Background(), srv.URL, name); err == nil {
+		t.Fatal("interrupted, want error")
+	}
+	if _, err := os.Stat(name + http_.DownloadStateSuffix); err != nil {
+		t.Fatalf("no state file: %s", err)
+	}
+
+	atomic.StoreInt32(&chunks, 0)
+	atomic.StoreInt32(&failAfter, 100)
+	if err := d.Download(context.Background(), srv.URL, name); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, name, content)
+	if got, want := atomic.LoadInt32(&chunks), int32(10-3); got != want {
+		t.Errorf("resumed, requested %d chunks, want %d", got, want)
+	}
+}
+
+func TestDownloader_Changed(t *testing.T) {
+	content := downloadContent(100 << 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") == "bytes=0-0" {
+			serveContent(content, `"v1"`)(w, r)
+			return
+		}
+		serveContent(content, `"v2"`)(w, r)
+	}))
+	defer srv.Close()
+
+	d := &http_.Downloader{ChunkSize: 10 << 10}
+	err := d.Download(context.Background(), srv.URL, filepath.Join(t.TempDir(), "file"))
+	if !errors.Is(err, http_.ErrDownloadChanged) {
+		t.Fatalf("got %v, want %v", err, http_.ErrDownloadChanged)
+	}
+}
+
+func TestDownloader_RetryTruncated(t *testing.T) {
+	content := downloadContent(100 << 10)
+	var truncated int32
+	handler := serveContent(content, `"v1"`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") == "bytes=10240-20479" && atomic.AddInt32(&truncated, 1) == 1 {
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Last-Modified", time.Unix(1e9, 0).UTC().Format(http.TimeFormat))
+			w.Header().Set("Content-Range", "bytes 10240-20479/"+strconv.Itoa(len(content)))
+			w.Header().Set("Content-Length", "10240")
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(content[10240:10340])
+			return
+		}
+		handler(w, r)
+	}))
+	defer srv.Close()
+
+	name := filepath.Join(t.TempDir(), "file")
+	d := &http_.Downloader{ChunkSize: 10 << 10, BackOffOptions: []http_.DoWithBackoffOption{
+		http_.WithDoWithBackoffOptionExponentialBackOffOption(time_.WithExponentialBackOffOptionInitialInterval(time.Millisecond)),
+	}}
+	if err := d.Download(context.Background(), srv.URL, name); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, name, content)
+	if got := atomic.LoadInt32(&truncated); got != 2 {
+		t.Errorf("truncated chunk requested %d times, want %d", got, 2)
+	}
+}
+
+func TestDownloader_RangeNotSupported(t *testing.T) {
+	content := downloadContent(10 << 10)
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_, _ = w.Write(content)
+	}))
+	defer srv.Close()
+
+	name := filepath.Join(t.TempDir(), "file")
+	d := &http_.Downloader{ChunkSize: 1 << 10}
+	if err := d.Download(context.Background(), srv.URL, name); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, name, content)
+	// the entire resource replied to the probe is saved
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("requested %d times, want %d", got, 1)
+	}
+}
+
+func TestDownloader_Empty(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "bytes */0")
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+	}))
+	defer srv.Close()
+
+	name := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(name, []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d := &http_.Downloader{ChunkSize: 1 << 10}
+	if err := d.Download(context.Background(), srv.URL, name); err != nil {
+		t.Fatal(err)
+	}
+	checkDownloaded(t, name, nil)
+}
@@ -6,8 +6,10 @@ package http
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/searKing/golang/go/net/http/internal"
+	"github.com/searKing/golang/go/time/rate"
 )
 
 func WithHandlerInterceptor(
@@ -52,3 +54,10 @@ func WithHandlerInterceptor(
 		})
 	})
 }
+
+// WithHandlerInterceptorRateLimit appends a rate limiting interceptor, see RateLimitServerInterceptor.
+func WithHandlerInterceptorRateLimit(limiter rate.Limiter, timeout time.Duration) HandlerInterceptorChainOption {
+	return WithHandlerInterceptor(nil, func(h http.Handler) http.Handler {
+		return RateLimitServerInterceptor(h, limiter, timeout)
+	}, nil, nil)
+}
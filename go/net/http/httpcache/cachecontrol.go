@@ -0,0 +1,164 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httpcache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cacheControl holds directives of Cache-Control, lowercased, with arguments unquoted.
+// See: https://www.rfc-editor.org/rfc/rfc9111#section-5.2
+type cacheControl map[string]string
+
+func parseCacheControl(h http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, line := range h.Values("Cache-Control") {
+		for _, directive := range strings.Split(line, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			cc[name] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return cc
+}
+
+// requestCacheControl returns Cache-Control of a request, "Pragma: no-cache" is taken as no-cache if no Cache-Control.
+// See: https://www.rfc-editor.org/rfc/rfc9111#section-5.4
+func requestCacheControl(h http.Header) cacheControl {
+	cc := parseCacheControl(h)
+	if len(h.Values("Cache-Control")) == 0 && strings.EqualFold(strings.TrimSpace(h.Get("Pragma")), "no-cache") {
+		cc["no-cache"] = ""
+	}
+	return cc
+}
+
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// seconds returns the delta-seconds argument of the directive name, false if absent or invalid.
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	v, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// headerTime returns the HTTP-date of header name, false if absent or invalid.
+func headerTime(h http.Header, name string) (time.Time, bool) {
+	v := h.Get(name)
+	if v == "" {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// freshnessLifetime returns the freshness lifetime of a response received at responseTime, by max-age, Expires,
+// or a heuristic of 10% of the time since Last-Modified.
+// s-maxage is ignored, as a private cache.
+// See: https://www.rfc-editor.org/rfc/rfc9111#section-4.2.1
+func freshnessLifetime(h http.Header, cc cacheControl, responseTime time.Time) time.Duration {
+	if maxAge, ok := cc.seconds("max-age"); ok {
+		return maxAge
+	}
+	date, ok := headerTime(h, "Date")
+	if !ok {
+		date = responseTime
+	}
+	if len(h.Values("Expires")) > 0 {
+		// an invalid date, as "0", represents a time in the past
+		expires, ok := headerTime(h, "Expires")
+		if !ok || !expires.After(date) {
+			return 0
+		}
+		return expires.Sub(date)
+	}
+	if lastModified, ok := headerTime(h, "Last-Modified"); ok && date.After(lastModified) {
+		return date.Sub(lastModified) / 10
+	}
+	return 0
+}
+
+// currentAge returns the age of a response requested at requestTime, received at responseTime, at now.
+// See: https://www.rfc-editor.org/rfc/rfc9111#section-4.2.3
+func currentAge(h http.Header, requestTime, responseTime, now time.Time) time.Duration {
+	date, ok := headerTime(h, "Date")
+	if !ok {
+		date = responseTime
+	}
+	var ageValue time.Duration
+	if age, err := strconv.ParseInt(h.Get("Age"), 10, 64); err == nil && age > 0 {
+		ageValue = time.Duration(age) * time.Second
+	}
+	apparentAge := responseTime.Sub(date)
+	if apparentAge < 0 {
+		apparentAge = 0
+	}
+	responseDelay := responseTime.Sub(requestTime)
+	correctedAgeValue := ageValue + responseDelay
+	correctedInitialAge := apparentAge
+	if correctedAgeValue > correctedInitialAge {
+		correctedInitialAge = correctedAgeValue
+	}
+	residentTime := now.Sub(responseTime)
+	return correctedInitialAge + residentTime
+}
+
+// heuristicallyCacheable holds status codes cacheable by default.
+// See: https://www.rfc-editor.org/rfc/rfc9110#section-15.1
+var heuristicallyCacheable = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// storable reports whether the response of req can be stored.
+// Partial content is never stored, as Range is not supported.
+// See: https://www.rfc-editor.org/rfc/rfc9111#section-3
+func storable(req *http.Request, reqCC cacheControl, resp *http.Response) bool {
+	if req.Method != http.MethodGet || resp.StatusCode == http.StatusPartialContent {
+		return false
+	}
+	cc := parseCacheControl(resp.Header)
+	if reqCC.has("no-store") || cc.has("no-store") {
+		return false
+	}
+	if req.Header.Get("Authorization") != "" &&
+		!cc.has("public") && !cc.has("must-revalidate") && !cc.has("s-maxage") {
+		return false
+	}
+	if strings.TrimSpace(resp.Header.Get("Vary")) == "*" {
+		return false
+	}
+	if heuristicallyCacheable[resp.StatusCode] {
+		return true
+	}
+	explicit := cc.has("max-age") || cc.has("public") || len(resp.Header.Values("Expires")) > 0
+	return explicit && resp.StatusCode >= 200 && resp.StatusCode < 600 && resp.StatusCode != http.StatusNotModified
+}
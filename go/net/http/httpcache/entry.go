@@ -0,0 +1,221 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httpcache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// freshness tells how a stored response can be used.
+type freshness int
+
+const (
+	fresh                freshness = iota // served without validation
+	stale                                 // validated before served
+	staleWhileRevalidate                  // served, and validated in background
+)
+
+// entry is a response stored, or fetched from the origin.
+type entry struct {
+	Status       string
+	StatusCode   int
+	Proto        string
+	ProtoMajor   int
+	ProtoMinor   int
+	Header       http.Header
+	Body         []byte
+	RequestTime  time.Time
+	ResponseTime time.Time
+
+	// RequestHeader holds the request header fields nominated by Vary.
+	RequestHeader http.Header
+}
+
+func decodeEntry(data []byte) (*entry, error) {
+	var e entry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (e *entry) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(e); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// stored returns a copy of e to store for req, with request header fields nominated by Vary.
+func (e *entry) stored(req *http.Request) *entry {
+	s := *e
+	s.Header = e.Header.Clone()
+	s.RequestHeader = http.Header{}
+	for _, name := range varyNames(e.Header) {
+		for _, v := range req.Header.Values(name) {
+			s.RequestHeader.Add(name, v)
+		}
+	}
+	return &s
+}
+
+// varyNames returns the request header field names nominated by Vary.
+func varyNames(h http.Header) []string {
+	var names []string
+	for _, line := range h.Values("Vary") {
+		for _, name := range strings.Split(line, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				names = append(names, textproto.CanonicalMIMEHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// varyMatches reports whether req selects e, by request header fields nominated by Vary.
+// See: https://www.rfc-editor.org/rfc/rfc9111#section-4.1
+func (e *entry) varyMatches(req *http.Request) bool {
+	for _, name := range varyNames(e.Header) {
+		if name == "*" {
+			return false
+		}
+		if normalizeValues(req.Header.Values(name)) != normalizeValues(e.RequestHeader.Values(name)) {
+			return false
+		}
+	}
+	return true
+}
+
+func normalizeValues(values []string) string {
+	var fields []string
+	for _, v := range values {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				fields = append(fields, f)
+			}
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+func (e *entry) age(now time.Time) time.Duration {
+	return currentAge(e.Header, e.RequestTime, e.ResponseTime, now)
+}
+
+// freshness returns the age of e at now, and how e can be used for a request with reqCC.
+// See: https://www.rfc-editor.org/rfc/rfc9111#section-4.2
+// See: https://www.rfc-editor.org/rfc/rfc5861#section-3
+func (e *entry) freshness(reqCC cacheControl, now time.Time) (time.Duration, freshness) {
+	cc := parseCacheControl(e.Header)
+	age := e.age(now)
+	if cc.has("no-cache") || reqCC.has("no-cache") {
+		return age, stale
+	}
+	if maxAge, ok := reqCC.seconds("max-age"); ok && age > maxAge {
+		return age, stale
+	}
+	lifetime := freshnessLifetime(e.Header, cc, e.ResponseTime)
+	if minFresh, ok := reqCC.seconds("min-fresh"); ok {
+		lifetime -= minFresh
+	}
+	if age < lifetime {
+		return age, fresh
+	}
+	if cc.has("must-revalidate") || cc.has("proxy-revalidate") {
+		return age, stale
+	}
+	staleness := age - lifetime
+	if maxStale, ok := reqCC["max-stale"]; ok {
+		if d, ok := reqCC.seconds("max-stale"); maxStale == "" || ok && staleness <= d {
+			return age, fresh
+		}
+	}
+	if d, ok := cc.seconds("stale-while-revalidate"); ok && staleness <= d {
+		return age, staleWhileRevalidate
+	}
+	return age, stale
+}
+
+// staleIfError reports whether e can be served at now if the origin failed.
+// See: https://www.rfc-editor.org/rfc/rfc5861#section-4
+func (e *entry) staleIfError(reqCC cacheControl, now time.Time) bool {
+	cc := parseCacheControl(e.Header)
+	if cc.has("must-revalidate") || cc.has("proxy-revalidate") || cc.has("no-cache") {
+		return false
+	}
+	d, ok := reqCC.seconds("stale-if-error")
+	if !ok {
+		d, ok = cc.seconds("stale-if-error")
+	}
+	if !ok {
+		return false
+	}
+	staleness := e.age(now) - freshnessLifetime(e.Header, cc, e.ResponseTime)
+	return staleness <= d
+}
+
+// validate updates e by a 304 Not Modified response.
+// See: https://www.rfc-editor.org/rfc/rfc9111#section-4.3.4
+func (e *entry) validate(notModified *entry) {
+	for name, values := range notModified.Header {
+		switch name {
+		case "Content-Length", "Transfer-Encoding", "Connection":
+			continue
+		}
+		e.Header[name] = append([]string(nil), values...)
+	}
+	e.RequestTime = notModified.RequestTime
+	e.ResponseTime = notModified.ResponseTime
+}
+
+// withValidators returns a conditional request of req validating e, nil if no validator in e.
+// See: https://www.rfc-editor.org/rfc/rfc9111#section-4.3.1
+func (e *entry) withValidators(req *http.Request) *http.Request {
+	etag := e.Header.Get("ETag")
+	lastModified := e.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return nil
+	}
+	r := req.Clone(req.Context())
+	if etag != "" {
+		r.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		r.Header.Set("If-Modified-Since", lastModified)
+	}
+	return r
+}
+
+// response returns a response of e for req, whose Age is age if served from the cache.
+func (e *entry) response(req *http.Request, fromCache bool, age time.Duration) *http.Response {
+	resp := &http.Response{
+		Status:        e.Status,
+		StatusCode:    e.StatusCode,
+		Proto:         e.Proto,
+		ProtoMajor:    e.ProtoMajor,
+		ProtoMinor:    e.ProtoMinor,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	if fromCache {
+		resp.Header.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+		resp.Header.Set(XFromCache, "1")
+	}
+	return resp
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httpcache
+
+import (
+	"context"
+	"sync"
+)
+
+// call is an in-flight or completed fetch.
+type call struct {
+	done chan struct{}
+	e    *entry
+	err  error
+}
+
+// group coalesces concurrent identical fetches, so that the origin is hit once.
+type group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// do executes fn once for concurrent calls of key, the result is shared by all.
+// shared reports whether the result is of a call of another caller.
+func (g *group) do(ctx context.Context, key string, fn func() (*entry, error)) (e *entry, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.e, true, c.err
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+	}
+	c := &call{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	c.e, c.err = fn()
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(c.done)
+	return c.e, false, c.err
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httpcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/searKing/golang/go/exp/container/lru"
+)
+
+// Storage stores responses cached, keyed by the target URI, safe for concurrent use.
+// A Storage of LevelDB is provided in github.com/searKing/golang/third_party/github.com/syndtr/goleveldb/leveldb.
+type Storage interface {
+	// Get returns the value of key, false if not found.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set sets the value of key.
+	Set(ctx context.Context, key string, value []byte) error
+	// Delete deletes the value of key, no error if not found.
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryStorage is a Storage in memory, evicting the least recently used if full.
+type MemoryStorage struct {
+	mu  sync.Mutex
+	lru *lru.LRU[string, []byte]
+}
+
+// NewMemoryStorage returns a MemoryStorage holding size responses at most.
+func NewMemoryStorage(size int) *MemoryStorage {
+	return &MemoryStorage{lru: lru.New[string, []byte](size)}
+}
+
+func (s *MemoryStorage) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.lru.Get(key)
+	return v, ok, nil
+}
+
+func (s *MemoryStorage) Set(ctx context.Context, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lru.Add(key, value)
+	return nil
+}
+
+func (s *MemoryStorage) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lru.Remove(key)
+	return nil
+}
+
+// DiskStorage is a Storage in a directory, a file per key, named by the SHA-256 of the key.
+type DiskStorage struct {
+	dir string
+}
+
+// NewDiskStorage returns a DiskStorage in dir, created if not exist.
+func NewDiskStorage(dir string) (*DiskStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskStorage{dir: dir}, nil
+}
+
+func (s *DiskStorage) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+func (s *DiskStorage) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Set writes value to a temporary file renamed to the file of key, so that a partial file is never read.
+func (s *DiskStorage) Set(ctx context.Context, key string, value []byte) error {
+	f, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(value); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path(key))
+}
+
+func (s *DiskStorage) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
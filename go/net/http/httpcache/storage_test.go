@@ -0,0 +1,45 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httpcache_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/searKing/golang/go/net/http/httpcache"
+)
+
+func TestStorage(t *testing.T) {
+	disk, err := httpcache.NewDiskStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, s := range map[string]httpcache.Storage{
+		"memory": httpcache.NewMemoryStorage(1),
+		"disk":   disk,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, ok, err := s.Get(ctx, "a"); ok || err != nil {
+				t.Fatalf("Get(a) = %t, %v, want false, nil", ok, err)
+			}
+			if err := s.Set(ctx, "a", []byte("1")); err != nil {
+				t.Fatal(err)
+			}
+			if v, ok, err := s.Get(ctx, "a"); !ok || err != nil || string(v) != "1" {
+				t.Fatalf("Get(a) = %q, %t, %v, want %q, true, nil", v, ok, err, "1")
+			}
+			if err := s.Delete(ctx, "a"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Delete(ctx, "a"); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok, err := s.Get(ctx, "a"); ok || err != nil {
+				t.Fatalf("Get(a) = %t, %v, want false, nil", ok, err)
+			}
+		})
+	}
+}
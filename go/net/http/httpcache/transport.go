@@ -0,0 +1,263 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package httpcache implements a private HTTP cache as a http.RoundTripper, as specified in RFC 9111,
+// honoring Cache-Control, validation by ETag and Last-Modified, Vary, and stale-while-revalidate and
+// stale-if-error of RFC 5861, with storage pluggable.
+//
+// Concurrent identical GET requests are coalesced, so that the origin is hit once.
+// Responses of GET requests are read into memory, which makes it unsuitable for large downloads.
+//
+// See: https://www.rfc-editor.org/rfc/rfc9111
+// See: https://www.rfc-editor.org/rfc/rfc5861
+package httpcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// XFromCache is the header set to "1" on responses served from the cache.
+const XFromCache = "X-From-Cache"
+
+// Transport is a http.RoundTripper caching responses in Storage.
+type Transport struct {
+	// Transport fetches from the origin, http.DefaultTransport if nil.
+	Transport http.RoundTripper
+	// Storage stores responses cached.
+	Storage Storage
+
+	group group
+}
+
+// NewTransport returns a Transport caching responses in storage,
+// fetching from the origin by http.DefaultTransport.
+func NewTransport(storage Storage) *Transport {
+	return &Transport{Storage: storage}
+}
+
+// Client returns a http.Client caching responses.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// WrapRoundTrip returns a Transport caching in the same Storage, fetching from the origin by rt,
+// so that it can be used as a RoundTripDecorator.
+func (t *Transport) WrapRoundTrip(rt http.RoundTripper) http.RoundTripper {
+	return &Transport{Transport: rt, Storage: t.Storage}
+}
+
+func (t *Transport) transport() http.RoundTripper {
+	if t.Transport != nil {
+		return t.Transport
+	}
+	return http.DefaultTransport
+}
+
+// RoundTrip serves req from the cache if possible, or fetches from the origin and stores the response.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.roundTripUncached(req)
+	}
+	// Range and conditional requests made by the caller are passed through.
+	if req.Header.Get("Range") != "" || isConditional(req) {
+		return t.transport().RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+	reqCC := requestCacheControl(req.Header)
+	now := time.Now()
+	e := t.load(req.Context(), key, req)
+	if e != nil {
+		age, state := e.freshness(reqCC, now)
+		switch state {
+		case fresh:
+			return e.response(req, true, age), nil
+		case staleWhileRevalidate:
+			if !reqCC.has("only-if-cached") {
+				go t.revalidate(req, key, reqCC, e)
+			}
+			return e.response(req, true, age), nil
+		}
+	}
+	// See: https://www.rfc-editor.org/rfc/rfc9111#section-5.2.1.7
+	if reqCC.has("only-if-cached") {
+		return gatewayTimeout(req), nil
+	}
+	return t.fetch(req, key, reqCC, e)
+}
+
+// roundTripUncached passes req through, and invalidates the responses stored of the target URI,
+// and of the Location and Content-Location of the same origin, if an unsafe method succeeded.
+// See: https://www.rfc-editor.org/rfc/rfc9111#section-4.4
+func (t *Transport) roundTripUncached(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport().RoundTrip(req)
+	if err != nil || isSafeMethod(req.Method) || resp.StatusCode >= 400 {
+		return resp, err
+	}
+	ctx := req.Context()
+	_ = t.Storage.Delete(ctx, cacheKey(req))
+	for _, name := range []string{"Location", "Content-Location"} {
+		v := resp.Header.Get(name)
+		if v == "" {
+			continue
+		}
+		u, err := req.URL.Parse(v)
+		if err != nil || u.Scheme != req.URL.Scheme || u.Host != req.URL.Host {
+			continue
+		}
+		_ = t.Storage.Delete(ctx, cacheKey(&http.Request{URL: u}))
+	}
+	return resp, err
+}
+
+// fetch fetches from the origin, validating e if not nil, and stores the response.
+func (t *Transport) fetch(req *http.Request, key string, reqCC cacheControl, e *entry) (*http.Response, error) {
+	outReq := req
+	if e != nil {
+		if r := e.withValidators(req); r != nil {
+			outReq = r
+		}
+	}
+	res, err := t.do(outReq)
+	now := time.Now()
+	if e != nil && (err != nil || res.StatusCode >= http.StatusInternalServerError) && e.staleIfError(reqCC, now) {
+		return e.response(req, true, e.age(now)), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if e != nil && outReq != req && res.StatusCode == http.StatusNotModified {
+		e.validate(res)
+		t.store(req.Context(), key, e)
+		return e.response(req, true, e.age(now)), nil
+	}
+
+	resp := res.response(req, false, 0)
+	if storable(req, reqCC, resp) {
+		t.store(req.Context(), key, res.stored(req))
+	}
+	return resp, nil
+}
+
+// revalidate validates e in background, detached from the context of req.
+func (t *Transport) revalidate(req *http.Request, key string, reqCC cacheControl, e *entry) {
+	resp, err := t.fetch(req.Clone(context.Background()), key, reqCC, e)
+	if err == nil {
+		resp.Body.Close()
+	}
+}
+
+// do fetches req from the origin, coalesced with concurrent identical requests.
+func (t *Transport) do(req *http.Request) (*entry, error) {
+	e, shared, err := t.group.do(req.Context(), coalesceKey(req), func() (*entry, error) {
+		return t.roundTrip(req)
+	})
+	// the request shared is canceled, but not this one
+	if shared && err != nil && req.Context().Err() == nil &&
+		(errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		return t.roundTrip(req)
+	}
+	return e, err
+}
+
+func (t *Transport) roundTrip(req *http.Request) (*entry, error) {
+	requestTime := time.Now()
+	resp, err := t.transport().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &entry{
+		Status:       resp.Status,
+		StatusCode:   resp.StatusCode,
+		Proto:        resp.Proto,
+		ProtoMajor:   resp.ProtoMajor,
+		ProtoMinor:   resp.ProtoMinor,
+		Header:       resp.Header,
+		Body:         body,
+		RequestTime:  requestTime,
+		ResponseTime: time.Now(),
+	}, nil
+}
+
+// load returns the response stored of key selected by req, nil if not found.
+func (t *Transport) load(ctx context.Context, key string, req *http.Request) *entry {
+	data, ok, err := t.Storage.Get(ctx, key)
+	if err != nil || !ok {
+		return nil
+	}
+	e, err := decodeEntry(data)
+	if err != nil || !e.varyMatches(req) {
+		return nil
+	}
+	return e
+}
+
+// store stores e as key, the cache is best-effort, so errors are ignored.
+func (t *Transport) store(ctx context.Context, key string, e *entry) {
+	data, err := e.encode()
+	if err != nil {
+		return
+	}
+	_ = t.Storage.Set(ctx, key, data)
+}
+
+// cacheKey returns the key of responses of req, the target URI without fragment.
+func cacheKey(req *http.Request) string {
+	u := *req.URL
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
+
+// coalesceKey returns the key of identical requests.
+func coalesceKey(req *http.Request) string {
+	var b strings.Builder
+	b.WriteString(req.Method)
+	b.WriteString(" ")
+	b.WriteString(cacheKey(req))
+	b.WriteString("\n")
+	_ = req.Header.Write(&b)
+	return b.String()
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func isConditional(req *http.Request) bool {
+	for _, name := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "If-Range"} {
+		if req.Header.Get(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func gatewayTimeout(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", http.StatusGatewayTimeout, http.StatusText(http.StatusGatewayTimeout)),
+		StatusCode: http.StatusGatewayTimeout,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+		Request:    req,
+	}
+}
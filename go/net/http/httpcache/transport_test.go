@@ -0,0 +1,249 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httpcache_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/net/http/httpcache"
+)
+
+func get(t *testing.T, c *http.Client, url string, header http.Header) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func TestTransport_Fresh(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = io.WriteString(w, "hello")
+	}))
+	defer srv.Close()
+
+	c := httpcache.NewTransport(httpcache.NewMemoryStorage(10)).Client()
+	resp, body := get(t, c, srv.URL, nil)
+	if resp.Header.Get(httpcache.XFromCache) != "" || body != "hello" {
+		t.Fatalf("first, got %q from cache %q", body, resp.Header.Get(httpcache.XFromCache))
+	}
+	resp, body = get(t, c, srv.URL, nil)
+	if resp.Header.Get(httpcache.XFromCache) != "1" || body != "hello" {
+		t.Fatalf("second, got %q from cache %q", body, resp.Header.Get(httpcache.XFromCache))
+	}
+	if resp.Header.Get("Age") == "" {
+		t.Errorf("no Age served from cache")
+	}
+	// bypass the cache
+	get(t, c, srv.URL, http.Header{"Cache-Control": {"no-cache"}})
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("server hit %d times, want %d", got, 2)
+	}
+}
+
+func TestTransport_Revalidate(t *testing.T) {
+	for _, validator := range []string{"ETag", "Last-Modified"} {
+		t.Run(validator, func(t *testing.T) {
+			var hits, notModified int32
+			lastModified := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&hits, 1)
+				w.Header().Set("Cache-Control", "no-cache")
+				switch validator {
+				case "ETag":
+					w.Header().Set("ETag", `"v1"`)
+					if r.Header.Get("If-None-Match") == `"v1"` {
+						atomic.AddInt32(&notModified, 1)
+						w.WriteHeader(http.StatusNotModified)
+						return
+					}
+				case "Last-Modified":
+					w.Header().Set("Last-Modified", lastModified)
+					if r.Header.Get("If-Modified-Since") == lastModified {
+						atomic.AddInt32(&notModified, 1)
+						w.WriteHeader(http.StatusNotModified)
+						return
+					}
+				}
+				_, _ = io.WriteString(w, "hello")
+			}))
+			defer srv.Close()
+
+			c := httpcache.NewTransport(httpcache.NewMemoryStorage(10)).Client()
+			get(t, c, srv.URL, nil)
+			resp, body := get(t, c, srv.URL, nil)
+			if resp.StatusCode != http.StatusOK || body != "hello" || resp.Header.Get(httpcache.XFromCache) != "1" {
+				t.Fatalf("revalidated, got %d %q from cache %q", resp.StatusCode, body, resp.Header.Get(httpcache.XFromCache))
+			}
+			if got, want := atomic.LoadInt32(&hits), int32(2); got != want {
+				t.Errorf("server hit %d times, want %d", got, want)
+			}
+			if got, want := atomic.LoadInt32(&notModified), int32(1); got != want {
+				t.Errorf("server not modified %d times, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestTransport_Vary(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		_, _ = io.WriteString(w, r.Header.Get("Accept-Language"))
+	}))
+	defer srv.Close()
+
+	c := httpcache.NewTransport(httpcache.NewMemoryStorage(10)).Client()
+	get(t, c, srv.URL, http.Header{"Accept-Language": {"en"}})
+	if _, body := get(t, c, srv.URL, http.Header{"Accept-Language": {"en"}}); body != "en" {
+		t.Errorf("en, got %q", body)
+	}
+	if _, body := get(t, c, srv.URL, http.Header{"Accept-Language": {"fr"}}); body != "fr" {
+		t.Errorf("fr, got %q", body)
+	}
+	if got, want := atomic.LoadInt32(&hits), int32(2); got != want {
+		t.Errorf("server hit %d times, want %d", got, want)
+	}
+}
+
+func TestTransport_StaleWhileRevalidate(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit := atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=0, stale-while-revalidate=60")
+		if hit == 1 {
+			_, _ = io.WriteString(w, "v1")
+			return
+		}
+		_, _ = io.WriteString(w, "v2")
+	}))
+	defer srv.Close()
+
+	c := httpcache.NewTransport(httpcache.NewMemoryStorage(10)).Client()
+	get(t, c, srv.URL, nil)
+	if resp, body := get(t, c, srv.URL, nil); body != "v1" || resp.Header.Get(httpcache.XFromCache) != "1" {
+		t.Fatalf("stale, got %q from cache %q", body, resp.Header.Get(httpcache.XFromCache))
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, body := get(t, c, srv.URL, nil); body == "v2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("not revalidated in background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTransport_StaleIfError(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=0, stale-if-error=60")
+		_, _ = io.WriteString(w, "hello")
+	}))
+	defer srv.Close()
+
+	c := httpcache.NewTransport(httpcache.NewMemoryStorage(10)).Client()
+	get(t, c, srv.URL, nil)
+	if resp, body := get(t, c, srv.URL, nil); resp.StatusCode != http.StatusOK || body != "hello" {
+		t.Errorf("origin failed, got %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestTransport_Coalesce(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		_, _ = io.WriteString(w, "hello")
+	}))
+	defer srv.Close()
+
+	c := httpcache.NewTransport(httpcache.NewMemoryStorage(10)).Client()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := c.Get(srv.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer resp.Body.Close()
+			if body, _ := io.ReadAll(resp.Body); string(body) != "hello" {
+				t.Errorf("got %q", body)
+			}
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("server hit %d times, want %d", got, 1)
+	}
+}
+
+func TestTransport_Invalidate(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&hits, 1)
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+	}))
+	defer srv.Close()
+
+	c := httpcache.NewTransport(httpcache.NewMemoryStorage(10)).Client()
+	get(t, c, srv.URL, nil)
+	get(t, c, srv.URL, nil)
+	resp, err := c.Post(srv.URL, "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	get(t, c, srv.URL, nil)
+	if got, want := atomic.LoadInt32(&hits), int32(2); got != want {
+		t.Errorf("server hit %d times, want %d", got, want)
+	}
+}
+
+func TestTransport_OnlyIfCached(t *testing.T) {
+	c := httpcache.NewTransport(httpcache.NewMemoryStorage(10)).Client()
+	resp, _ := get(t, c, "http://127.0.0.1:1/", http.Header{"Cache-Control": {"only-if-cached"}})
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("got %d, want %d", resp.StatusCode, http.StatusGatewayTimeout)
+	}
+}
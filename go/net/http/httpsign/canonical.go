@@ -0,0 +1,145 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package httpsign signs HTTP requests, by a generic HMAC scheme with replay protection verified on the server side,
+// or by AWS Signature Version 4, for S3 compatible stores.
+package httpsign
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// emptySHA256 is the hex encoded SHA-256 of an empty payload.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func hexSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// payloadSHA256 returns the hex encoded SHA-256 of the body of req, which is rewound to be sent.
+func payloadSHA256(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return emptySHA256, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, body); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	_ = req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return hexSHA256(data), nil
+}
+
+// escape percent-encodes s as RFC 3986, all but unreserved characters, and '/' if keepSlash.
+func escape(s string, keepSlash bool) string {
+	const upperhex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || keepSlash && c == '/' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(upperhex[c>>4])
+		b.WriteByte(upperhex[c&15])
+	}
+	return b.String()
+}
+
+// canonicalQuery returns the query of u, sorted by name then value, percent-encoded as RFC 3986.
+func canonicalQuery(u *url.URL) string {
+	query := u.Query()
+	var pairs []string
+	for name, values := range query {
+		for _, v := range values {
+			pairs = append(pairs, escape(name, false)+"="+escape(v, false))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// canonicalHeaders returns the canonical headers and the signed headers, of names signed, lowercased and sorted.
+func canonicalHeaders(req *http.Request, names []string) (canonical, signed string) {
+	var lower []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if !seen[name] {
+			seen[name] = true
+			lower = append(lower, name)
+		}
+	}
+	sort.Strings(lower)
+
+	var b strings.Builder
+	for _, name := range lower {
+		var values []string
+		if name == "host" {
+			values = []string{host(req)}
+		} else {
+			values = req.Header.Values(name)
+		}
+		b.WriteString(name)
+		b.WriteByte(':')
+		for i, v := range values {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			// trim and collapse sequential spaces
+			b.WriteString(strings.Join(strings.Fields(v), " "))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), strings.Join(lower, ";")
+}
+
+func host(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+// parseAuthorization parses an Authorization of scheme, as "scheme k1=v1, k2=v2".
+func parseAuthorization(authorization, scheme string) (map[string]string, bool) {
+	params, ok := strings.CutPrefix(authorization, scheme+" ")
+	if !ok {
+		return nil, false
+	}
+	m := map[string]string{}
+	for _, param := range strings.Split(params, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			return nil, false
+		}
+		m[k] = v
+	}
+	return m, true
+}
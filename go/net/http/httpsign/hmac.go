@@ -0,0 +1,246 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httpsign
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	hmac_ "github.com/searKing/golang/go/crypto/hmac"
+	rand_ "github.com/searKing/golang/go/crypto/rand"
+)
+
+const (
+	// HMACScheme is the scheme of Authorization signed by HMAC.
+	HMACScheme = "HMAC-SHA256"
+	// HeaderTimestamp is the header of the time signed, in unix seconds.
+	HeaderTimestamp = "X-Signature-Timestamp"
+	// HeaderNonce is the header of the nonce signed, random per request.
+	HeaderNonce = "X-Signature-Nonce"
+	// HeaderContentSHA256 is the header of the hex encoded SHA-256 of the body signed.
+	HeaderContentSHA256 = "X-Content-Sha256"
+
+	// DefaultMaxSkew is the max difference between the time signed and the time verified, if not set.
+	DefaultMaxSkew = 5 * time.Minute
+)
+
+var (
+	// ErrSignatureMissing is returned if a request is not signed.
+	ErrSignatureMissing = errors.New("httpsign: signature missing")
+	// ErrSignatureMismatch is returned if the signature mismatches, as tampered.
+	ErrSignatureMismatch = errors.New("httpsign: signature mismatch")
+	// ErrSignatureExpired is returned if the time signed is out of the max skew.
+	ErrSignatureExpired = errors.New("httpsign: signature expired")
+	// ErrSignatureReplayed is returned if the nonce has been seen, as replayed.
+	ErrSignatureReplayed = errors.New("httpsign: signature replayed")
+)
+
+// HMACSigner signs requests by HMAC-SHA256 with a secret key shared with the server:
+//
+//	CanonicalRequest = Method + '\n' + EscapedPath + '\n' + CanonicalQuery + '\n' +
+//		CanonicalHeaders + '\n' + SignedHeaders + '\n' + HexSHA256(Body)
+//	StringToSign = "HMAC-SHA256" + '\n' + Timestamp + '\n' + Nonce + '\n' + HexSHA256(CanonicalRequest)
+//	Signature = HexHMACSHA256(Key, StringToSign)
+//	Authorization: HMAC-SHA256 KeyId=<KeyID>,SignedHeaders=<SignedHeaders>,Signature=<Signature>
+//
+// The Host, HeaderTimestamp, HeaderNonce and HeaderContentSHA256 are always signed.
+type HMACSigner struct {
+	// KeyID identifies the Key to the server.
+	KeyID string
+	// Key is the secret key.
+	Key []byte
+	// SignedHeaders are headers signed besides, as Content-Type.
+	SignedHeaders []string
+
+	// Now returns the time signed, time.Now if nil.
+	Now func() time.Time
+	// Nonce returns a nonce per request, 16 random bytes hex encoded if nil.
+	Nonce func() (string, error)
+}
+
+// Sign signs req in place, with the body read and rewound.
+func (s *HMACSigner) Sign(req *http.Request) error {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	nonce, err := s.nonce()
+	if err != nil {
+		return err
+	}
+	payload, err := payloadSHA256(req)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now().Unix(), 10))
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderContentSHA256, payload)
+
+	names := append([]string{"host", HeaderTimestamp, HeaderNonce, HeaderContentSHA256}, s.SignedHeaders...)
+	signature, signedHeaders := hmacSignature(req, s.Key, names, payload)
+	req.Header.Set("Authorization", fmt.Sprintf("%s KeyId=%s,SignedHeaders=%s,Signature=%s",
+		HMACScheme, s.KeyID, signedHeaders, signature))
+	return nil
+}
+
+// WrapRoundTrip returns a http.RoundTripper signing requests sent by rt, http.DefaultTransport if nil,
+// so that it can be used as a RoundTripDecorator.
+func (s *HMACSigner) WrapRoundTrip(rt http.RoundTripper) http.RoundTripper {
+	return signingRoundTripper(rt, s.Sign)
+}
+
+func (s *HMACSigner) nonce() (string, error) {
+	if s.Nonce != nil {
+		return s.Nonce()
+	}
+	b, err := rand_.BytesCrypto(16)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hmacSignature(req *http.Request, key []byte, names []string, payload string) (signature, signedHeaders string) {
+	headers, signedHeaders := canonicalHeaders(req, names)
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, canonicalQuery(req.URL), headers, signedHeaders, payload}, "\n")
+	stringToSign := strings.Join([]string{
+		HMACScheme, req.Header.Get(HeaderTimestamp), req.Header.Get(HeaderNonce), hexSHA256([]byte(canonicalRequest))}, "\n")
+	return hmac_.SumHex(sha256.New, key, stringToSign), signedHeaders
+}
+
+// HMACVerifier verifies requests signed by HMACSigner, rejecting requests out of MaxSkew, or replayed by nonce.
+type HMACVerifier struct {
+	// Key returns the secret key of keyID.
+	Key func(keyID string) ([]byte, error)
+	// MaxSkew is the max difference between the time signed and now, DefaultMaxSkew if not positive.
+	MaxSkew time.Duration
+	// Nonces remembers nonces seen, a NonceCache in memory if nil.
+	Nonces NonceCache
+	// MaxBodySize limits the size of the body read to verify, unlimited if not positive.
+	MaxBodySize int64
+
+	// Now returns the time verified, time.Now if nil.
+	Now func() time.Time
+}
+
+// NewHMACVerifier returns a HMACVerifier verifying with keys returned by key.
+func NewHMACVerifier(key func(keyID string) ([]byte, error)) *HMACVerifier {
+	return &HMACVerifier{Key: key, Nonces: NewMemoryNonceCache()}
+}
+
+// Verify verifies the signature of req, with the body read and rewound.
+func (v *HMACVerifier) Verify(req *http.Request) error {
+	params, ok := parseAuthorization(req.Header.Get("Authorization"), HMACScheme)
+	if !ok || params["KeyId"] == "" || params["SignedHeaders"] == "" || params["Signature"] == "" {
+		return ErrSignatureMissing
+	}
+	names := strings.Split(params["SignedHeaders"], ";")
+	for _, required := range []string{"host", HeaderTimestamp, HeaderNonce, HeaderContentSHA256} {
+		if !containsFold(names, required) {
+			return fmt.Errorf("%w: %s not signed", ErrSignatureMismatch, required)
+		}
+	}
+
+	now := time.Now
+	if v.Now != nil {
+		now = v.Now
+	}
+	maxSkew := v.MaxSkew
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxSkew
+	}
+	ts, err := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed timestamp", ErrSignatureMismatch)
+	}
+	if skew := now().Sub(time.Unix(ts, 0)); skew > maxSkew || skew < -maxSkew {
+		return ErrSignatureExpired
+	}
+
+	key, err := v.Key(params["KeyId"])
+	if err != nil {
+		return err
+	}
+	if v.MaxBodySize > 0 && req.Body != nil {
+		req.Body = http.MaxBytesReader(nil, req.Body, v.MaxBodySize)
+	}
+	payload, err := payloadSHA256(req)
+	if err != nil {
+		return err
+	}
+	if payload != req.Header.Get(HeaderContentSHA256) {
+		return fmt.Errorf("%w: body", ErrSignatureMismatch)
+	}
+	signature, _ := hmacSignature(req, key, names, payload)
+	if !hmac.Equal([]byte(signature), []byte(params["Signature"])) {
+		return ErrSignatureMismatch
+	}
+
+	// remember nonces after verified, so that a forged request can not burn a nonce.
+	nonces := v.Nonces
+	if nonces == nil {
+		return nil
+	}
+	if !nonces.Add(params["KeyId"]+"/"+req.Header.Get(HeaderNonce), 2*maxSkew) {
+		return ErrSignatureReplayed
+	}
+	return nil
+}
+
+// WrapHandler returns a http.Handler rejecting requests not verified with 401 Unauthorized,
+// so that it can be used as a HandlerDecorator.
+func (v *HMACVerifier) WrapHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.Verify(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// signingRoundTripper returns a http.RoundTripper sending requests signed by sign, cloned.
+func signingRoundTripper(rt http.RoundTripper, sign func(req *http.Request) error) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		r := req.Clone(req.Context())
+		if err := sign(r); err != nil {
+			if req.Body != nil {
+				_ = req.Body.Close()
+			}
+			return nil, err
+		}
+		return rt.RoundTrip(r)
+	})
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httpsign
+
+import (
+	"sync"
+	"time"
+)
+
+// NonceCache remembers nonces seen for a while, to reject requests replayed.
+type NonceCache interface {
+	// Add remembers nonce for ttl, reports false if nonce is remembered already.
+	Add(nonce string, ttl time.Duration) bool
+}
+
+// MemoryNonceCache is a NonceCache in memory, expired nonces are pruned periodically on Add.
+type MemoryNonceCache struct {
+	mu        sync.Mutex
+	nonces    map[string]time.Time // nonce -> expiry
+	nextPrune time.Time
+}
+
+// NewMemoryNonceCache returns a NonceCache in memory.
+func NewMemoryNonceCache() *MemoryNonceCache {
+	return &MemoryNonceCache{nonces: make(map[string]time.Time)}
+}
+
+func (c *MemoryNonceCache) Add(nonce string, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if now.After(c.nextPrune) {
+		for n, expiry := range c.nonces {
+			if now.After(expiry) {
+				delete(c.nonces, n)
+			}
+		}
+		c.nextPrune = now.Add(ttl)
+	}
+	if expiry, ok := c.nonces[nonce]; ok && !now.After(expiry) {
+		return false
+	}
+	c.nonces[nonce] = now.Add(ttl)
+	return true
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/searKing/golang/go/sync/breaker"
+)
+
+// BreakerKeyFunc returns the key of the Breaker for the request.
+type BreakerKeyFunc func(req *http.Request) string
+
+// BreakerSuccessFunc reports whether the request succeeded, as the Breaker counts.
+type BreakerSuccessFunc func(resp *http.Response, err error) bool
+
+// BreakerKeyByHost returns URL's host of the request as the key of the Breaker, a Breaker per host.
+func BreakerKeyByHost(req *http.Request) string {
+	return req.URL.Host
+}
+
+// BreakerSuccessful reports a request failed if an error returned, or a 5xx but http.StatusNotImplemented replied,
+// as RetryAfter does.
+func BreakerSuccessful(resp *http.Response, err error) bool {
+	if err != nil {
+		return false
+	}
+	return resp.StatusCode < http.StatusInternalServerError || resp.StatusCode == http.StatusNotImplemented
+}
+
+// IsBreakerRejected reports whether err is returned by a Breaker rejecting the request.
+func IsBreakerRejected(err error) bool {
+	return errors.Is(err, breaker.ErrOpenState) || errors.Is(err, breaker.ErrTooManyRequests)
+}
+
+// BreakerClientInterceptor returns a new client interceptor failing fast by Breakers of g,
+// keyed by key, BreakerKeyByHost if nil, and counted by success, BreakerSuccessful if nil.
+// The request rejected is not retried by DoWithBackoff.
+func BreakerClientInterceptor(g *breaker.Group, key BreakerKeyFunc, success BreakerSuccessFunc) ClientInterceptor {
+	return func(req *http.Request, retry int, invoker ClientInvoker, opts ...DoWithBackoffOption) (resp *http.Response, err error) {
+		return doWithBreaker(g, key, success, req, func(req *http.Request) (*http.Response, error) {
+			return invoker(req, retry)
+		})
+	}
+}
+
+// BreakerRoundTripper returns a new http.RoundTripper failing fast by Breakers of g,
+// keyed by key, BreakerKeyByHost if nil, and counted by success, BreakerSuccessful if nil.
+// http.DefaultTransport is used if next is nil.
+func BreakerRoundTripper(next http.RoundTripper, g *breaker.Group, key BreakerKeyFunc, success BreakerSuccessFunc) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return RoundTripFunc(func(req *http.Request) (resp *http.Response, err error) {
+		return doWithBreaker(g, key, success, req, next.RoundTrip)
+	})
+}
+
+func doWithBreaker(g *breaker.Group, key BreakerKeyFunc, success BreakerSuccessFunc,
+	req *http.Request, do func(req *http.Request) (*http.Response, error)) (*http.Response, error) {
+	if key == nil {
+		key = BreakerKeyByHost
+	}
+	if success == nil {
+		success = BreakerSuccessful
+	}
+	k := key(req)
+	done, err := g.Allow(k)
+	if err != nil {
+		return nil, fmt.Errorf("http do %s rejected by circuit breaker %q: %w", req.URL.Redacted(), k, err)
+	}
+	resp, err := do(req)
+	done(success(resp, err))
+	return resp, err
+}
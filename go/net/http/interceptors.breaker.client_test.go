@@ -0,0 +1,71 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	http_ "github.com/searKing/golang/go/net/http"
+	"github.com/searKing/golang/go/sync/breaker"
+	time_ "github.com/searKing/golang/go/time"
+)
+
+func TestBreakerClientInterceptor(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	g := breaker.NewGroup(breaker.WithReadyToTrip(breaker.ReadyToTripByConsecutiveFailures(2)))
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = http_.DoWithBackoff(req,
+		http_.WithDoWithBackoffOptionChainUnaryInterceptor(http_.BreakerClientInterceptor(g, nil, nil)),
+		http_.WithDoWithBackoffOptionExponentialBackOffOption(time_.WithExponentialBackOffOptionInitialInterval(1)),
+		http_.WithDoWithBackoffOptionMaxRetries(5))
+	if !http_.IsBreakerRejected(err) {
+		t.Fatalf("got %v, want rejected by breaker", err)
+	}
+	// stop retrying once the breaker is open
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("server hit %d times, want %d", got, 2)
+	}
+	if got := g.Get(req.URL.Host).State(); got != breaker.StateOpen {
+		t.Errorf("state %s, want %s", got, breaker.StateOpen)
+	}
+}
+
+func TestBreakerRoundTripper(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	g := breaker.NewGroup(breaker.WithReadyToTrip(breaker.ReadyToTripByConsecutiveFailures(1)))
+	cli := &http.Client{Transport: http_.BreakerRoundTripper(nil, g,
+		func(req *http.Request) string { return req.URL.Path }, nil)}
+	resp, err := cli.Get(srv.URL + "/fail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if _, err := cli.Get(srv.URL + "/fail"); !http_.IsBreakerRejected(err) {
+		t.Errorf("/fail, got %v, want rejected by breaker", err)
+	}
+	resp, err = cli.Get(srv.URL + "/ok")
+	if err != nil {
+		t.Fatalf("/ok, got %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,40 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/searKing/golang/go/time/rate"
+)
+
+// RateLimitServerInterceptor returns a new server interceptor that performs request rate limiting,
+// requests not permitted by limiter are rejected with 429 Too Many Requests.
+// timeout rejects if cost more than timeout to get a token, take effect if timeout > 0;
+// requests are rejected at once if no token is available, if timeout <= 0.
+// Tokens taken from a rate.BurstLimiter are put back once the request is served.
+func RateLimitServerInterceptor(next http.Handler, limiter rate.Limiter, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowRequest(r.Context(), limiter, timeout) {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		if lim, ok := limiter.(*rate.BurstLimiter); ok {
+			defer lim.PutToken()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func allowRequest(ctx context.Context, limiter rate.Limiter, timeout time.Duration) bool {
+	if timeout <= 0 {
+		return limiter.Allow()
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return limiter.Wait(ctx) == nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	http_ "github.com/searKing/golang/go/net/http"
+	"github.com/searKing/golang/go/time/rate"
+)
+
+func TestRateLimitServerInterceptor(t *testing.T) {
+	for _, limiter := range []rate.Limiter{
+		rate.NewFullBurstLimiter(2),
+		rate.NewSlidingLogLimiter(2, time.Hour),
+		rate.NewSlidingWindowLimiter(2, time.Hour),
+		rate.NewGCRALimiter(time.Hour, 2),
+	} {
+		var served int
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { served++ })
+		h := http_.NewHandlerInterceptorChain(http_.WithHandlerInterceptorRateLimit(limiter, 0)).InjectHttpHandler(next)
+
+		var rejected int
+		for i := 0; i < 3; i++ {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code == http.StatusTooManyRequests {
+				rejected++
+			}
+		}
+		want := 1
+		if _, ok := limiter.(*rate.BurstLimiter); ok {
+			// tokens are put back once the request is served
+			want = 0
+		}
+		if rejected != want || served != 3-want {
+			t.Errorf("%T: served %d, rejected %d, want %d, %d", limiter, served, rejected, 3-want, want)
+		}
+	}
+}
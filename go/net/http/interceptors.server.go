@@ -69,11 +69,13 @@ func (chain HandlerInterceptorChain) InjectHttpHandler(next http.Handler) http.H
 			it = i
 		}
 
+		// wrap a copy, next is shared by every request
+		h := next
 		for i := range chain.interceptors {
-			next = chain.interceptors[len(chain.interceptors)-1-i].WrapHandle(next)
+			h = chain.interceptors[len(chain.interceptors)-1-i].WrapHandle(h)
 		}
 
-		next.ServeHTTP(w, r)
+		h.ServeHTTP(w, r)
 	})
 
 }
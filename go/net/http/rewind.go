@@ -202,3 +202,25 @@ func _isNeverCloseReader(r io.Reader, nopclose bool) (rr io.Reader, nopClose boo
 	}
 	return r, nopclose
 }
+
+// RequestWithBodyConcurrentRewindable is the same as RequestWithBodyRewindable, but bodies returned by GetBody
+// can be read concurrently, as hedged requests in flight at the same time.
+// A body of type unknown is buffered in memory all at once, as the replay reader can not be read concurrently.
+func RequestWithBodyConcurrentRewindable(req *http.Request) error {
+	if err := RequestWithBodyRewindable(req); err != nil {
+		return err
+	}
+	if _, ok := req.Body.(replayReadCloser); !ok {
+		return nil
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	contentLength := req.ContentLength
+	ReplaceHttpRequestBody(req, bytes.NewReader(data))
+	if contentLength > 0 {
+		req.ContentLength = contentLength
+	}
+	return nil
+}
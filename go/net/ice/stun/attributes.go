@@ -0,0 +1,255 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stun
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+// ErrAttributeNotFound is returned when getting an attribute missing from a message.
+var ErrAttributeNotFound = errors.New("stun: attribute not found")
+
+// AttrType is the type of an attribute.
+// Types below 0x8000 are comprehension-required, an agent not understanding them
+// fails the transaction.
+type AttrType uint16
+
+// https://tools.ietf.org/html/rfc5389#section-18.2
+const (
+	AttrMappedAddress     AttrType = 0x0001
+	AttrUsername          AttrType = 0x0006
+	AttrMessageIntegrity  AttrType = 0x0008
+	AttrErrorCode         AttrType = 0x0009
+	AttrUnknownAttributes AttrType = 0x000A
+	AttrRealm             AttrType = 0x0014
+	AttrNonce             AttrType = 0x0015
+	AttrXORMappedAddress  AttrType = 0x0020
+	AttrSoftware          AttrType = 0x8022
+	AttrAlternateServer   AttrType = 0x8023
+	AttrFingerprint       AttrType = 0x8028
+)
+
+var attrNames = map[AttrType]string{
+	AttrMappedAddress:     "MAPPED-ADDRESS",
+	AttrUsername:          "USERNAME",
+	AttrMessageIntegrity:  "MESSAGE-INTEGRITY",
+	AttrErrorCode:         "ERROR-CODE",
+	AttrUnknownAttributes: "UNKNOWN-ATTRIBUTES",
+	AttrRealm:             "REALM",
+	AttrNonce:             "NONCE",
+	AttrXORMappedAddress:  "XOR-MAPPED-ADDRESS",
+	AttrSoftware:          "SOFTWARE",
+	AttrAlternateServer:   "ALTERNATE-SERVER",
+	AttrFingerprint:       "FINGERPRINT",
+}
+
+// RegisterAttrName registers the name of an attribute type, for String, as
+// extensions of STUN such as TURN define their own attributes.
+func RegisterAttrName(t AttrType, name string) { attrNames[t] = name }
+
+func (t AttrType) String() string {
+	if name, ok := attrNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("AttrType(0x%04x)", uint16(t))
+}
+
+// Required reports whether the attribute is comprehension-required.
+func (t AttrType) Required() bool { return t < 0x8000 }
+
+// address families
+const (
+	familyIPv4 = 0x01
+	familyIPv6 = 0x02
+)
+
+// https://tools.ietf.org/html/rfc5389#section-15.1
+func appendAddress(b []byte, addr netip.AddrPort) []byte {
+	ip := addr.Addr().Unmap()
+	family := byte(familyIPv4)
+	if ip.Is6() {
+		family = familyIPv6
+	}
+	b = append(b, 0, family)
+	b = binary.BigEndian.AppendUint16(b, addr.Port())
+	return append(b, ip.AsSlice()...)
+}
+
+func parseAddress(v []byte) (netip.AddrPort, error) {
+	if len(v) < 4 {
+		return netip.AddrPort{}, fmt.Errorf("stun: bad address length %d", len(v))
+	}
+	port := binary.BigEndian.Uint16(v[2:4])
+	var ip netip.Addr
+	switch v[1] {
+	case familyIPv4:
+		if len(v) != 4+4 {
+			return netip.AddrPort{}, fmt.Errorf("stun: bad IPv4 address length %d", len(v))
+		}
+		ip = netip.AddrFrom4(*(*[4]byte)(v[4:]))
+	case familyIPv6:
+		if len(v) != 4+16 {
+			return netip.AddrPort{}, fmt.Errorf("stun: bad IPv6 address length %d", len(v))
+		}
+		ip = netip.AddrFrom16(*(*[16]byte)(v[4:]))
+	default:
+		return netip.AddrPort{}, fmt.Errorf("stun: bad address family %d", v[1])
+	}
+	return netip.AddrPortFrom(ip, port), nil
+}
+
+// xorAddress xors the port and address of an encoded address with the magic cookie
+// and the transaction id, in place.
+// https://tools.ietf.org/html/rfc5389#section-15.2
+func xorAddress(v []byte, id TransactionID) {
+	var key [16]byte
+	binary.BigEndian.PutUint32(key[:4], MagicCookie)
+	copy(key[4:], id[:])
+	v[2] ^= key[0]
+	v[3] ^= key[1]
+	for i := 4; i < len(v) && i-4 < len(key); i++ {
+		v[i] ^= key[i-4]
+	}
+}
+
+// AddAddress appends an address attribute of type t, such as MAPPED-ADDRESS.
+func (m *Message) AddAddress(t AttrType, addr netip.AddrPort) {
+	m.Add(t, appendAddress(nil, addr))
+}
+
+// Address returns the address attribute of type t, such as MAPPED-ADDRESS.
+func (m *Message) Address(t AttrType) (netip.AddrPort, error) {
+	v, ok := m.Get(t)
+	if !ok {
+		return netip.AddrPort{}, fmt.Errorf("%w: %s", ErrAttributeNotFound, t)
+	}
+	return parseAddress(v)
+}
+
+// AddXORAddress appends a xor'd address attribute of type t, such as XOR-MAPPED-ADDRESS.
+func (m *Message) AddXORAddress(t AttrType, addr netip.AddrPort) {
+	v := appendAddress(nil, addr)
+	xorAddress(v, m.TransactionID)
+	m.Add(t, v)
+}
+
+// XORAddress returns the xor'd address attribute of type t, such as XOR-MAPPED-ADDRESS.
+func (m *Message) XORAddress(t AttrType) (netip.AddrPort, error) {
+	v, ok := m.Get(t)
+	if !ok {
+		return netip.AddrPort{}, fmt.Errorf("%w: %s", ErrAttributeNotFound, t)
+	}
+	v = append([]byte(nil), v...)
+	if len(v) >= 4 {
+		xorAddress(v, m.TransactionID)
+	}
+	return parseAddress(v)
+}
+
+// XORAddresses returns every xor'd address attribute of type t, such as XOR-PEER-ADDRESS of TURN.
+func (m *Message) XORAddresses(t AttrType) ([]netip.AddrPort, error) {
+	var addrs []netip.AddrPort
+	for _, a := range m.Attributes {
+		if a.Type != t {
+			continue
+		}
+		v := append([]byte(nil), a.Value...)
+		if len(v) >= 4 {
+			xorAddress(v, m.TransactionID)
+		}
+		addr, err := parseAddress(v)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// MappedAddress returns XOR-MAPPED-ADDRESS, or MAPPED-ADDRESS of servers predating it.
+func (m *Message) MappedAddress() (netip.AddrPort, error) {
+	if m.Contains(AttrXORMappedAddress) {
+		return m.XORAddress(AttrXORMappedAddress)
+	}
+	return m.Address(AttrMappedAddress)
+}
+
+// AddString appends an attribute of type t holding s, such as USERNAME or SOFTWARE.
+func (m *Message) AddString(t AttrType, s string) {
+	m.Add(t, []byte(s))
+}
+
+// GetString returns the attribute of type t holding a string, such as USERNAME or SOFTWARE.
+func (m *Message) GetString(t AttrType) (string, bool) {
+	v, ok := m.Get(t)
+	return string(v), ok
+}
+
+// ErrorCode is the ERROR-CODE attribute of an error response.
+// https://tools.ietf.org/html/rfc5389#section-15.6
+type ErrorCode struct {
+	Code   int
+	Reason string
+}
+
+// https://tools.ietf.org/html/rfc5389#section-15.6
+var (
+	ErrorTryAlternate     = ErrorCode{300, "Try Alternate"}
+	ErrorBadRequest       = ErrorCode{400, "Bad Request"}
+	ErrorUnauthorized     = ErrorCode{401, "Unauthorized"}
+	ErrorUnknownAttribute = ErrorCode{420, "Unknown Attribute"}
+	ErrorStaleNonce       = ErrorCode{438, "Stale Nonce"}
+	ErrorServerError      = ErrorCode{500, "Server Error"}
+)
+
+func (e ErrorCode) Error() string { return fmt.Sprintf("stun: error %d %s", e.Code, e.Reason) }
+
+// AddErrorCode appends an ERROR-CODE attribute.
+func (m *Message) AddErrorCode(e ErrorCode) {
+	v := []byte{0, 0, byte(e.Code / 100), byte(e.Code % 100)}
+	m.Add(AttrErrorCode, append(v, e.Reason...))
+}
+
+// ErrorCode returns the ERROR-CODE attribute.
+func (m *Message) ErrorCode() (ErrorCode, error) {
+	v, ok := m.Get(AttrErrorCode)
+	if !ok {
+		return ErrorCode{}, fmt.Errorf("%w: %s", ErrAttributeNotFound, AttrErrorCode)
+	}
+	if len(v) < 4 {
+		return ErrorCode{}, fmt.Errorf("stun: bad ERROR-CODE length %d", len(v))
+	}
+	return ErrorCode{Code: int(v[2]&0x07)*100 + int(v[3]), Reason: string(v[4:])}, nil
+}
+
+// AddUnknownAttributes appends an UNKNOWN-ATTRIBUTES attribute listing types.
+func (m *Message) AddUnknownAttributes(types ...AttrType) {
+	v := make([]byte, 0, 2*len(types))
+	for _, t := range types {
+		v = binary.BigEndian.AppendUint16(v, uint16(t))
+	}
+	m.Add(AttrUnknownAttributes, v)
+}
+
+// UnknownAttributes returns the comprehension-required attributes of m not in known.
+func (m *Message) UnknownAttributes(known ...AttrType) []AttrType {
+	var unknown []AttrType
+next:
+	for _, a := range m.Attributes {
+		if !a.Type.Required() {
+			continue
+		}
+		for _, t := range known {
+			if a.Type == t {
+				continue next
+			}
+		}
+		unknown = append(unknown, a.Type)
+	}
+	return unknown
+}
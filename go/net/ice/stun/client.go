@@ -0,0 +1,241 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/searKing/golang/go/net/ice"
+	time_ "github.com/searKing/golang/go/time"
+)
+
+// https://tools.ietf.org/html/rfc5389#section-7.2.1
+const (
+	DefaultRTO = 500 * time.Millisecond
+	DefaultRc  = 7
+	DefaultRm  = 16
+)
+
+// ErrTimeout is returned when no response is received for a request after its last retransmission.
+var ErrTimeout = errors.New("stun: transaction timed out")
+
+// maxMessageSize is the size of the buffer responses are read into,
+// STUN messages over UDP are expected to fit in the path MTU.
+const maxMessageSize = 1500
+
+// Client sends requests over a PacketConn, retransmitting them until a response is received.
+// The zero value is a usable Client with the defaults of rfc5389.
+type Client struct {
+	// RTO is the retransmission timeout of the first request, doubled for each retransmission.
+	// DefaultRTO if zero.
+	RTO time.Duration
+	// Rc is the count of requests sent at most, the first included. DefaultRc if zero.
+	Rc int
+	// Rm is the multiple of RTO the last request waits for a response. DefaultRm if zero.
+	Rm int
+	// Software, if not empty, is sent as SOFTWARE in requests.
+	Software string
+}
+
+func (c *Client) rto() time.Duration {
+	if c.RTO > 0 {
+		return c.RTO
+	}
+	return DefaultRTO
+}
+
+func (c *Client) rc() int {
+	if c.Rc > 0 {
+		return c.Rc
+	}
+	return DefaultRc
+}
+
+func (c *Client) rm() int {
+	if c.Rm > 0 {
+		return c.Rm
+	}
+	return DefaultRm
+}
+
+// Do sends req to addr over conn and returns its response, retransmitting req
+// until a response of the same transaction is received, ctx is done or the last
+// retransmission timed out.
+// An error response is returned along with its ERROR-CODE as error.
+// Do reads conn, which must not be read concurrently.
+// https://tools.ietf.org/html/rfc5389#section-7.2.1
+func (c *Client) Do(ctx context.Context, conn net.PacketConn, addr net.Addr, req *Message) (*Message, error) {
+	// interrupt the pending read once ctx is done
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			_ = conn.SetReadDeadline(time.Unix(1, 0))
+		case <-stop:
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-stopped
+		_ = conn.SetReadDeadline(time.Time{})
+	}()
+
+	buf := make([]byte, maxMessageSize)
+	return c.retransmit(req, func(p []byte) error {
+		_, err := conn.WriteTo(p, addr)
+		return err
+	}, func(d time.Duration) (*Message, error) {
+		resp, err := c.readResponse(ctx, conn, req.TransactionID, time.Now().Add(d), buf)
+		var netErr net.Error
+		// context.DeadlineExceeded is a net.Error timing out too
+		if !errors.Is(err, context.DeadlineExceeded) && errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, errWaitTimeout
+		}
+		return resp, err
+	})
+}
+
+// Transact is like Do, for a caller reading the connection itself: req is sent
+// with send, and its responses are expected on responses.
+func (c *Client) Transact(ctx context.Context, req *Message, send func(p []byte) error, responses <-chan *Message) (*Message, error) {
+	return c.retransmit(req, send, func(d time.Duration) (*Message, error) {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case resp := <-responses:
+			return resp, nil
+		case <-timer.C:
+			return nil, errWaitTimeout
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+}
+
+// errWaitTimeout is returned by the wait of retransmit once no response came in time.
+var errWaitTimeout = errors.New("stun: no response yet")
+
+// retransmit sends req with send, then waits for its response with wait, for the
+// retransmission timeouts of rfc5389.
+func (c *Client) retransmit(req *Message, send func(p []byte) error, wait func(d time.Duration) (*Message, error)) (*Message, error) {
+	raw := req.Marshal()
+	backoff := time_.NewExponentialBackOff(
+		time_.WithExponentialBackOffOptionInitialInterval(c.rto()),
+		time_.WithExponentialBackOffOptionMultiplier(2),
+		time_.WithExponentialBackOffOptionRandomizationFactor(0),
+		time_.WithExponentialBackOffOptionMaxElapsedCount(c.rc()-1))
+	for {
+		if err := send(raw); err != nil {
+			return nil, err
+		}
+		d, ok := backoff.NextBackOff()
+		if !ok {
+			d = time.Duration(c.rm()) * c.rto()
+		}
+		resp, err := wait(d)
+		if err == nil {
+			return resp, resp.errorCode()
+		}
+		if !errors.Is(err, errWaitTimeout) {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrTimeout
+		}
+	}
+}
+
+// readResponse reads from conn until the response of transaction id is received, deadline, or ctx is done.
+func (c *Client) readResponse(ctx context.Context, conn net.PacketConn, id TransactionID, deadline time.Time, buf []byte) (*Message, error) {
+	ctxDeadline, hasCtxDeadline := ctx.Deadline()
+	if hasCtxDeadline && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+	// ctx may be done before the deadline set above, overwriting the one set once ctx is done
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			var netErr net.Error
+			if hasCtxDeadline && errors.As(err, &netErr) && netErr.Timeout() && !time.Now().Before(ctxDeadline) {
+				// timed out by the deadline of ctx, which may not be reported by ctx.Err() yet
+				return nil, context.DeadlineExceeded
+			}
+			return nil, err
+		}
+		var resp Message
+		if err := resp.Unmarshal(append([]byte(nil), buf[:n]...)); err != nil {
+			continue // not STUN, or garbled
+		}
+		if resp.TransactionID != id || resp.Type.Class == ClassRequest || resp.Type.Class == ClassIndication {
+			continue // late response of a former transaction
+		}
+		if resp.Contains(AttrFingerprint) && resp.CheckFingerprint() != nil {
+			continue
+		}
+		return &resp, nil
+	}
+}
+
+// errorCode returns ERROR-CODE as an error if m is an error response.
+func (m *Message) errorCode() error {
+	if m.Type.Class != ClassErrorResponse {
+		return nil
+	}
+	e, err := m.ErrorCode()
+	if err != nil {
+		return fmt.Errorf("stun: %s without ERROR-CODE: %w", m.Type, err)
+	}
+	return e
+}
+
+// Binding sends a Binding request to addr over conn and returns the reflexive
+// transport address of conn seen by the server.
+func (c *Client) Binding(ctx context.Context, conn net.PacketConn, addr net.Addr) (netip.AddrPort, error) {
+	req := NewMessage(MessageType{Method: MethodBinding, Class: ClassRequest})
+	if c.Software != "" {
+		req.AddString(AttrSoftware, c.Software)
+	}
+	req.AddFingerprint()
+	resp, err := c.Do(ctx, conn, addr, req)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	return resp.MappedAddress()
+}
+
+// Discover returns the server reflexive address of a new UDP socket, as seen by
+// the STUN server of u.
+// Only stun URLs over UDP are supported.
+func (c *Client) Discover(ctx context.Context, u *ice.URL) (netip.AddrPort, error) {
+	if u.Scheme != ice.SchemeSTUN || u.Proto != ice.TransportUDP {
+		return netip.AddrPort{}, fmt.Errorf("stun: unsupported url %s", u)
+	}
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(u.Host, strconv.Itoa(u.Port)))
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	defer conn.Close()
+	return c.Binding(ctx, conn, addr)
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stun
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+var (
+	// ErrIntegrityMismatch is returned when MESSAGE-INTEGRITY does not match the key.
+	ErrIntegrityMismatch = errors.New("stun: message integrity mismatch")
+	// ErrFingerprintMismatch is returned when FINGERPRINT does not match the message.
+	ErrFingerprintMismatch = errors.New("stun: fingerprint mismatch")
+)
+
+const (
+	messageIntegritySize = sha1.Size
+	fingerprintSize      = 4
+	// fingerprintXOR is xor'd with the CRC-32 of the message, "STUN" in ASCII.
+	fingerprintXOR uint32 = 0x5354554e
+)
+
+// ShortTermKey returns the key of the short-term credential mechanism.
+// https://tools.ietf.org/html/rfc5389#section-10.1.2
+func ShortTermKey(password string) []byte {
+	return []byte(password)
+}
+
+// LongTermKey returns the key of the long-term credential mechanism,
+// MD5(username ":" realm ":" password), password being already processed by SASLprep.
+// https://tools.ietf.org/html/rfc5389#section-15.4
+func LongTermKey(username, realm, password string) []byte {
+	sum := md5.Sum([]byte(username + ":" + realm + ":" + password))
+	return sum[:]
+}
+
+// AddMessageIntegrity appends a MESSAGE-INTEGRITY attribute, HMAC-SHA1 of the message
+// keyed by key. Only FINGERPRINT may be added after it.
+// https://tools.ietf.org/html/rfc5389#section-15.4
+func (m *Message) AddMessageIntegrity(key []byte) {
+	b := m.appendTo(nil, len(m.Attributes), 4+messageIntegritySize)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(b)
+	m.Add(AttrMessageIntegrity, mac.Sum(nil))
+}
+
+// CheckMessageIntegrity checks the MESSAGE-INTEGRITY attribute of m against key.
+func (m *Message) CheckMessageIntegrity(key []byte) error {
+	raw, off, err := m.rawUpTo(AttrMessageIntegrity)
+	if err != nil {
+		return err
+	}
+	got := raw[off+4:]
+	if len(got) < messageIntegritySize {
+		return fmt.Errorf("stun: bad MESSAGE-INTEGRITY length %d", len(got))
+	}
+	b := append([]byte(nil), raw[:off]...)
+	binary.BigEndian.PutUint16(b[2:4], uint16(off-HeaderSize+4+messageIntegritySize))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(b)
+	if !hmac.Equal(mac.Sum(nil), got[:messageIntegritySize]) {
+		return ErrIntegrityMismatch
+	}
+	return nil
+}
+
+// AddFingerprint appends a FINGERPRINT attribute, which must be the last one.
+// https://tools.ietf.org/html/rfc5389#section-15.5
+func (m *Message) AddFingerprint() {
+	b := m.appendTo(nil, len(m.Attributes), 4+fingerprintSize)
+	m.Add(AttrFingerprint, binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(b)^fingerprintXOR))
+}
+
+// CheckFingerprint checks the FINGERPRINT attribute of m.
+func (m *Message) CheckFingerprint() error {
+	raw, off, err := m.rawUpTo(AttrFingerprint)
+	if err != nil {
+		return err
+	}
+	got := raw[off+4:]
+	if len(got) != fingerprintSize {
+		return fmt.Errorf("stun: bad FINGERPRINT length %d", len(got))
+	}
+	b := append([]byte(nil), raw[:off]...)
+	binary.BigEndian.PutUint16(b[2:4], uint16(off-HeaderSize+4+fingerprintSize))
+	if crc32.ChecksumIEEE(b)^fingerprintXOR != binary.BigEndian.Uint32(got) {
+		return ErrFingerprintMismatch
+	}
+	return nil
+}
+
+// rawUpTo returns the wire form of m, Raw if unmarshaled, and the offset of the
+// first attribute of type t in it.
+func (m *Message) rawUpTo(t AttrType) (raw []byte, off int, err error) {
+	raw = m.Raw
+	if raw == nil {
+		raw = m.Marshal()
+	}
+	for off = HeaderSize; off+4 <= len(raw); {
+		at := AttrType(binary.BigEndian.Uint16(raw[off : off+2]))
+		l := int(binary.BigEndian.Uint16(raw[off+2 : off+4]))
+		if at == t {
+			return raw, off, nil
+		}
+		off += 4 + l + padding(l)
+	}
+	return nil, 0, fmt.Errorf("%w: %s", ErrAttributeNotFound, t)
+}
@@ -0,0 +1,243 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package stun implements Session Traversal Utilities for NAT (STUN),
+// as described in https://tools.ietf.org/html/rfc5389: the message codec, a
+// Binding client discovering the server reflexive address with retransmissions,
+// and a minimal Binding server.
+package stun
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// MagicCookie is the fixed value of every STUN message, in network byte order.
+const MagicCookie uint32 = 0x2112A442
+
+// HeaderSize is the size of a STUN message header.
+const HeaderSize = 20
+
+// ErrNotSTUNMessage is returned when unmarshaling bytes which are not a STUN message.
+var ErrNotSTUNMessage = errors.New("stun: not a STUN message")
+
+// Method is a STUN method, such as Binding.
+type Method uint16
+
+// https://tools.ietf.org/html/rfc5389#section-18.1
+const (
+	MethodBinding Method = 0x001
+)
+
+var methodNames = map[Method]string{
+	MethodBinding: "Binding",
+}
+
+// RegisterMethodName registers the name of a method, for String, as
+// extensions of STUN such as TURN define their own methods.
+func RegisterMethodName(m Method, name string) { methodNames[m] = name }
+
+func (m Method) String() string {
+	if name, ok := methodNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Method(0x%03x)", uint16(m))
+}
+
+// Class is the class of a STUN message.
+type Class uint8
+
+const (
+	ClassRequest         Class = 0b00
+	ClassIndication      Class = 0b01
+	ClassSuccessResponse Class = 0b10
+	ClassErrorResponse   Class = 0b11
+)
+
+func (c Class) String() string {
+	switch c {
+	case ClassRequest:
+		return "request"
+	case ClassIndication:
+		return "indication"
+	case ClassSuccessResponse:
+		return "success response"
+	case ClassErrorResponse:
+		return "error response"
+	default:
+		return fmt.Sprintf("Class(%d)", uint8(c))
+	}
+}
+
+// MessageType is the type of a STUN message, its method and class.
+type MessageType struct {
+	Method Method
+	Class  Class
+}
+
+// https://tools.ietf.org/html/rfc5389#section-6
+//
+//	 0                 1
+//	 2  3  4 5 6 7 8 9 0 1 2 3 4 5
+//	+--+--+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|M |M |M|M|M|C|M|M|M|C|M|M|M|M|
+//	|11|10|9|8|7|1|6|5|4|0|3|2|1|0|
+//	+--+--+-+-+-+-+-+-+-+-+-+-+-+-+
+func (t MessageType) value() uint16 {
+	m := uint16(t.Method)
+	c := uint16(t.Class)
+	return m&0x000F | (m&0x0070)<<1 | (m&0x0F80)<<2 | (c&0b01)<<4 | (c&0b10)<<7
+}
+
+func parseMessageType(v uint16) MessageType {
+	m := v&0x000F | (v>>1)&0x0070 | (v>>2)&0x0F80
+	c := (v>>4)&0b01 | (v>>7)&0b10
+	return MessageType{Method: Method(m), Class: Class(c)}
+}
+
+func (t MessageType) String() string { return t.Method.String() + " " + t.Class.String() }
+
+// TransactionID identifies a transaction, the request and its response.
+type TransactionID [12]byte
+
+// NewTransactionID returns a cryptographically random TransactionID.
+func NewTransactionID() TransactionID {
+	var id TransactionID
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(fmt.Sprintf("stun: read random transaction id: %v", err))
+	}
+	return id
+}
+
+func (id TransactionID) String() string { return hex.EncodeToString(id[:]) }
+
+// Attribute is a TLV attribute of a STUN message.
+type Attribute struct {
+	Type  AttrType
+	Value []byte
+}
+
+// Message is a STUN message.
+type Message struct {
+	Type          MessageType
+	TransactionID TransactionID
+	Attributes    []Attribute
+
+	// Raw holds the bytes the message was unmarshaled from,
+	// over which MESSAGE-INTEGRITY and FINGERPRINT are checked.
+	Raw []byte
+}
+
+// NewMessage returns a message of type t with a new transaction id.
+func NewMessage(t MessageType) *Message {
+	return &Message{Type: t, TransactionID: NewTransactionID()}
+}
+
+// NewResponse returns a response of class c to req, sharing its transaction id.
+func NewResponse(req *Message, c Class) *Message {
+	return &Message{Type: MessageType{Method: req.Type.Method, Class: c}, TransactionID: req.TransactionID}
+}
+
+func (m *Message) String() string {
+	return fmt.Sprintf("%s id=%s attrs=%d", m.Type, m.TransactionID, len(m.Attributes))
+}
+
+// Add appends an attribute of type t and value v.
+func (m *Message) Add(t AttrType, v []byte) {
+	m.Attributes = append(m.Attributes, Attribute{Type: t, Value: v})
+}
+
+// Get returns the value of the first attribute of type t.
+func (m *Message) Get(t AttrType) ([]byte, bool) {
+	for _, a := range m.Attributes {
+		if a.Type == t {
+			return a.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Contains reports whether m has an attribute of type t.
+func (m *Message) Contains(t AttrType) bool {
+	_, ok := m.Get(t)
+	return ok
+}
+
+// Marshal returns the wire form of m, padding attributes with zeros.
+func (m *Message) Marshal() []byte {
+	return m.AppendTo(nil)
+}
+
+// AppendTo appends the wire form of m to b.
+func (m *Message) AppendTo(b []byte) []byte {
+	return m.appendTo(b, len(m.Attributes), 0)
+}
+
+// appendTo appends the header and the first n attributes of m to b, the length of
+// the header counting extra more bytes.
+func (m *Message) appendTo(b []byte, n int, extra int) []byte {
+	start := len(b)
+	b = binary.BigEndian.AppendUint16(b, m.Type.value())
+	b = binary.BigEndian.AppendUint16(b, 0) // length, set below
+	b = binary.BigEndian.AppendUint32(b, MagicCookie)
+	b = append(b, m.TransactionID[:]...)
+	for _, a := range m.Attributes[:n] {
+		b = binary.BigEndian.AppendUint16(b, uint16(a.Type))
+		b = binary.BigEndian.AppendUint16(b, uint16(len(a.Value)))
+		b = append(b, a.Value...)
+		b = append(b, make([]byte, padding(len(a.Value)))...)
+	}
+	binary.BigEndian.PutUint16(b[start+2:], uint16(len(b)-start-HeaderSize+extra))
+	return b
+}
+
+// padding returns the padding of an attribute value of n bytes, to a multiple of 4 bytes.
+func padding(n int) int {
+	return (4 - n%4) % 4
+}
+
+// IsMessage reports whether b looks like a STUN message, as opposed to other
+// protocols multiplexed on the same port.
+func IsMessage(b []byte) bool {
+	return len(b) >= HeaderSize && b[0]&0xC0 == 0 && binary.BigEndian.Uint32(b[4:8]) == MagicCookie
+}
+
+// Unmarshal parses the STUN message in b, which m keeps a reference of as Raw.
+func (m *Message) Unmarshal(b []byte) error {
+	if !IsMessage(b) {
+		return ErrNotSTUNMessage
+	}
+	n := int(binary.BigEndian.Uint16(b[2:4]))
+	if n%4 != 0 || HeaderSize+n != len(b) {
+		return fmt.Errorf("stun: bad message length %d of %d bytes", n, len(b))
+	}
+	m.Type = parseMessageType(binary.BigEndian.Uint16(b[0:2]))
+	copy(m.TransactionID[:], b[8:HeaderSize])
+	m.Attributes = m.Attributes[:0]
+	m.Raw = b
+
+	for p := b[HeaderSize:]; len(p) > 0; {
+		if len(p) < 4 {
+			return fmt.Errorf("stun: truncated attribute header")
+		}
+		t := AttrType(binary.BigEndian.Uint16(p[0:2]))
+		l := int(binary.BigEndian.Uint16(p[2:4]))
+		if 4+l > len(p) {
+			return fmt.Errorf("stun: truncated attribute %s", t)
+		}
+		m.Attributes = append(m.Attributes, Attribute{Type: t, Value: p[4 : 4+l]})
+		p = p[min(4+l+padding(l), len(p)):]
+	}
+	return nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stun
+
+import (
+	"net"
+	"net/netip"
+)
+
+// Server answers Binding requests with the reflexive transport address of their sender.
+// https://tools.ietf.org/html/rfc5389#section-7.3
+type Server struct {
+	// Software, if not empty, is sent as SOFTWARE in responses.
+	Software string
+}
+
+// ListenAndServe listens on the UDP network address addr and serves STUN requests.
+func (srv *Server) ListenAndServe(addr string) error {
+	if addr == "" {
+		addr = ":3478"
+	}
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return srv.Serve(conn)
+}
+
+// Serve reads STUN messages from conn and answers them, until conn fails,
+// returning the error of its read. Datagrams which are not STUN messages are dropped.
+func (srv *Server) Serve(conn net.PacketConn) error {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		var req Message
+		if err := req.Unmarshal(buf[:n]); err != nil {
+			continue
+		}
+		addr, ok := AddrPortOf(from)
+		if !ok {
+			continue
+		}
+		if resp := srv.ServeMessage(&req, addr); resp != nil {
+			_, _ = conn.WriteTo(resp.Marshal(), from)
+		}
+	}
+}
+
+// ServeMessage returns the response to req received from addr, nil if none is due,
+// as for indications and messages failing their FINGERPRINT.
+func (srv *Server) ServeMessage(req *Message, addr netip.AddrPort) *Message {
+	if req.Type.Class != ClassRequest {
+		return nil
+	}
+	if req.Contains(AttrFingerprint) && req.CheckFingerprint() != nil {
+		return nil
+	}
+	if req.Type.Method != MethodBinding {
+		return srv.finish(NewErrorResponse(req, ErrorBadRequest))
+	}
+	if unknown := req.UnknownAttributes(AttrUsername, AttrMessageIntegrity); len(unknown) > 0 {
+		resp := NewErrorResponse(req, ErrorUnknownAttribute)
+		resp.AddUnknownAttributes(unknown...)
+		return srv.finish(resp)
+	}
+	resp := NewResponse(req, ClassSuccessResponse)
+	resp.AddXORAddress(AttrXORMappedAddress, addr)
+	return srv.finish(resp)
+}
+
+func (srv *Server) finish(resp *Message) *Message {
+	if srv.Software != "" {
+		resp.AddString(AttrSoftware, srv.Software)
+	}
+	resp.AddFingerprint()
+	return resp
+}
+
+// NewErrorResponse returns an error response to req carrying e as ERROR-CODE.
+func NewErrorResponse(req *Message, e ErrorCode) *Message {
+	resp := NewResponse(req, ClassErrorResponse)
+	resp.AddErrorCode(e)
+	return resp
+}
+
+// AddrPortOf returns the IP address and port of addr, a *net.UDPAddr or *net.TCPAddr,
+// IPv4-mapped IPv6 addresses being unmapped.
+func AddrPortOf(addr net.Addr) (netip.AddrPort, bool) {
+	var ap netip.AddrPort
+	switch addr := addr.(type) {
+	case *net.UDPAddr:
+		ap = addr.AddrPort()
+	case *net.TCPAddr:
+		ap = addr.AddrPort()
+	default:
+		var err error
+		if ap, err = netip.ParseAddrPort(addr.String()); err != nil {
+			return netip.AddrPort{}, false
+		}
+	}
+	return netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port()), ap.IsValid()
+}
@@ -0,0 +1,249 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stun_test
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/net/ice"
+	"github.com/searKing/golang/go/net/ice/stun"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// https://tools.ietf.org/html/rfc5769#section-2.1
+func TestMessageSampleRequest(t *testing.T) {
+	b := decodeHex(t, `
+		0001 0058 2112a442 b7e7a701 bc34d686 fa87dfae
+		8022 0010 53545 54e 20746573 7420636c 69656e74
+		0024 0004 6e0001ff
+		8029 0008 932ff9b1 51263b36
+		0006 0009 6576746a 3a683676 59202020
+		0008 0014 9aeaa70c bfd8cb56 781ef2b5 b2d3f249 c1b571a2
+		8028 0004 e57a3bcf`)
+	var m stun.Message
+	if err := m.Unmarshal(b); err != nil {
+		t.Fatal(err)
+	}
+	if want := (stun.MessageType{Method: stun.MethodBinding, Class: stun.ClassRequest}); m.Type != want {
+		t.Errorf("Type = %s, want %s", m.Type, want)
+	}
+	if username, _ := m.GetString(stun.AttrUsername); username != "evtj:h6vY" {
+		t.Errorf("USERNAME = %q, want %q", username, "evtj:h6vY")
+	}
+	if err := m.CheckMessageIntegrity(stun.ShortTermKey("VOkJxbRl1RmTxUk/WvJxBt")); err != nil {
+		t.Errorf("CheckMessageIntegrity: %v", err)
+	}
+	if err := m.CheckMessageIntegrity(stun.ShortTermKey("wrong")); !errors.Is(err, stun.ErrIntegrityMismatch) {
+		t.Errorf("CheckMessageIntegrity with a wrong key = %v, want ErrIntegrityMismatch", err)
+	}
+	if err := m.CheckFingerprint(); err != nil {
+		t.Errorf("CheckFingerprint: %v", err)
+	}
+	if unknown := m.UnknownAttributes(stun.AttrUsername, stun.AttrMessageIntegrity); len(unknown) != 1 || unknown[0] != 0x0024 {
+		t.Errorf("UnknownAttributes = %v, want [PRIORITY]", unknown)
+	}
+}
+
+// https://tools.ietf.org/html/rfc5769#section-2.2
+func TestMessageSampleResponse(t *testing.T) {
+	b := decodeHex(t, `
+		0101 003c 2112a442 b7e7a701 bc34d686 fa87dfae
+		8022 000b 74657374 20766563 746f7220
+		0020 0008 0001a147 e112a643
+		0008 0014 2b91f599 fd9e90c3 8c7489f9 2af9ba53 f06be7d7
+		8028 0004 c07d4c96`)
+	key := stun.ShortTermKey("VOkJxbRl1RmTxUk/WvJxBt")
+
+	var m stun.Message
+	if err := m.Unmarshal(b); err != nil {
+		t.Fatal(err)
+	}
+	addr, err := m.MappedAddress()
+	if want := netip.MustParseAddrPort("192.0.2.1:32853"); err != nil || addr != want {
+		t.Errorf("MappedAddress = %s, %v, want %s", addr, err, want)
+	}
+	if err := m.CheckMessageIntegrity(key); err != nil {
+		t.Errorf("CheckMessageIntegrity: %v", err)
+	}
+	if err := m.CheckFingerprint(); err != nil {
+		t.Errorf("CheckFingerprint: %v", err)
+	}
+
+	// build it back, the SOFTWARE padding of the sample being a space
+	out := stun.NewResponse(&stun.Message{Type: m.Type, TransactionID: m.TransactionID}, stun.ClassSuccessResponse)
+	out.AddString(stun.AttrSoftware, "test vector")
+	out.AddXORAddress(stun.AttrXORMappedAddress, addr)
+	out.AddMessageIntegrity(key)
+	out.AddFingerprint()
+	got := out.Marshal()
+	var back stun.Message
+	if err := back.Unmarshal(got); err != nil {
+		t.Fatal(err)
+	}
+	if err := back.CheckMessageIntegrity(key); err != nil {
+		t.Errorf("CheckMessageIntegrity of the marshaled response: %v", err)
+	}
+	if err := back.CheckFingerprint(); err != nil {
+		t.Errorf("CheckFingerprint of the marshaled response: %v", err)
+	}
+	if a, _ := back.MappedAddress(); a != addr {
+		t.Errorf("MappedAddress = %s, want %s", a, addr)
+	}
+}
+
+func TestXORAddressIPv6(t *testing.T) {
+	m := stun.NewMessage(stun.MessageType{Method: stun.MethodBinding, Class: stun.ClassSuccessResponse})
+	want := netip.MustParseAddrPort("[2001:db8:1234:5678:11:2233:4455:6677]:32853")
+	m.AddXORAddress(stun.AttrXORMappedAddress, want)
+	var back stun.Message
+	if err := back.Unmarshal(m.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := back.MappedAddress(); err != nil || got != want {
+		t.Errorf("MappedAddress = %s, %v, want %s", got, err, want)
+	}
+}
+
+func listen(t *testing.T) net.PacketConn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestClientBinding(t *testing.T) {
+	srvConn := listen(t)
+	go (&stun.Server{Software: "test"}).Serve(srvConn)
+
+	conn := listen(t)
+	c := &stun.Client{RTO: 50 * time.Millisecond}
+	got, err := c.Binding(context.Background(), conn, srvConn.LocalAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := conn.LocalAddr().(*net.UDPAddr).AddrPort(); got != want {
+		t.Errorf("Binding = %s, want %s", got, want)
+	}
+
+	u, err := ice.ParseURL("stun:" + srvConn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.Discover(context.Background(), u); err != nil || !got.Addr().IsLoopback() {
+		t.Errorf("Discover = %s, %v", got, err)
+	}
+}
+
+func TestClientRetransmission(t *testing.T) {
+	// a server losing the first requests
+	srvConn := listen(t)
+	var received atomic.Int32
+	go func() {
+		srv := &stun.Server{}
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := srvConn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if received.Add(1) <= 2 {
+				continue
+			}
+			var req stun.Message
+			if err := req.Unmarshal(buf[:n]); err != nil {
+				continue
+			}
+			addr, _ := stun.AddrPortOf(from)
+			_, _ = srvConn.WriteTo(srv.ServeMessage(&req, addr).Marshal(), from)
+		}
+	}()
+
+	c := &stun.Client{RTO: 20 * time.Millisecond}
+	if _, err := c.Binding(context.Background(), listen(t), srvConn.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+	if n := received.Load(); n != 3 {
+		t.Errorf("server received %d requests, want 3", n)
+	}
+
+	// nobody answering
+	c = &stun.Client{RTO: 10 * time.Millisecond, Rc: 3, Rm: 2}
+	start := time.Now()
+	if _, err := c.Binding(context.Background(), listen(t), listen(t).LocalAddr()); !errors.Is(err, stun.ErrTimeout) {
+		t.Errorf("Binding = %v, want ErrTimeout", err)
+	}
+	// 10ms + 20ms + 2*10ms
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("Binding timed out after %v, before its retransmissions", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if _, err := (&stun.Client{}).Binding(ctx, listen(t), listen(t).LocalAddr()); !errors.Is(err, context.Canceled) {
+		t.Errorf("Binding = %v, want Canceled", err)
+	}
+
+	// the deadline of ctx bounds the read, rather than the default RTO of 500ms
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, err := (&stun.Client{}).Binding(ctx, listen(t), listen(t).LocalAddr()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Binding = %v, want DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > 400*time.Millisecond {
+		t.Errorf("Binding returned after %v, not at the deadline of ctx", d)
+	}
+
+	// ctx done already
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := (&stun.Client{}).Binding(ctx, listen(t), listen(t).LocalAddr()); !errors.Is(err, context.Canceled) {
+		t.Errorf("Binding = %v, want Canceled", err)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	srv := &stun.Server{}
+	addr := netip.MustParseAddrPort("192.0.2.1:32853")
+
+	req := stun.NewMessage(stun.MessageType{Method: stun.MethodBinding, Class: stun.ClassRequest})
+	req.Add(0x7fff, []byte("?"))
+	resp := srv.ServeMessage(req, addr)
+	if e, err := resp.ErrorCode(); err != nil || e.Code != stun.ErrorUnknownAttribute.Code {
+		t.Errorf("ErrorCode = %v, %v, want %v", e, err, stun.ErrorUnknownAttribute)
+	}
+	if v, _ := resp.Get(stun.AttrUnknownAttributes); hex.EncodeToString(v) != "7fff" {
+		t.Errorf("UNKNOWN-ATTRIBUTES = %x, want 7fff", v)
+	}
+
+	req = stun.NewMessage(stun.MessageType{Method: 0x00f, Class: stun.ClassRequest})
+	if e, _ := srv.ServeMessage(req, addr).ErrorCode(); e.Code != stun.ErrorBadRequest.Code {
+		t.Errorf("ErrorCode = %v, want %v", e, stun.ErrorBadRequest)
+	}
+
+	ind := stun.NewMessage(stun.MessageType{Method: stun.MethodBinding, Class: stun.ClassIndication})
+	if resp := srv.ServeMessage(ind, addr); resp != nil {
+		t.Errorf("ServeMessage of an indication = %s, want nil", resp)
+	}
+}
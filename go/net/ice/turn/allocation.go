@@ -0,0 +1,244 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package turn
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/searKing/golang/go/net/ice/stun"
+)
+
+// allocKey identifies an allocation by its 5-tuple: the client transport address
+// and the server transport address, UDP being the only transport.
+type allocKey struct {
+	conn   net.PacketConn
+	client netip.AddrPort
+}
+
+// allocation is a relayed transport address allocated to a client, and the
+// permissions and channels installed on it.
+// https://tools.ietf.org/html/rfc8656#section-2.2
+type allocation struct {
+	srv           *Server
+	key           allocKey
+	client        net.Addr
+	username      string
+	transactionID stun.TransactionID // of the Allocate request
+	relay         net.PacketConn
+	relayed       netip.AddrPort
+
+	mu          sync.Mutex
+	expires     time.Time
+	timer       *time.Timer
+	permissions map[netip.Addr]time.Time           // expiry time by peer IP
+	channels    map[uint16]*channelBinding         // by channel number
+	peers       map[netip.AddrPort]*channelBinding // by peer
+}
+
+// channelBinding binds a channel number to a peer transport address.
+type channelBinding struct {
+	number  uint16
+	peer    netip.AddrPort
+	expires time.Time
+}
+
+func newAllocation(srv *Server, k allocKey, client net.Addr, username string, id stun.TransactionID, relay net.PacketConn, lifetime time.Duration) *allocation {
+	relayed, _ := stun.AddrPortOf(relay.LocalAddr())
+	a := &allocation{
+		srv:           srv,
+		key:           k,
+		client:        client,
+		username:      username,
+		transactionID: id,
+		relay:         relay,
+		relayed:       relayed,
+		expires:       time.Now().Add(lifetime),
+		permissions:   make(map[netip.Addr]time.Time),
+		channels:      make(map[uint16]*channelBinding),
+		peers:         make(map[netip.AddrPort]*channelBinding),
+	}
+	a.timer = time.AfterFunc(lifetime, a.expire)
+	return a
+}
+
+func (a *allocation) allocateResponse(req *stun.Message) *stun.Message {
+	a.mu.Lock()
+	lifetime := time.Until(a.expires).Round(time.Second)
+	a.mu.Unlock()
+	resp := stun.NewResponse(req, stun.ClassSuccessResponse)
+	resp.AddXORAddress(AttrXORRelayedAddress, a.relayed)
+	AddLifetime(resp, lifetime)
+	resp.AddXORAddress(stun.AttrXORMappedAddress, a.key.client)
+	return resp
+}
+
+func (a *allocation) refresh(lifetime time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.expires = time.Now().Add(lifetime)
+	a.timer.Reset(lifetime)
+}
+
+// expire removes the allocation unless refreshed meanwhile.
+func (a *allocation) expire() {
+	a.mu.Lock()
+	if left := time.Until(a.expires); left > 0 {
+		a.timer.Reset(left)
+		a.mu.Unlock()
+		return
+	}
+	a.mu.Unlock()
+	a.srv.removeAllocation(a)
+}
+
+func (a *allocation) close() {
+	a.timer.Stop()
+	_ = a.relay.Close()
+}
+
+// https://tools.ietf.org/html/rfc8656#section-10
+func (a *allocation) createPermission(req *stun.Message) *stun.Message {
+	peers, err := req.XORAddresses(AttrXORPeerAddress)
+	if err != nil || len(peers) == 0 {
+		return stun.NewErrorResponse(req, stun.ErrorBadRequest)
+	}
+	for _, peer := range peers {
+		if peer.Addr().Is4() != a.relayed.Addr().Is4() {
+			return stun.NewErrorResponse(req, ErrorPeerAddressFamilyMismatch)
+		}
+	}
+	a.mu.Lock()
+	for _, peer := range peers {
+		a.permissions[peer.Addr()] = time.Now().Add(PermissionLifetime)
+	}
+	a.mu.Unlock()
+	return stun.NewResponse(req, stun.ClassSuccessResponse)
+}
+
+// https://tools.ietf.org/html/rfc8656#section-12.2
+func (a *allocation) channelBind(req *stun.Message) *stun.Message {
+	number, err := ChannelNumber(req)
+	if err != nil || number < MinChannelNumber || number > MaxChannelNumber {
+		return stun.NewErrorResponse(req, stun.ErrorBadRequest)
+	}
+	peer, err := req.XORAddress(AttrXORPeerAddress)
+	if err != nil {
+		return stun.NewErrorResponse(req, stun.ErrorBadRequest)
+	}
+	if peer.Addr().Is4() != a.relayed.Addr().Is4() {
+		return stun.NewErrorResponse(req, ErrorPeerAddressFamilyMismatch)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	// neither the number nor the peer may be bound to another one
+	if b, ok := a.channels[number]; ok && b.peer != peer && now.Before(b.expires) {
+		return stun.NewErrorResponse(req, stun.ErrorBadRequest)
+	}
+	if b, ok := a.peers[peer]; ok && b.number != number && now.Before(b.expires) {
+		return stun.NewErrorResponse(req, stun.ErrorBadRequest)
+	}
+	if b, ok := a.channels[number]; ok {
+		delete(a.peers, b.peer)
+	}
+	if b, ok := a.peers[peer]; ok {
+		delete(a.channels, b.number)
+	}
+	b := &channelBinding{number: number, peer: peer, expires: now.Add(ChannelLifetime)}
+	a.channels[number] = b
+	a.peers[peer] = b
+	a.permissions[peer.Addr()] = now.Add(PermissionLifetime)
+	return stun.NewResponse(req, stun.ClassSuccessResponse)
+}
+
+// permitted reports whether datagrams may be relayed to and from ip.
+func (a *allocation) permitted(ip netip.Addr) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	expires, ok := a.permissions[ip]
+	return ok && time.Now().Before(expires)
+}
+
+// channelOf returns the channel bound to peer, if any.
+func (a *allocation) channelOf(peer netip.AddrPort) (uint16, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	b, ok := a.peers[peer]
+	if !ok || !time.Now().Before(b.expires) {
+		return 0, false
+	}
+	return b.number, true
+}
+
+// peerOf returns the peer bound to channel number, if any.
+func (a *allocation) peerOf(number uint16) (netip.AddrPort, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	b, ok := a.channels[number]
+	if !ok || !time.Now().Before(b.expires) {
+		return netip.AddrPort{}, false
+	}
+	return b.peer, true
+}
+
+// sendIndication relays the DATA of a Send indication to its XOR-PEER-ADDRESS.
+// https://tools.ietf.org/html/rfc8656#section-11.2
+func (a *allocation) sendIndication(ind *stun.Message) {
+	peer, err := ind.XORAddress(AttrXORPeerAddress)
+	if err != nil {
+		return
+	}
+	data, ok := ind.Get(AttrData)
+	if !ok {
+		return
+	}
+	a.sendTo(peer, data)
+}
+
+// sendChannelData relays the data of a ChannelData message to the peer bound to number.
+// https://tools.ietf.org/html/rfc8656#section-12.5
+func (a *allocation) sendChannelData(number uint16, data []byte) {
+	if peer, ok := a.peerOf(number); ok {
+		a.sendTo(peer, data)
+	}
+}
+
+func (a *allocation) sendTo(peer netip.AddrPort, data []byte) {
+	if !a.permitted(peer.Addr()) {
+		return
+	}
+	_, _ = a.relay.WriteTo(data, net.UDPAddrFromAddrPort(peer))
+}
+
+// serveRelay relays the datagrams received from permitted peers to the client,
+// over their channel if bound, in Data indications otherwise, until the relay is closed.
+// https://tools.ietf.org/html/rfc8656#section-11.3
+func (a *allocation) serveRelay() {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, from, err := a.relay.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		peer, ok := stun.AddrPortOf(from)
+		if !ok || !a.permitted(peer.Addr()) {
+			continue
+		}
+		var p []byte
+		if number, ok := a.channelOf(peer); ok {
+			p = AppendChannelData(nil, number, buf[:n])
+		} else {
+			ind := stun.NewMessage(stun.MessageType{Method: MethodData, Class: stun.ClassIndication})
+			ind.AddXORAddress(AttrXORPeerAddress, peer)
+			ind.Add(AttrData, buf[:n])
+			p = ind.Marshal()
+		}
+		_, _ = a.key.conn.WriteTo(p, a.client)
+	}
+}
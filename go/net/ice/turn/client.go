@@ -0,0 +1,425 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package turn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/searKing/golang/go/net/ice"
+	"github.com/searKing/golang/go/net/ice/stun"
+)
+
+// ErrClientClosed is returned by a Client closed by Close.
+var ErrClientClosed = errors.New("turn: client closed")
+
+// receiveQueueSize is the count of datagrams received from peers a Client queues
+// until Receive is called, further ones being dropped.
+const receiveQueueSize = 64
+
+// packet is a datagram received from a peer.
+type packet struct {
+	peer netip.AddrPort
+	data []byte
+}
+
+// Client holds an allocation on a TURN server, over a PacketConn to the server,
+// sending datagrams to and receiving datagrams from peers through its relayed
+// transport address.
+// Permissions, channels and the allocation itself expire unless refreshed by
+// calling CreatePermission, ChannelBind and Refresh again.
+type Client struct {
+	conn       net.PacketConn
+	server     net.Addr
+	serverAddr netip.AddrPort
+	username   string
+	password   string
+
+	stun     stun.Client
+	lifetime time.Duration
+
+	mu          sync.Mutex
+	realm       string
+	nonce       string
+	key         []byte
+	relayed     netip.AddrPort
+	mapped      netip.AddrPort
+	pending     map[stun.TransactionID]chan *stun.Message
+	channels    map[netip.AddrPort]uint16 // by peer
+	peers       map[uint16]netip.AddrPort // by channel number
+	nextChannel uint16
+	err         error // set once closed
+	done        chan struct{}
+
+	received chan packet
+}
+
+// NewClient returns a Client of the TURN server at server over conn, taking it over,
+// authenticating as username with password.
+func NewClient(conn net.PacketConn, server net.Addr, username, password string, opts ...ClientOption) *Client {
+	serverAddr, _ := stun.AddrPortOf(server)
+	c := &Client{
+		conn:        conn,
+		server:      server,
+		serverAddr:  serverAddr,
+		username:    username,
+		password:    password,
+		pending:     make(map[stun.TransactionID]chan *stun.Message),
+		channels:    make(map[netip.AddrPort]uint16),
+		peers:       make(map[uint16]netip.AddrPort),
+		nextChannel: MinChannelNumber,
+		done:        make(chan struct{}),
+		received:    make(chan packet, receiveQueueSize),
+	}
+	c.ApplyOptions(opts...)
+	go c.readLoop()
+	return c
+}
+
+// Dial returns a Client of the TURN server of u over a new UDP socket.
+// Only turn URLs over UDP are supported.
+func Dial(ctx context.Context, u *ice.URL, username, password string, opts ...ClientOption) (*Client, error) {
+	if u.Scheme != ice.SchemeTURN || u.Proto != ice.TransportUDP {
+		return nil, fmt.Errorf("turn: unsupported url %s", u)
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("turn: no address of %s", u.Host)
+	}
+	server := net.UDPAddrFromAddrPort(netip.AddrPortFrom(ips[0].Unmap(), uint16(u.Port)))
+	var lc net.ListenConfig
+	conn, err := lc.ListenPacket(ctx, "udp", ":0")
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn, server, username, password, opts...), nil
+}
+
+func (c *Client) ApplyOptions(options ...ClientOption) *Client {
+	for _, opt := range options {
+		if opt == nil {
+			continue
+		}
+		opt.apply(c)
+	}
+	return c
+}
+
+// Allocate asks the server for a relayed transport address and returns it.
+// https://tools.ietf.org/html/rfc8656#section-7.1
+func (c *Client) Allocate(ctx context.Context) (netip.AddrPort, error) {
+	resp, err := c.do(ctx, MethodAllocate, func(req *stun.Message) {
+		AddRequestedTransport(req, ProtoUDP)
+		if c.lifetime > 0 {
+			AddLifetime(req, c.lifetime)
+		}
+	})
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	relayed, err := resp.XORAddress(AttrXORRelayedAddress)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	mapped, _ := resp.XORAddress(stun.AttrXORMappedAddress)
+	c.mu.Lock()
+	c.relayed, c.mapped = relayed, mapped
+	c.mu.Unlock()
+	return relayed, nil
+}
+
+// Refresh extends the lifetime of the allocation by lifetime, within the limits of the
+// server, and returns the lifetime granted. A zero lifetime releases the allocation.
+// https://tools.ietf.org/html/rfc8656#section-8.1
+func (c *Client) Refresh(ctx context.Context, lifetime time.Duration) (time.Duration, error) {
+	resp, err := c.do(ctx, MethodRefresh, func(req *stun.Message) {
+		AddLifetime(req, lifetime)
+	})
+	var e stun.ErrorCode
+	if lifetime == 0 && errors.As(err, &e) && e.Code == ErrorAllocationMismatch.Code {
+		// released already, by a retransmission whose response got lost
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return Lifetime(resp)
+}
+
+// CreatePermission installs or refreshes permissions for the IP addresses of peers,
+// datagrams being relayed to and from permitted IP addresses only.
+// https://tools.ietf.org/html/rfc8656#section-9.1
+func (c *Client) CreatePermission(ctx context.Context, peers ...netip.AddrPort) error {
+	_, err := c.do(ctx, MethodCreatePermission, func(req *stun.Message) {
+		for _, peer := range peers {
+			req.AddXORAddress(AttrXORPeerAddress, peer)
+		}
+	})
+	return err
+}
+
+// ChannelBind binds a channel to peer, or refreshes its binding, and returns its number.
+// Datagrams to and from peer are then relayed as ChannelData messages, sparing the
+// 36 bytes overhead of Send and Data indications. It creates a permission for peer too.
+// https://tools.ietf.org/html/rfc8656#section-12.1
+func (c *Client) ChannelBind(ctx context.Context, peer netip.AddrPort) (uint16, error) {
+	c.mu.Lock()
+	number, bound := c.channels[peer]
+	if !bound {
+		if c.nextChannel > MaxChannelNumber {
+			c.mu.Unlock()
+			return 0, fmt.Errorf("turn: no channel number left")
+		}
+		number = c.nextChannel
+		c.nextChannel++
+	}
+	c.mu.Unlock()
+
+	_, err := c.do(ctx, MethodChannelBind, func(req *stun.Message) {
+		AddChannelNumber(req, number)
+		req.AddXORAddress(AttrXORPeerAddress, peer)
+	})
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	c.channels[peer] = number
+	c.peers[number] = peer
+	c.mu.Unlock()
+	return number, nil
+}
+
+// Send sends p to peer through the relayed transport address.
+// As for UDP, delivery is not guaranteed, p being dropped if peer is not permitted.
+func (c *Client) Send(p []byte, peer netip.AddrPort) error {
+	c.mu.Lock()
+	number, bound := c.channels[peer]
+	c.mu.Unlock()
+	var b []byte
+	if bound {
+		b = AppendChannelData(make([]byte, 0, 4+len(p)), number, p)
+	} else {
+		// https://tools.ietf.org/html/rfc8656#section-11.1
+		ind := stun.NewMessage(stun.MessageType{Method: MethodSend, Class: stun.ClassIndication})
+		ind.AddXORAddress(AttrXORPeerAddress, peer)
+		ind.Add(AttrData, p)
+		b = ind.Marshal()
+	}
+	_, err := c.conn.WriteTo(b, c.server)
+	return err
+}
+
+// Receive returns the next datagram received from a peer through the relayed transport address.
+func (c *Client) Receive(ctx context.Context) (p []byte, peer netip.AddrPort, err error) {
+	select {
+	case pkt := <-c.received:
+		return pkt.data, pkt.peer, nil
+	case <-ctx.Done():
+		return nil, netip.AddrPort{}, ctx.Err()
+	case <-c.done:
+		return nil, netip.AddrPort{}, c.Err()
+	}
+}
+
+// RelayedAddr returns the relayed transport address allocated, invalid until allocated.
+func (c *Client) RelayedAddr() netip.AddrPort {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.relayed
+}
+
+// MappedAddr returns the server reflexive transport address of the client, invalid until allocated.
+func (c *Client) MappedAddr() netip.AddrPort {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mapped
+}
+
+// Close closes the connection to the server.
+// The allocation is left to expire, Refresh with a zero lifetime releases it beforehand.
+func (c *Client) Close() error {
+	return c.closeWithError(ErrClientClosed)
+}
+
+// Err returns why the connection is closed, nil if still open.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// do sends a request of method, whose attributes are added by build, authenticated
+// with the long-term credentials, and returns its success response.
+// The request is sent again with the REALM and NONCE of the server once challenged
+// for them, or once the nonce is stale.
+// https://tools.ietf.org/html/rfc8489#section-9.2.3
+func (c *Client) do(ctx context.Context, method stun.Method, build func(req *stun.Message)) (*stun.Message, error) {
+	for attempt := 0; ; attempt++ {
+		c.mu.Lock()
+		realm, nonce, key := c.realm, c.nonce, c.key
+		c.mu.Unlock()
+
+		req := stun.NewMessage(stun.MessageType{Method: method, Class: stun.ClassRequest})
+		build(req)
+		if c.stun.Software != "" {
+			req.AddString(stun.AttrSoftware, c.stun.Software)
+		}
+		if key != nil {
+			req.AddString(stun.AttrUsername, c.username)
+			req.AddString(stun.AttrRealm, realm)
+			req.AddString(stun.AttrNonce, nonce)
+			req.AddMessageIntegrity(key)
+		}
+		req.AddFingerprint()
+
+		resp, err := c.transact(ctx, req)
+		var e stun.ErrorCode
+		if errors.As(err, &e) && attempt < 2 &&
+			(e.Code == stun.ErrorUnauthorized.Code && key == nil || e.Code == stun.ErrorStaleNonce.Code) {
+			realm, _ := resp.GetString(stun.AttrRealm)
+			nonce, ok := resp.GetString(stun.AttrNonce)
+			if !ok {
+				return nil, err
+			}
+			c.mu.Lock()
+			c.realm, c.nonce = realm, nonce
+			c.key = stun.LongTermKey(c.username, realm, c.password)
+			c.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if key != nil {
+			if err := resp.CheckMessageIntegrity(key); err != nil {
+				return nil, err
+			}
+		}
+		return resp, nil
+	}
+}
+
+// transact sends req and waits for its response, delivered by readLoop.
+func (c *Client) transact(ctx context.Context, req *stun.Message) (*stun.Message, error) {
+	ch := make(chan *stun.Message, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.pending[req.TransactionID] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, req.TransactionID)
+		c.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-c.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	resp, err := c.stun.Transact(ctx, req, func(p []byte) error {
+		_, err := c.conn.WriteTo(p, c.server)
+		return err
+	}, ch)
+	if err != nil && errors.Is(err, context.Canceled) {
+		if closeErr := c.Err(); closeErr != nil {
+			return nil, closeErr
+		}
+	}
+	return resp, err
+}
+
+// readLoop delivers responses to their transactions, and datagrams relayed from
+// peers to Receive, until the connection is closed.
+func (c *Client) readLoop() {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, from, err := c.conn.ReadFrom(buf)
+		if err != nil {
+			c.closeWithError(err)
+			return
+		}
+		if addr, ok := stun.AddrPortOf(from); !ok || addr != c.serverAddr {
+			continue
+		}
+		p := buf[:n]
+
+		if IsChannelData(p) {
+			number, data, err := ParseChannelData(p)
+			if err != nil {
+				continue
+			}
+			c.mu.Lock()
+			peer, ok := c.peers[number]
+			c.mu.Unlock()
+			if ok {
+				c.deliver(peer, append([]byte(nil), data...))
+			}
+			continue
+		}
+
+		var m stun.Message
+		if err := m.Unmarshal(append([]byte(nil), p...)); err != nil {
+			continue
+		}
+		switch m.Type.Class {
+		case stun.ClassSuccessResponse, stun.ClassErrorResponse:
+			c.mu.Lock()
+			ch, ok := c.pending[m.TransactionID]
+			c.mu.Unlock()
+			if ok {
+				select {
+				case ch <- &m:
+				default: // a response to a retransmission
+				}
+			}
+		case stun.ClassIndication:
+			if m.Type.Method != MethodData {
+				continue
+			}
+			peer, err := m.XORAddress(AttrXORPeerAddress)
+			data, ok := m.Get(AttrData)
+			if err == nil && ok {
+				c.deliver(peer, data)
+			}
+		}
+	}
+}
+
+// deliver queues a datagram received from peer for Receive, dropping it if the queue is full.
+func (c *Client) deliver(peer netip.AddrPort, data []byte) {
+	select {
+	case c.received <- packet{peer: peer, data: data}:
+	default:
+	}
+}
+
+// closeWithError closes the connection once, recording err as the reason.
+func (c *Client) closeWithError(err error) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil
+	}
+	c.err = err
+	close(c.done)
+	c.mu.Unlock()
+	return c.conn.Close()
+}
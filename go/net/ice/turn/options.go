@@ -0,0 +1,87 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package turn
+
+import (
+	"net/netip"
+	"time"
+
+	"github.com/searKing/golang/go/net/ice/stun"
+)
+
+// A ClientOption sets options of a Client.
+type ClientOption interface {
+	apply(*Client)
+}
+
+// ClientOptionFunc wraps a function that modifies Client into an
+// implementation of the ClientOption interface.
+type ClientOptionFunc func(*Client)
+
+func (f ClientOptionFunc) apply(c *Client) {
+	f(c)
+}
+
+// WithClientSTUN sets the retransmission timeouts of requests and the SOFTWARE they carry.
+func WithClientSTUN(s stun.Client) ClientOption {
+	return ClientOptionFunc(func(c *Client) {
+		c.stun = s
+	})
+}
+
+// WithClientLifetime sets the lifetime asked for the allocation, DefaultLifetime if not positive.
+func WithClientLifetime(d time.Duration) ClientOption {
+	return ClientOptionFunc(func(c *Client) {
+		c.lifetime = d
+	})
+}
+
+// A ServerOption sets options of a Server.
+type ServerOption interface {
+	apply(*Server)
+}
+
+// ServerOptionFunc wraps a function that modifies Server into an
+// implementation of the ServerOption interface.
+type ServerOptionFunc func(*Server)
+
+func (f ServerOptionFunc) apply(srv *Server) {
+	f(srv)
+}
+
+// WithServerSoftware sets the SOFTWARE of responses, none if empty.
+func WithServerSoftware(software string) ServerOption {
+	return ServerOptionFunc(func(srv *Server) {
+		srv.software = software
+		srv.binding.Software = software
+	})
+}
+
+// WithServerRelayIP sets the IP relayed transport addresses are allocated on.
+// By default, the IP of the connection a client is served on, or the loopback
+// address if unspecified.
+func WithServerRelayIP(ip netip.Addr) ServerOption {
+	return ServerOptionFunc(func(srv *Server) {
+		srv.relayIP = ip
+	})
+}
+
+// WithServerMaxLifetime limits the lifetime of allocations, DefaultMaxLifetime if not positive.
+func WithServerMaxLifetime(d time.Duration) ServerOption {
+	return ServerOptionFunc(func(srv *Server) {
+		if d > 0 {
+			srv.maxLifetime = d
+		}
+	})
+}
+
+// WithServerNonceLifetime sets how long a NONCE is valid for, DefaultNonceLifetime if not positive.
+func WithServerNonceLifetime(d time.Duration) ServerOption {
+	return ServerOptionFunc(func(srv *Server) {
+		if d > 0 {
+			srv.nonceLifetime = d
+		}
+	})
+}
@@ -0,0 +1,382 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package turn
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/searKing/golang/go/net/ice/stun"
+)
+
+// DefaultNonceLifetime is the default time a NONCE is valid for,
+// requests with an older one failing with stun.ErrorStaleNonce.
+const DefaultNonceLifetime = time.Hour
+
+// maxPacketSize is the size of the buffers datagrams are read into.
+const maxPacketSize = 64 << 10
+
+// AuthHandler returns the long-term key of username in realm, as computed by
+// stun.LongTermKey, false if username is unknown.
+type AuthHandler func(username, realm string) (key []byte, ok bool)
+
+// Server relays UDP datagrams between its clients and their peers, allocating a
+// relayed transport address to each client asking for it.
+// Binding requests are answered too, as by a stun.Server.
+type Server struct {
+	realm         string
+	auth          AuthHandler
+	software      string
+	relayIP       netip.Addr
+	maxLifetime   time.Duration
+	nonceLifetime time.Duration
+	nonceKey      []byte
+
+	binding stun.Server
+
+	mu          sync.Mutex
+	allocations map[allocKey]*allocation
+}
+
+// NewServer returns a Server authenticating clients of realm with auth.
+func NewServer(realm string, auth AuthHandler, opts ...ServerOption) *Server {
+	srv := &Server{
+		realm:         realm,
+		auth:          auth,
+		maxLifetime:   DefaultMaxLifetime,
+		nonceLifetime: DefaultNonceLifetime,
+		nonceKey:      make([]byte, sha1.Size),
+		allocations:   make(map[allocKey]*allocation),
+	}
+	if _, err := rand.Read(srv.nonceKey); err != nil {
+		panic("turn: read random nonce key: " + err.Error())
+	}
+	srv.ApplyOptions(opts...)
+	return srv
+}
+
+func (srv *Server) ApplyOptions(options ...ServerOption) *Server {
+	for _, opt := range options {
+		if opt == nil {
+			continue
+		}
+		opt.apply(srv)
+	}
+	return srv
+}
+
+// ListenAndServe listens on the UDP network address addr and serves TURN requests.
+func (srv *Server) ListenAndServe(addr string) error {
+	if addr == "" {
+		addr = ":3478"
+	}
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return srv.Serve(conn)
+}
+
+// Serve reads STUN and ChannelData messages from conn and serves them, until conn
+// fails, returning the error of its read.
+// Allocations made over conn are kept until they expire, are released or Close is called.
+func (srv *Server) Serve(conn net.PacketConn) error {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		client, ok := stun.AddrPortOf(from)
+		if !ok {
+			continue
+		}
+		srv.servePacket(allocKey{conn: conn, client: client}, from, buf[:n])
+	}
+}
+
+// Close releases all the allocations.
+func (srv *Server) Close() error {
+	srv.mu.Lock()
+	allocations := srv.allocations
+	srv.allocations = make(map[allocKey]*allocation)
+	srv.mu.Unlock()
+	for _, a := range allocations {
+		a.close()
+	}
+	return nil
+}
+
+func (srv *Server) servePacket(key allocKey, from net.Addr, p []byte) {
+	if IsChannelData(p) {
+		number, data, err := ParseChannelData(p)
+		if err != nil {
+			return
+		}
+		if a := srv.allocation(key); a != nil {
+			a.sendChannelData(number, data)
+		}
+		return
+	}
+
+	var m stun.Message
+	if err := m.Unmarshal(p); err != nil {
+		return
+	}
+	if m.Contains(stun.AttrFingerprint) && m.CheckFingerprint() != nil {
+		return
+	}
+	switch m.Type.Class {
+	case stun.ClassIndication:
+		if m.Type.Method != MethodSend {
+			return
+		}
+		if a := srv.allocation(key); a != nil {
+			a.sendIndication(&m)
+		}
+	case stun.ClassRequest:
+		if resp := srv.serveRequest(key, from, &m); resp != nil {
+			_, _ = key.conn.WriteTo(resp.Marshal(), from)
+		}
+	}
+}
+
+// comprehension-required attributes understood, by method
+var knownAttrs = map[stun.Method][]stun.AttrType{
+	MethodAllocate:         withCredentials(AttrRequestedTransport, AttrLifetime, AttrRequestedAddressFamily),
+	MethodRefresh:          withCredentials(AttrLifetime),
+	MethodCreatePermission: withCredentials(AttrXORPeerAddress),
+	MethodChannelBind:      withCredentials(AttrChannelNumber, AttrXORPeerAddress),
+}
+
+func withCredentials(types ...stun.AttrType) []stun.AttrType {
+	return append(types, stun.AttrUsername, stun.AttrRealm, stun.AttrNonce, stun.AttrMessageIntegrity)
+}
+
+func (srv *Server) serveRequest(k allocKey, from net.Addr, req *stun.Message) *stun.Message {
+	if req.Type.Method == stun.MethodBinding {
+		return srv.binding.ServeMessage(req, k.client)
+	}
+	known, ok := knownAttrs[req.Type.Method]
+	if !ok {
+		return srv.finish(stun.NewErrorResponse(req, stun.ErrorBadRequest), nil)
+	}
+	if unknown := req.UnknownAttributes(known...); len(unknown) > 0 {
+		resp := stun.NewErrorResponse(req, stun.ErrorUnknownAttribute)
+		resp.AddUnknownAttributes(unknown...)
+		return srv.finish(resp, nil)
+	}
+	username, key, challenge := srv.authenticate(req)
+	if challenge != nil {
+		return srv.finish(challenge, nil)
+	}
+
+	if req.Type.Method == MethodAllocate {
+		return srv.finish(srv.allocate(k, from, username, req), key)
+	}
+	a := srv.allocation(k)
+	if a == nil {
+		return srv.finish(stun.NewErrorResponse(req, ErrorAllocationMismatch), key)
+	}
+	if a.username != username {
+		return srv.finish(stun.NewErrorResponse(req, ErrorWrongCredentials), key)
+	}
+	var resp *stun.Message
+	switch req.Type.Method {
+	case MethodRefresh:
+		resp = srv.refresh(a, req)
+	case MethodCreatePermission:
+		resp = a.createPermission(req)
+	case MethodChannelBind:
+		resp = a.channelBind(req)
+	}
+	return srv.finish(resp, key)
+}
+
+// finish appends SOFTWARE, MESSAGE-INTEGRITY if key is not nil, and FINGERPRINT to resp.
+func (srv *Server) finish(resp *stun.Message, key []byte) *stun.Message {
+	if srv.software != "" {
+		resp.AddString(stun.AttrSoftware, srv.software)
+	}
+	if key != nil {
+		resp.AddMessageIntegrity(key)
+	}
+	resp.AddFingerprint()
+	return resp
+}
+
+// authenticate checks the long-term credentials of req, returning the error
+// response to send back if they are missing or wrong.
+// https://tools.ietf.org/html/rfc8489#section-9.2.4
+func (srv *Server) authenticate(req *stun.Message) (username string, key []byte, challenge *stun.Message) {
+	if !req.Contains(stun.AttrMessageIntegrity) {
+		return "", nil, srv.challenge(req, stun.ErrorUnauthorized)
+	}
+	username, hasUsername := req.GetString(stun.AttrUsername)
+	realm, hasRealm := req.GetString(stun.AttrRealm)
+	nonce, hasNonce := req.GetString(stun.AttrNonce)
+	if !hasUsername || !hasRealm || !hasNonce {
+		return "", nil, stun.NewErrorResponse(req, stun.ErrorBadRequest)
+	}
+	if !srv.checkNonce(nonce) {
+		return "", nil, srv.challenge(req, stun.ErrorStaleNonce)
+	}
+	if realm != srv.realm {
+		return "", nil, srv.challenge(req, stun.ErrorUnauthorized)
+	}
+	key, ok := srv.auth(username, realm)
+	if !ok || req.CheckMessageIntegrity(key) != nil {
+		return "", nil, srv.challenge(req, stun.ErrorUnauthorized)
+	}
+	return username, key, nil
+}
+
+// challenge returns an error response carrying the REALM and a new NONCE to authenticate with.
+func (srv *Server) challenge(req *stun.Message, e stun.ErrorCode) *stun.Message {
+	resp := stun.NewErrorResponse(req, e)
+	resp.AddString(stun.AttrRealm, srv.realm)
+	resp.AddString(stun.AttrNonce, srv.nonce())
+	return resp
+}
+
+// nonce returns a new nonce, its expiry time followed by a MAC of it, so that
+// no state is kept per client.
+func (srv *Server) nonce() string {
+	expiry := binary.BigEndian.AppendUint64(nil, uint64(time.Now().Add(srv.nonceLifetime).UnixNano()))
+	return hex.EncodeToString(append(expiry, srv.nonceMAC(expiry)...))
+}
+
+func (srv *Server) checkNonce(nonce string) bool {
+	b, err := hex.DecodeString(nonce)
+	if err != nil || len(b) != 8+nonceMACSize {
+		return false
+	}
+	if !hmac.Equal(b[8:], srv.nonceMAC(b[:8])) {
+		return false
+	}
+	return time.Now().UnixNano() < int64(binary.BigEndian.Uint64(b[:8]))
+}
+
+const nonceMACSize = 8
+
+func (srv *Server) nonceMAC(expiry []byte) []byte {
+	mac := hmac.New(sha1.New, srv.nonceKey)
+	mac.Write(expiry)
+	return mac.Sum(nil)[:nonceMACSize]
+}
+
+// lifetime returns the lifetime granted to the allocation of req: zero if asked for,
+// else the one asked for within [DefaultLifetime, maxLifetime].
+// https://tools.ietf.org/html/rfc8656#section-7.2
+func (srv *Server) lifetime(req *stun.Message) (time.Duration, error) {
+	d, err := Lifetime(req)
+	if errors.Is(err, stun.ErrAttributeNotFound) {
+		return DefaultLifetime, nil
+	}
+	if err != nil || d == 0 {
+		return 0, err
+	}
+	if d > srv.maxLifetime {
+		d = srv.maxLifetime
+	}
+	if d < DefaultLifetime {
+		d = DefaultLifetime
+	}
+	return d, nil
+}
+
+func (srv *Server) allocate(k allocKey, from net.Addr, username string, req *stun.Message) *stun.Message {
+	if a := srv.allocation(k); a != nil {
+		if a.transactionID == req.TransactionID && a.username == username {
+			// a retransmission, whose response got lost
+			return a.allocateResponse(req)
+		}
+		return stun.NewErrorResponse(req, ErrorAllocationMismatch)
+	}
+	proto, err := RequestedTransport(req)
+	if err != nil {
+		return stun.NewErrorResponse(req, stun.ErrorBadRequest)
+	}
+	if proto != ProtoUDP {
+		return stun.NewErrorResponse(req, ErrorUnsupportedTransport)
+	}
+	relayIP := srv.relayIPOf(k.conn)
+	if v, ok := req.Get(AttrRequestedAddressFamily); ok {
+		if len(v) != 4 {
+			return stun.NewErrorResponse(req, stun.ErrorBadRequest)
+		}
+		if !(v[0] == familyIPv4 && relayIP.Is4() || v[0] == familyIPv6 && relayIP.Is6()) {
+			return stun.NewErrorResponse(req, ErrorAddressFamilyNotSupported)
+		}
+	}
+	lifetime, err := srv.lifetime(req)
+	if err != nil {
+		return stun.NewErrorResponse(req, stun.ErrorBadRequest)
+	}
+	if lifetime == 0 {
+		lifetime = DefaultLifetime
+	}
+	relay, err := net.ListenUDP("udp", net.UDPAddrFromAddrPort(netip.AddrPortFrom(relayIP, 0)))
+	if err != nil {
+		return stun.NewErrorResponse(req, ErrorInsufficientCapacity)
+	}
+
+	a := newAllocation(srv, k, from, username, req.TransactionID, relay, lifetime)
+	srv.mu.Lock()
+	srv.allocations[k] = a
+	srv.mu.Unlock()
+	go a.serveRelay()
+	return a.allocateResponse(req)
+}
+
+func (srv *Server) refresh(a *allocation, req *stun.Message) *stun.Message {
+	lifetime, err := srv.lifetime(req)
+	if err != nil {
+		return stun.NewErrorResponse(req, stun.ErrorBadRequest)
+	}
+	if lifetime == 0 {
+		srv.removeAllocation(a)
+	} else {
+		a.refresh(lifetime)
+	}
+	resp := stun.NewResponse(req, stun.ClassSuccessResponse)
+	AddLifetime(resp, lifetime)
+	return resp
+}
+
+// relayIPOf returns the IP to allocate relayed transport addresses of clients served on conn on.
+func (srv *Server) relayIPOf(conn net.PacketConn) netip.Addr {
+	if srv.relayIP.IsValid() {
+		return srv.relayIP
+	}
+	if local, ok := stun.AddrPortOf(conn.LocalAddr()); ok && !local.Addr().IsUnspecified() {
+		return local.Addr()
+	}
+	return netip.AddrFrom4([4]byte{127, 0, 0, 1})
+}
+
+func (srv *Server) allocation(k allocKey) *allocation {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return srv.allocations[k]
+}
+
+func (srv *Server) removeAllocation(a *allocation) {
+	srv.mu.Lock()
+	if srv.allocations[a.key] == a {
+		delete(srv.allocations, a.key)
+	}
+	srv.mu.Unlock()
+	a.close()
+}
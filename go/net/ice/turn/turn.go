@@ -0,0 +1,187 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package turn implements Traversal Using Relays around NAT (TURN) over UDP,
+// as described in https://tools.ietf.org/html/rfc8656: a relay Server allocating
+// relayed transport addresses to its clients, and a Client holding an allocation.
+// Requests are authenticated by the long-term credential mechanism of STUN.
+package turn
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/searKing/golang/go/net/ice/stun"
+)
+
+// https://tools.ietf.org/html/rfc8656#section-17
+const (
+	MethodAllocate         stun.Method = 0x003
+	MethodRefresh          stun.Method = 0x004
+	MethodSend             stun.Method = 0x006
+	MethodData             stun.Method = 0x007
+	MethodCreatePermission stun.Method = 0x008
+	MethodChannelBind      stun.Method = 0x009
+)
+
+// https://tools.ietf.org/html/rfc8656#section-18
+const (
+	AttrChannelNumber          stun.AttrType = 0x000C
+	AttrLifetime               stun.AttrType = 0x000D
+	AttrXORPeerAddress         stun.AttrType = 0x0012
+	AttrData                   stun.AttrType = 0x0013
+	AttrXORRelayedAddress      stun.AttrType = 0x0016
+	AttrRequestedAddressFamily stun.AttrType = 0x0017
+	AttrEvenPort               stun.AttrType = 0x0018
+	AttrRequestedTransport     stun.AttrType = 0x0019
+	AttrDontFragment           stun.AttrType = 0x001A
+	AttrReservationToken       stun.AttrType = 0x0022
+)
+
+// https://tools.ietf.org/html/rfc8656#section-19
+var (
+	ErrorForbidden                 = stun.ErrorCode{Code: 403, Reason: "Forbidden"}
+	ErrorAllocationMismatch        = stun.ErrorCode{Code: 437, Reason: "Allocation Mismatch"}
+	ErrorAddressFamilyNotSupported = stun.ErrorCode{Code: 440, Reason: "Address Family not Supported"}
+	ErrorWrongCredentials          = stun.ErrorCode{Code: 441, Reason: "Wrong Credentials"}
+	ErrorUnsupportedTransport      = stun.ErrorCode{Code: 442, Reason: "Unsupported Transport Protocol"}
+	ErrorPeerAddressFamilyMismatch = stun.ErrorCode{Code: 443, Reason: "Peer Address Family Mismatch"}
+	ErrorAllocationQuotaReached    = stun.ErrorCode{Code: 486, Reason: "Allocation Quota Reached"}
+	ErrorInsufficientCapacity      = stun.ErrorCode{Code: 508, Reason: "Insufficient Capacity"}
+)
+
+// https://tools.ietf.org/html/rfc8656#section-3.8
+const (
+	// DefaultLifetime is the lifetime of an allocation not asking for a longer one.
+	DefaultLifetime = 10 * time.Minute
+	// DefaultMaxLifetime is the default limit of the lifetime of an allocation.
+	DefaultMaxLifetime = time.Hour
+	// PermissionLifetime is the lifetime of a permission, unless refreshed.
+	PermissionLifetime = 5 * time.Minute
+	// ChannelLifetime is the lifetime of a channel binding, unless refreshed.
+	ChannelLifetime = 10 * time.Minute
+)
+
+// ProtoUDP is the value of REQUESTED-TRANSPORT for UDP, the only transport relayed.
+const ProtoUDP = 17
+
+// Channel numbers are in [MinChannelNumber, MaxChannelNumber].
+// https://tools.ietf.org/html/rfc8656#section-12
+const (
+	MinChannelNumber uint16 = 0x4000
+	MaxChannelNumber uint16 = 0x4FFF
+)
+
+// address families of REQUESTED-ADDRESS-FAMILY
+const (
+	familyIPv4 = 0x01
+	familyIPv6 = 0x02
+)
+
+func init() {
+	for m, name := range map[stun.Method]string{
+		MethodAllocate:         "Allocate",
+		MethodRefresh:          "Refresh",
+		MethodSend:             "Send",
+		MethodData:             "Data",
+		MethodCreatePermission: "CreatePermission",
+		MethodChannelBind:      "ChannelBind",
+	} {
+		stun.RegisterMethodName(m, name)
+	}
+	for t, name := range map[stun.AttrType]string{
+		AttrChannelNumber:          "CHANNEL-NUMBER",
+		AttrLifetime:               "LIFETIME",
+		AttrXORPeerAddress:         "XOR-PEER-ADDRESS",
+		AttrData:                   "DATA",
+		AttrXORRelayedAddress:      "XOR-RELAYED-ADDRESS",
+		AttrRequestedAddressFamily: "REQUESTED-ADDRESS-FAMILY",
+		AttrEvenPort:               "EVEN-PORT",
+		AttrRequestedTransport:     "REQUESTED-TRANSPORT",
+		AttrDontFragment:           "DONT-FRAGMENT",
+		AttrReservationToken:       "RESERVATION-TOKEN",
+	} {
+		stun.RegisterAttrName(t, name)
+	}
+}
+
+// AddLifetime appends a LIFETIME attribute, d truncated to seconds.
+func AddLifetime(m *stun.Message, d time.Duration) {
+	m.Add(AttrLifetime, binary.BigEndian.AppendUint32(nil, uint32(d/time.Second)))
+}
+
+// Lifetime returns the LIFETIME attribute.
+func Lifetime(m *stun.Message) (time.Duration, error) {
+	v, ok := m.Get(AttrLifetime)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", stun.ErrAttributeNotFound, AttrLifetime)
+	}
+	if len(v) != 4 {
+		return 0, fmt.Errorf("turn: bad LIFETIME length %d", len(v))
+	}
+	return time.Duration(binary.BigEndian.Uint32(v)) * time.Second, nil
+}
+
+// AddChannelNumber appends a CHANNEL-NUMBER attribute.
+func AddChannelNumber(m *stun.Message, number uint16) {
+	m.Add(AttrChannelNumber, []byte{byte(number >> 8), byte(number), 0, 0}) // number, RFFU
+}
+
+// ChannelNumber returns the CHANNEL-NUMBER attribute.
+func ChannelNumber(m *stun.Message) (uint16, error) {
+	v, ok := m.Get(AttrChannelNumber)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", stun.ErrAttributeNotFound, AttrChannelNumber)
+	}
+	if len(v) != 4 {
+		return 0, fmt.Errorf("turn: bad CHANNEL-NUMBER length %d", len(v))
+	}
+	return binary.BigEndian.Uint16(v), nil
+}
+
+// AddRequestedTransport appends a REQUESTED-TRANSPORT attribute of protocol proto, such as ProtoUDP.
+func AddRequestedTransport(m *stun.Message, proto byte) {
+	m.Add(AttrRequestedTransport, []byte{proto, 0, 0, 0})
+}
+
+// RequestedTransport returns the protocol of the REQUESTED-TRANSPORT attribute.
+func RequestedTransport(m *stun.Message) (byte, error) {
+	v, ok := m.Get(AttrRequestedTransport)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", stun.ErrAttributeNotFound, AttrRequestedTransport)
+	}
+	if len(v) != 4 {
+		return 0, fmt.Errorf("turn: bad REQUESTED-TRANSPORT length %d", len(v))
+	}
+	return v[0], nil
+}
+
+// IsChannelData reports whether b looks like a ChannelData message, as opposed to
+// a STUN message.
+func IsChannelData(b []byte) bool {
+	return len(b) >= 4 && b[0]&0xC0 == 0x40
+}
+
+// AppendChannelData appends a ChannelData message carrying data over channel number to b.
+// https://tools.ietf.org/html/rfc8656#section-12.4
+func AppendChannelData(b []byte, number uint16, data []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, number)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
+
+// ParseChannelData returns the channel number and data of the ChannelData message in b,
+// data referencing b.
+func ParseChannelData(b []byte) (number uint16, data []byte, err error) {
+	if !IsChannelData(b) {
+		return 0, nil, fmt.Errorf("turn: not a ChannelData message")
+	}
+	number = binary.BigEndian.Uint16(b[0:2])
+	n := int(binary.BigEndian.Uint16(b[2:4]))
+	if 4+n > len(b) {
+		return 0, nil, fmt.Errorf("turn: truncated ChannelData of %d bytes, want %d", len(b)-4, n)
+	}
+	return number, b[4 : 4+n], nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package turn_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/net/ice/stun"
+	"github.com/searKing/golang/go/net/ice/turn"
+)
+
+const realm = "example.org"
+
+var users = map[string]string{"alice": "secret"}
+
+func auth(username, realm string) ([]byte, bool) {
+	password, ok := users[username]
+	if !ok {
+		return nil, false
+	}
+	return stun.LongTermKey(username, realm, password), true
+}
+
+func listen(t *testing.T) net.PacketConn {
+	t.Helper()
+	return listenOn(t, "127.0.0.1:0")
+}
+
+func listenOn(t *testing.T, addr string) net.PacketConn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// serve serves TURN on loopback, returning the address of the server.
+func serve(t *testing.T, opts ...turn.ServerOption) net.Addr {
+	t.Helper()
+	srv := turn.NewServer(realm, auth, opts...)
+	conn := listen(t)
+	go srv.Serve(conn)
+	t.Cleanup(func() { srv.Close() })
+	return conn.LocalAddr()
+}
+
+func newClient(t *testing.T, server net.Addr, username, password string) *turn.Client {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := turn.NewClient(conn, server, username, password,
+		turn.WithClientSTUN(stun.Client{RTO: 50 * time.Millisecond, Rc: 3}))
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func peerAddr(conn net.PacketConn) netip.AddrPort {
+	return conn.LocalAddr().(*net.UDPAddr).AddrPort()
+}
+
+func readFrom(t *testing.T, conn net.PacketConn) (string, netip.AddrPort) {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1500)
+	n, from, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, _ := stun.AddrPortOf(from)
+	return string(buf[:n]), addr
+}
+
+func receive(t *testing.T, c *turn.Client) (string, netip.AddrPort) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	p, peer, err := c.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(p), peer
+}
+
+func TestClientRelay(t *testing.T) {
+	server := serve(t)
+	c := newClient(t, server, "alice", "secret")
+	ctx := context.Background()
+
+	relayed, err := c.Allocate(ctx)
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if !relayed.Addr().IsLoopback() {
+		t.Errorf("relayed address %s, want a loopback one", relayed)
+	}
+	if mapped := c.MappedAddr(); !mapped.Addr().IsLoopback() {
+		t.Errorf("mapped address %s, want a loopback one", mapped)
+	}
+
+	// permissions are by IP address
+	peer, stranger := listen(t), listenOn(t, "127.0.0.2:0")
+	if err := c.CreatePermission(ctx, peerAddr(peer)); err != nil {
+		t.Fatalf("CreatePermission: %v", err)
+	}
+
+	// Send and Data indications
+	if err := c.Send([]byte("hello"), peerAddr(peer)); err != nil {
+		t.Fatal(err)
+	}
+	if msg, from := readFrom(t, peer); msg != "hello" || from != relayed {
+		t.Errorf("peer received %q from %s, want %q from %s", msg, from, "hello", relayed)
+	}
+	// not permitted, dropped
+	if _, err := stranger.WriteTo([]byte("intrusion"), net.UDPAddrFromAddrPort(relayed)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := peer.WriteTo([]byte("hi"), net.UDPAddrFromAddrPort(relayed)); err != nil {
+		t.Fatal(err)
+	}
+	if msg, from := receive(t, c); msg != "hi" || from != peerAddr(peer) {
+		t.Errorf("client received %q from %s, want %q from %s", msg, from, "hi", peerAddr(peer))
+	}
+
+	// ChannelData
+	number, err := c.ChannelBind(ctx, peerAddr(peer))
+	if err != nil {
+		t.Fatalf("ChannelBind: %v", err)
+	}
+	if number < turn.MinChannelNumber || number > turn.MaxChannelNumber {
+		t.Errorf("channel number %#x out of range", number)
+	}
+	if err := c.Send([]byte("over channel"), peerAddr(peer)); err != nil {
+		t.Fatal(err)
+	}
+	if msg, _ := readFrom(t, peer); msg != "over channel" {
+		t.Errorf("peer received %q, want %q", msg, "over channel")
+	}
+	if _, err := peer.WriteTo([]byte("back over channel"), net.UDPAddrFromAddrPort(relayed)); err != nil {
+		t.Fatal(err)
+	}
+	if msg, from := receive(t, c); msg != "back over channel" || from != peerAddr(peer) {
+		t.Errorf("client received %q from %s, want %q from %s", msg, from, "back over channel", peerAddr(peer))
+	}
+
+	// refresh, then release
+	if d, err := c.Refresh(ctx, 20*time.Minute); err != nil || d != 20*time.Minute {
+		t.Errorf("Refresh = %v, %v, want %v", d, err, 20*time.Minute)
+	}
+	if _, err := c.Refresh(ctx, 0); err != nil {
+		t.Errorf("Refresh(0): %v", err)
+	}
+	var e stun.ErrorCode
+	if err := c.CreatePermission(ctx, peerAddr(peer)); !errors.As(err, &e) || e.Code != turn.ErrorAllocationMismatch.Code {
+		t.Errorf("CreatePermission once released = %v, want %v", err, turn.ErrorAllocationMismatch)
+	}
+}
+
+func TestServerAuthentication(t *testing.T) {
+	server := serve(t, turn.WithServerNonceLifetime(50*time.Millisecond))
+	ctx := context.Background()
+
+	var e stun.ErrorCode
+	if _, err := newClient(t, server, "alice", "wrong").Allocate(ctx); !errors.As(err, &e) || e.Code != stun.ErrorUnauthorized.Code {
+		t.Errorf("Allocate with a wrong password = %v, want %v", err, stun.ErrorUnauthorized)
+	}
+	if _, err := newClient(t, server, "mallory", "secret").Allocate(ctx); !errors.As(err, &e) || e.Code != stun.ErrorUnauthorized.Code {
+		t.Errorf("Allocate of an unknown user = %v, want %v", err, stun.ErrorUnauthorized)
+	}
+
+	c := newClient(t, server, "alice", "secret")
+	if _, err := c.Allocate(ctx); err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	// a second allocation of the same 5-tuple
+	if _, err := c.Allocate(ctx); !errors.As(err, &e) || e.Code != turn.ErrorAllocationMismatch.Code {
+		t.Errorf("Allocate again = %v, want %v", err, turn.ErrorAllocationMismatch)
+	}
+	// the nonce goes stale, the client retries with a fresh one
+	time.Sleep(100 * time.Millisecond)
+	if err := c.CreatePermission(ctx, netip.MustParseAddrPort("127.0.0.1:9")); err != nil {
+		t.Errorf("CreatePermission with a stale nonce: %v", err)
+	}
+}
+
+func TestServerBinding(t *testing.T) {
+	server := serve(t)
+	conn := listen(t)
+	got, err := (&stun.Client{RTO: 50 * time.Millisecond}).Binding(context.Background(), conn, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := peerAddr(conn); got != want {
+		t.Errorf("Binding = %s, want %s", got, want)
+	}
+}
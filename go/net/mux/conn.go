@@ -118,11 +118,18 @@ func (c *conn) setState(nc net.Conn, state ConnState) {
 	if state > 0xff || state < 0 {
 		panic("internal error")
 	}
+	from := state
+	if state != ConnStateNew {
+		from, _ = c.getState()
+	}
 	packedState := uint64(time.Now().Unix()<<8) | uint64(state)
 	c.curPacketState.Store(packedState)
 	if hook := srv.ConnStateHook; hook != nil {
 		hook(nc, state)
 	}
+	if observer := srv.Observer; observer != nil {
+		observer.ConnStateChanged(nc, from, state)
+	}
 }
 
 func (c *conn) getState() (state ConnState, unixSec int64) {
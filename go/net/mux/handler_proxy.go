@@ -0,0 +1,224 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mux
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/searKing/golang/go/net/resolver"
+	time_ "github.com/searKing/golang/go/time"
+)
+
+// ReverseProxy is a layer-4 HandlerConn, it splices connections to an upstream,
+// resolved by Target via github.com/searKing/golang/go/net/resolver, as "dns:///foo.bar:80" or "127.0.0.1:80".
+// Bytes sniffed by the ServeMux are replayed to the upstream, so connections are relayed untouched.
+//
+// A ReverseProxy is expected to be registered for one route, so ReverseProxyStats counts bytes of the route.
+type ReverseProxy struct {
+	// Target is the upstream to resolve for every connection, load-balanced by the resolver of the scheme.
+	Target string
+	// Network is the network to dial, "tcp" if empty.
+	Network string
+	// ResolveOneAddrOptions are options to resolve Target, as pickers.
+	ResolveOneAddrOptions []resolver.ResolveOneAddrOption
+
+	// DialContext specifies the dial function for creating connections to upstreams.
+	// If DialContext is nil, net.Dialer is used.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	// DialTimeout is the maximum amount of time a dial will wait for a connect to complete, no timeout if zero.
+	DialTimeout time.Duration
+	// IdleTimeout is the maximum amount of time to wait for data in both directions,
+	// connections are closed if idle for IdleTimeout, no timeout if zero.
+	IdleTimeout time.Duration
+
+	// ErrorLog specifies an optional logger for errors dialing upstreams.
+	// If nil, logging is done via the log package's standard logger.
+	ErrorLog *log.Logger
+
+	conns       atomic.Int64
+	activeConns atomic.Int64
+	bytesIn     atomic.Int64
+	bytesOut    atomic.Int64
+}
+
+// ReverseProxyStats is the statistics of a ReverseProxy.
+type ReverseProxyStats struct {
+	Conns       int64 // connections served
+	ActiveConns int64 // connections in serving
+	BytesIn     int64 // bytes relayed from clients to upstreams
+	BytesOut    int64 // bytes relayed from upstreams to clients
+}
+
+// NewReverseProxy returns a ReverseProxy relays connections to target.
+func NewReverseProxy(target string) *ReverseProxy {
+	return &ReverseProxy{Target: target}
+}
+
+// Stats returns the statistics of the ReverseProxy.
+func (p *ReverseProxy) Stats() ReverseProxyStats {
+	return ReverseProxyStats{
+		Conns:       p.conns.Load(),
+		ActiveConns: p.activeConns.Load(),
+		BytesIn:     p.bytesIn.Load(),
+		BytesOut:    p.bytesOut.Load(),
+	}
+}
+
+// Serve relays c to the upstream resolved, until both directions finished or idle for IdleTimeout.
+func (p *ReverseProxy) Serve(c net.Conn) {
+	defer c.Close()
+	p.conns.Add(1)
+	p.activeConns.Add(1)
+	defer p.activeConns.Add(-1)
+
+	ctx := context.Background()
+	var cost time_.Cost
+	cost.Start()
+	upstream, addr, err := p.dial(ctx)
+	if err != nil {
+		p.logf("mux: proxy %s to %s: %s", c.RemoteAddr(), p.Target, err)
+		if addr.Addr != "" {
+			_ = resolver.ResolveDone(ctx, p.Target, resolver.DoneInfo{Err: err, Addr: addr, Duration: cost.Elapse()})
+		}
+		return
+	}
+	defer upstream.Close()
+
+	err = p.splice(c, upstream)
+	_ = resolver.ResolveDone(ctx, p.Target, resolver.DoneInfo{Err: err, Addr: addr, Duration: cost.Elapse()})
+}
+
+func (p *ReverseProxy) dial(ctx context.Context) (net.Conn, resolver.Address, error) {
+	if p.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.DialTimeout)
+		defer cancel()
+	}
+	addr, err := resolver.ResolveOneAddr(ctx, p.Target, p.ResolveOneAddrOptions...)
+	if err != nil {
+		return nil, addr, err
+	}
+	network := p.Network
+	if network == "" {
+		network = "tcp"
+	}
+	dial := p.DialContext
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+	c, err := dial(ctx, network, addr.Addr)
+	return c, addr, err
+}
+
+// splice copies in both directions, the writing side is shut down once the reading side is done,
+// all are closed on error.
+func (p *ReverseProxy) splice(client, upstream net.Conn) error {
+	var wg sync.WaitGroup
+	var errs [2]error
+	var lastActive atomic.Int64 // unix nano of the last data relayed in either direction
+	lastActive.Store(time.Now().UnixNano())
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		errs[0] = p.copy(upstream, client, &p.bytesIn, &lastActive)
+	}()
+	go func() {
+		defer wg.Done()
+		errs[1] = p.copy(client, upstream, &p.bytesOut, &lastActive)
+	}()
+	wg.Wait()
+	if errs[0] != nil {
+		return errs[0]
+	}
+	return errs[1]
+}
+
+var proxyBufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 32<<10)
+		return &b
+	},
+}
+
+// copy copies from src to dst until EOF, a connection is idle if no data relayed in either direction.
+func (p *ReverseProxy) copy(dst, src net.Conn, written, lastActive *atomic.Int64) error {
+	bufp := proxyBufPool.Get().(*[]byte)
+	defer proxyBufPool.Put(bufp)
+	buf := *bufp
+
+	for {
+		if p.IdleTimeout > 0 {
+			_ = src.SetReadDeadline(time.Unix(0, lastActive.Load()).Add(p.IdleTimeout))
+		}
+		nr, rerr := src.Read(buf)
+		if nr > 0 {
+			lastActive.Store(time.Now().UnixNano())
+			if p.IdleTimeout > 0 {
+				_ = dst.SetWriteDeadline(time.Now().Add(p.IdleTimeout))
+			}
+			nw, werr := dst.Write(buf[:nr])
+			written.Add(int64(nw))
+			if werr == nil && nw != nr {
+				werr = io.ErrShortWrite
+			}
+			if werr != nil {
+				// abort both directions
+				_ = src.Close()
+				_ = dst.Close()
+				return werr
+			}
+		}
+		if rerr == nil {
+			continue
+		}
+		if errors.Is(rerr, io.EOF) {
+			// half-close, the other direction goes on
+			if closeWrite(dst) != nil {
+				_ = dst.Close()
+			}
+			return nil
+		}
+		if errors.Is(rerr, os.ErrDeadlineExceeded) {
+			// active in the other direction
+			if time.Since(time.Unix(0, lastActive.Load())) < p.IdleTimeout {
+				continue
+			}
+			rerr = errProxyIdleTimeout
+		}
+		_ = src.Close()
+		_ = dst.Close()
+		return rerr
+	}
+}
+
+func (p *ReverseProxy) logf(format string, args ...any) {
+	if p.ErrorLog != nil {
+		p.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// closeWrite shuts down the writing side of c, if supported, as *net.TCPConn and *net.UnixConn.
+func closeWrite(c net.Conn) error {
+	if cw, ok := c.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return errCloseWriteUnsupported
+}
+
+var (
+	errCloseWriteUnsupported = errors.New("mux: CloseWrite unsupported")
+	errProxyIdleTimeout      = errors.New("mux: proxy idle timeout")
+)
@@ -0,0 +1,128 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mux_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/net/mux"
+	_ "github.com/searKing/golang/go/net/resolver/passthrough"
+	"github.com/searKing/golang/go/testing/leakcheck"
+)
+
+// serveUpstream serves l by h, until l is closed.
+func serveUpstream(l net.Listener, h func(c net.Conn)) {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer c.Close()
+			h(c)
+		}()
+	}
+}
+
+// serveMux serves l by mu, until l is closed.
+func serveMux(l net.Listener, mu *mux.ServeMux) {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go mu.Serve(c)
+	}
+}
+
+func TestReverseProxy(t *testing.T) {
+	defer leakcheck.Check(t)
+	upstreamL := testListener(t)
+	defer upstreamL.Close()
+	// echo all read after the client half-closed, so the sniffed bytes are checked, as well as half-close.
+	go serveUpstream(upstreamL, func(c net.Conn) {
+		data, _ := io.ReadAll(c)
+		_, _ = c.Write(bytes.ToUpper(data))
+	})
+
+	l := testListener(t)
+	defer l.Close()
+	proxy := mux.NewReverseProxy(upstreamL.Addr().String())
+	var mu mux.ServeMux
+	mu.Handle(mux.AnyPrefixMatcher("ping"), proxy)
+	go serveMux(l, &mu)
+
+	const payload = "ping from client"
+	c, err := net.Dial(l.Addr().Network(), l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := io.WriteString(c, payload); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.(*net.TCPConn).CloseWrite()
+	got, err := io.ReadAll(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "PING FROM CLIENT"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// wait for the proxy to finish
+	deadline := time.Now().Add(5 * time.Second)
+	for proxy.Stats().ActiveConns > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	stats := proxy.Stats()
+	want := mux.ReverseProxyStats{Conns: 1, BytesIn: int64(len(payload)), BytesOut: int64(len(payload))}
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestReverseProxyIdleTimeout(t *testing.T) {
+	defer leakcheck.Check(t)
+	upstreamL := testListener(t)
+	defer upstreamL.Close()
+	// never replies
+	go serveUpstream(upstreamL, func(c net.Conn) { _, _ = io.Copy(io.Discard, c) })
+
+	l := testListener(t)
+	defer l.Close()
+	proxy := mux.NewReverseProxy(upstreamL.Addr().String())
+	proxy.IdleTimeout = 100 * time.Millisecond
+	var mu mux.ServeMux
+	mu.Handle(mux.Any(), proxy)
+	go serveMux(l, &mu)
+
+	c, err := net.Dial(l.Addr().Network(), l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// keeps active by writing only, in one direction
+	for i := 0; i < 5; i++ {
+		if _, err := io.WriteString(c, "tick"); err != nil {
+			t.Fatalf("#%d: expect active connection kept, got %s", i, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	start := time.Now()
+	_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := c.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expect EOF on idle, got %v", err)
+	}
+	if cost := time.Since(start); cost > time.Second {
+		t.Errorf("closed after %s, want about %s", cost, proxy.IdleTimeout)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mux
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// ServerSpeaksFirst matches connections on which the client sends nothing within timeout,
+// waiting for the server to speak first, as MySQL, SMTP, FTP and so on do.
+//
+// The read deadline of the connection is reset after matching, so ServerSpeaksFirst
+// is expected to be the last Matcher to try, and timeout to be shorter than the sniff timeout.
+// The Matcher never matches if the io.Writer passed in can not set a read deadline.
+func ServerSpeaksFirst(timeout time.Duration) MatcherFunc {
+	return func(w io.Writer, r io.Reader) bool {
+		c, ok := w.(interface{ SetReadDeadline(t time.Time) error })
+		if !ok {
+			return false
+		}
+		if err := c.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return false
+		}
+		defer c.SetReadDeadline(noTimeoutDeadline)
+
+		var b [1]byte
+		n, err := r.Read(b[:])
+		return n == 0 && errors.Is(err, os.ErrDeadlineExceeded)
+	}
+}
+
+// MySQL matches MySQL connections, in which the server sends the Initial Handshake Packet
+// before the client sends anything, so it's a ServerSpeaksFirst.
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_connection_phase.html
+func MySQL(timeout time.Duration) MatcherFunc {
+	return ServerSpeaksFirst(timeout)
+}
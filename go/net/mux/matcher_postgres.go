@@ -0,0 +1,45 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mux
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// https://www.postgresql.org/docs/current/protocol-message-formats.html
+const (
+	postgresProtocolVersion3 = 196608   // StartupMessage, protocol version 3.0
+	postgresCancelRequest    = 80877102 // CancelRequest
+	postgresSSLRequest       = 80877103 // SSLRequest
+	postgresGSSENCRequest    = 80877104 // GSSENCRequest
+
+	postgresMaxStartupPacketLength = 10000 // MAX_STARTUP_PACKET_LENGTH in postgres
+)
+
+// PostgreSQL matches PostgreSQL connections, by the first message sent by the client,
+// that's a StartupMessage, SSLRequest, GSSENCRequest or CancelRequest.
+//
+//	Int32 length of message contents in bytes, including self.
+//	Int32 protocol version number, or request code.
+func PostgreSQL() MatcherFunc {
+	return func(_ io.Writer, r io.Reader) bool {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return false
+		}
+		length := binary.BigEndian.Uint32(hdr[:4])
+		code := binary.BigEndian.Uint32(hdr[4:])
+		switch code {
+		case postgresSSLRequest, postgresGSSENCRequest:
+			return length == 8
+		case postgresCancelRequest:
+			return length == 16
+		case postgresProtocolVersion3:
+			return length > 8 && length <= postgresMaxStartupPacketLength
+		}
+		return false
+	}
+}
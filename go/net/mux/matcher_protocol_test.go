@@ -0,0 +1,191 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mux_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/net/mux"
+	"github.com/searKing/golang/go/testing/leakcheck"
+)
+
+func TestProtocolMatchers(t *testing.T) {
+	defer leakcheck.Check(t)
+	postgresStartup := func(code uint32, length uint32) string {
+		var b [8]byte
+		binary.BigEndian.PutUint32(b[:4], length)
+		binary.BigEndian.PutUint32(b[4:], code)
+		return string(b[:]) + strings.Repeat("\x00", int(length)-8)
+	}
+	testCases := []struct {
+		name    string
+		matcher mux.Matcher
+		payload string
+		expect  bool
+	}{
+		{"ssh", mux.SSH(), "SSH-2.0-OpenSSH_9.0\r\n", true},
+		{"ssh-http", mux.SSH(), "GET / HTTP/1.1\r\n", false},
+		{"postgres-startup", mux.PostgreSQL(), postgresStartup(196608, 24), true},
+		{"postgres-ssl", mux.PostgreSQL(), postgresStartup(80877103, 8), true},
+		{"postgres-ssl-bad-length", mux.PostgreSQL(), postgresStartup(80877103, 12), false},
+		{"postgres-http", mux.PostgreSQL(), "GET / HTTP/1.1\r\n", false},
+		{"redis", mux.Redis(), "*1\r\n$4\r\nPING\r\n", true},
+		{"redis-inline", mux.Redis(), "PING\r\n", false},
+		{"redis-no-length", mux.Redis(), "*\r\n$4\r\nPING\r\n", false},
+		{"proxy-v1", mux.ProxyProtocol(), "PROXY TCP4 1.2.3.4 5.6.7.8 1111 2222\r\n", true},
+		{"proxy-v2", mux.ProxyProtocol(), "\r\n\r\n\x00\r\nQUIT\n\x21\x11\x00\x0c", true},
+		{"proxy-http", mux.ProxyProtocol(), "GET / HTTP/1.1\r\n", false},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.matcher.Match(io.Discard, strings.NewReader(tt.payload)); got != tt.expect {
+				t.Errorf("Match(%q) = %t, want %t", tt.payload, got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestMySQL(t *testing.T) {
+	defer leakcheck.Check(t)
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	// client waits for the server greeting
+	if !mux.MySQL(10*time.Millisecond).Match(server, server) {
+		t.Errorf("expect silent client matched")
+	}
+
+	go func() { _, _ = io.WriteString(client, "GET / HTTP/1.1\r\n") }()
+	if mux.MySQL(time.Second).Match(server, server) {
+		t.Errorf("expect talking client not matched")
+	}
+	// deadline is reset after matching
+	if _, err := server.Read(make([]byte, 1)); err != nil {
+		t.Errorf("expect deadline reset, got %s", err)
+	}
+	if mux.MySQL(time.Second).Match(io.Discard, server) {
+		t.Errorf("expect not matched without deadline")
+	}
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	v2 := func(verCmd, fam byte, addrs []byte) string {
+		var b bytes.Buffer
+		b.WriteString("\r\n\r\n\x00\r\nQUIT\n")
+		b.WriteByte(verCmd)
+		b.WriteByte(fam)
+		_ = binary.Write(&b, binary.BigEndian, uint16(len(addrs)))
+		b.Write(addrs)
+		return b.String()
+	}
+	tcp4 := []byte{1, 2, 3, 4, 5, 6, 7, 8, 0x04, 0x57, 0x08, 0xae}
+	testCases := []struct {
+		name    string
+		header  string
+		wantErr bool
+		version int
+		cmd     mux.ProxyCommand
+		src     string
+		dst     string
+	}{
+		{name: "v1-tcp4", header: "PROXY TCP4 1.2.3.4 5.6.7.8 1111 2222\r\n",
+			version: 1, cmd: mux.ProxyCommandProxy, src: "1.2.3.4:1111", dst: "5.6.7.8:2222"},
+		{name: "v1-tcp6", header: "PROXY TCP6 ::1 ::2 1111 2222\r\n",
+			version: 1, cmd: mux.ProxyCommandProxy, src: "[::1]:1111", dst: "[::2]:2222"},
+		{name: "v1-unknown", header: "PROXY UNKNOWN\r\n", version: 1, cmd: mux.ProxyCommandProxy},
+		{name: "v1-family-mismatch", header: "PROXY TCP4 ::1 ::2 1111 2222\r\n", wantErr: true},
+		{name: "v1-no-crlf", header: "PROXY TCP4 1.2.3.4 5.6.7.8 1111 2222\n", wantErr: true},
+		{name: "v2-tcp4", header: v2(0x21, 0x11, tcp4),
+			version: 2, cmd: mux.ProxyCommandProxy, src: "1.2.3.4:1111", dst: "5.6.7.8:2222"},
+		{name: "v2-local", header: v2(0x20, 0x00, nil), version: 2, cmd: mux.ProxyCommandLocal},
+		{name: "v2-short", header: v2(0x21, 0x11, tcp4[:4]), wantErr: true},
+		{name: "v2-bad-version", header: v2(0x11, 0x11, tcp4), wantErr: true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.header + "payload"))
+			hdr, err := mux.ReadProxyHeader(r)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expect error, got %+v", hdr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hdr.Version != tt.version || hdr.Command != tt.cmd {
+				t.Errorf("got version %d command %d, want %d %d", hdr.Version, hdr.Command, tt.version, tt.cmd)
+			}
+			if got := addrString(hdr.SourceAddr); got != tt.src {
+				t.Errorf("SourceAddr = %q, want %q", got, tt.src)
+			}
+			if got := addrString(hdr.DestinationAddr); got != tt.dst {
+				t.Errorf("DestinationAddr = %q, want %q", got, tt.dst)
+			}
+			if rest, _ := io.ReadAll(r); string(rest) != "payload" {
+				t.Errorf("data after header = %q, want %q", rest, "payload")
+			}
+		})
+	}
+}
+
+func addrString(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
+
+func TestProxyProtocolHandler(t *testing.T) {
+	defer leakcheck.Check(t)
+	l := testListener(t)
+	defer l.Close()
+
+	type served struct {
+		remote string
+		data   string
+	}
+	servedCh := make(chan served, 1)
+	var mu mux.ServeMux
+	mu.Handle(mux.ProxyProtocol(), mux.ProxyProtocolHandler(mux.HandlerConnFunc(func(c net.Conn) {
+		defer c.Close()
+		data, _ := io.ReadAll(c)
+		servedCh <- served{remote: c.RemoteAddr().String(), data: string(data)}
+	})))
+
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		mu.Serve(c)
+	}()
+
+	c, err := net.Dial(l.Addr().Network(), l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.WriteString(c, "PROXY TCP4 1.2.3.4 5.6.7.8 1111 2222\r\nhello")
+	_ = c.(*net.TCPConn).CloseWrite()
+	defer c.Close()
+
+	select {
+	case s := <-servedCh:
+		if s.remote != "1.2.3.4:1111" || s.data != "hello" {
+			t.Errorf("served %+v, want remote 1.2.3.4:1111 and data hello", s)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mux
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// https://www.haproxy.org/download/2.8/doc/proxy-protocol.txt
+var (
+	proxyProtocolV1Prefix    = []byte("PROXY ")
+	proxyProtocolV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+const proxyProtocolV1MaxLength = 107 // including the CRLF
+
+// ErrProxyHeaderMalformed is returned by ReadProxyHeader if the PROXY protocol header is malformed.
+var ErrProxyHeaderMalformed = errors.New("net/mux: malformed PROXY protocol header")
+
+// ProxyCommand is the command of a PROXY protocol header.
+type ProxyCommand byte
+
+const (
+	// ProxyCommandLocal means the connection was established on purpose by the proxy
+	// without being relayed, as health checks, addresses carried are ignored.
+	ProxyCommandLocal ProxyCommand = 0x0
+	// ProxyCommandProxy means the connection was established on behalf of another node,
+	// and reflects the original connection endpoints.
+	ProxyCommandProxy ProxyCommand = 0x1
+)
+
+// ProxyHeader is the PROXY protocol header, version 1 or 2, sent by HAProxy and alike
+// before any data of the connection relayed.
+type ProxyHeader struct {
+	Version         int // 1 or 2
+	Command         ProxyCommand
+	SourceAddr      net.Addr // nil if unknown
+	DestinationAddr net.Addr // nil if unknown
+	TLVs            []byte   // Type-Length-Value vectors of version 2, raw
+}
+
+// ProxyProtocol matches connections relayed with a PROXY protocol header, version 1 or 2.
+// Serve connections matched by ProxyProtocolHandler to consume the header.
+func ProxyProtocol() MatcherFunc {
+	return AnyPrefixByteMatcher(proxyProtocolV1Prefix, proxyProtocolV2Signature)
+}
+
+// ReadProxyHeader reads a PROXY protocol header, version 1 or 2, from r.
+func ReadProxyHeader(r *bufio.Reader) (*ProxyHeader, error) {
+	sig, err := r.Peek(len(proxyProtocolV1Prefix))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(sig, proxyProtocolV1Prefix) {
+		return readProxyHeaderV1(r)
+	}
+	sig, err = r.Peek(len(proxyProtocolV2Signature))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(sig, proxyProtocolV2Signature) {
+		return readProxyHeaderV2(r)
+	}
+	return nil, ErrProxyHeaderMalformed
+}
+
+// PROXY TCP4 255.255.255.255 255.255.255.255 65535 65535\r\n
+// PROXY UNKNOWN\r\n
+func readProxyHeaderV1(r *bufio.Reader) (*ProxyHeader, error) {
+	var line []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+		if len(line) >= proxyProtocolV1MaxLength {
+			return nil, ErrProxyHeaderMalformed
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, ErrProxyHeaderMalformed
+	}
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	hdr := &ProxyHeader{Version: 1, Command: ProxyCommandProxy}
+	if len(fields) < 2 {
+		return nil, ErrProxyHeaderMalformed
+	}
+	switch fields[1] {
+	case "UNKNOWN":
+		// the receiver must ignore anything presented before the CRLF
+		return hdr, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, ErrProxyHeaderMalformed
+	}
+	if len(fields) != 6 {
+		return nil, ErrProxyHeaderMalformed
+	}
+	src, err := parseProxyHeaderV1Addr(fields[1], fields[2], fields[4])
+	if err != nil {
+		return nil, err
+	}
+	dst, err := parseProxyHeaderV1Addr(fields[1], fields[3], fields[5])
+	if err != nil {
+		return nil, err
+	}
+	hdr.SourceAddr, hdr.DestinationAddr = src, dst
+	return hdr, nil
+}
+
+func parseProxyHeaderV1Addr(proto, ip, port string) (*net.TCPAddr, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil || (proto == "TCP4") != (addr.To4() != nil) {
+		return nil, ErrProxyHeaderMalformed
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, ErrProxyHeaderMalformed
+	}
+	return &net.TCPAddr{IP: addr, Port: int(p)}, nil
+}
+
+// signature[12] + ver_cmd[1] + fam[1] + len[2] + addresses and TLVs[len]
+func readProxyHeaderV2(r *bufio.Reader) (*ProxyHeader, error) {
+	var head [16]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, err
+	}
+	verCmd, fam := head[12], head[13]
+	if verCmd>>4 != 0x2 {
+		return nil, ErrProxyHeaderMalformed
+	}
+	hdr := &ProxyHeader{Version: 2, Command: ProxyCommand(verCmd & 0xF)}
+	if hdr.Command != ProxyCommandLocal && hdr.Command != ProxyCommandProxy {
+		return nil, ErrProxyHeaderMalformed
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(head[14:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	var addrLen int
+	switch fam >> 4 {
+	case 0x0: // AF_UNSPEC
+	case 0x1: // AF_INET
+		addrLen = 2*net.IPv4len + 4
+	case 0x2: // AF_INET6
+		addrLen = 2*net.IPv6len + 4
+	case 0x3: // AF_UNIX
+		addrLen = 2 * 108
+	default:
+		return nil, ErrProxyHeaderMalformed
+	}
+	if len(payload) < addrLen {
+		return nil, ErrProxyHeaderMalformed
+	}
+	hdr.TLVs = payload[addrLen:]
+	if hdr.Command == ProxyCommandLocal || addrLen == 0 {
+		return hdr, nil
+	}
+
+	transport := fam & 0xF
+	switch fam >> 4 {
+	case 0x1, 0x2:
+		ipLen := (addrLen - 4) / 2
+		srcIP, dstIP := net.IP(payload[:ipLen]), net.IP(payload[ipLen:2*ipLen])
+		srcPort := int(binary.BigEndian.Uint16(payload[2*ipLen:]))
+		dstPort := int(binary.BigEndian.Uint16(payload[2*ipLen+2:]))
+		switch transport {
+		case 0x1: // STREAM
+			hdr.SourceAddr = &net.TCPAddr{IP: srcIP, Port: srcPort}
+			hdr.DestinationAddr = &net.TCPAddr{IP: dstIP, Port: dstPort}
+		case 0x2: // DGRAM
+			hdr.SourceAddr = &net.UDPAddr{IP: srcIP, Port: srcPort}
+			hdr.DestinationAddr = &net.UDPAddr{IP: dstIP, Port: dstPort}
+		default:
+			return nil, ErrProxyHeaderMalformed
+		}
+	case 0x3:
+		network := "unix"
+		switch transport {
+		case 0x1:
+		case 0x2:
+			network = "unixgram"
+		default:
+			return nil, ErrProxyHeaderMalformed
+		}
+		hdr.SourceAddr = &net.UnixAddr{Name: string(bytes.TrimRight(payload[:108], "\x00")), Net: network}
+		hdr.DestinationAddr = &net.UnixAddr{Name: string(bytes.TrimRight(payload[108:216], "\x00")), Net: network}
+	}
+	return hdr, nil
+}
+
+// ProxyConn is a net.Conn relayed with a PROXY protocol header,
+// RemoteAddr and LocalAddr are the addresses carried by the header, if any.
+type ProxyConn struct {
+	net.Conn
+	Header *ProxyHeader
+
+	r *bufio.Reader
+}
+
+// NewProxyConn reads the PROXY protocol header from c, and returns a ProxyConn reads data after the header.
+func NewProxyConn(c net.Conn) (*ProxyConn, error) {
+	r := bufio.NewReader(c)
+	hdr, err := ReadProxyHeader(r)
+	if err != nil {
+		return nil, fmt.Errorf("net/mux: read PROXY protocol header from %s: %w", c.RemoteAddr(), err)
+	}
+	return &ProxyConn{Conn: c, Header: hdr, r: r}, nil
+}
+
+func (c *ProxyConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// RemoteAddr returns the source address carried by the PROXY protocol header,
+// or the remote address of the connection if unknown.
+func (c *ProxyConn) RemoteAddr() net.Addr {
+	if c.Header.Command == ProxyCommandProxy && c.Header.SourceAddr != nil {
+		return c.Header.SourceAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the destination address carried by the PROXY protocol header,
+// or the local address of the connection if unknown.
+func (c *ProxyConn) LocalAddr() net.Addr {
+	if c.Header.Command == ProxyCommandProxy && c.Header.DestinationAddr != nil {
+		return c.Header.DestinationAddr
+	}
+	return c.Conn.LocalAddr()
+}
+
+// CloseWrite shuts down the writing side of the connection, if supported by the net.Conn wrapped.
+func (c *ProxyConn) CloseWrite() error {
+	return closeWrite(c.Conn)
+}
+
+// ProxyProtocolHandler returns a HandlerConn consumes the PROXY protocol header of connections,
+// and serves the ProxyConn by next. Connections with a malformed header are closed.
+func ProxyProtocolHandler(next HandlerConn) HandlerConn {
+	return HandlerConnFunc(func(c net.Conn) {
+		pc, err := NewProxyConn(c)
+		if err != nil {
+			_ = c.Close()
+			return
+		}
+		next.Serve(pc)
+	})
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mux
+
+import (
+	"bufio"
+	"io"
+)
+
+// Redis matches Redis connections, by the first command sent by the client in RESP,
+// that's an Array of Bulk Strings, as "*1\r\n$4\r\nPING\r\n".
+// Inline commands are not matched.
+// https://redis.io/docs/reference/protocol-spec/
+func Redis() MatcherFunc {
+	return func(_ io.Writer, r io.Reader) bool {
+		br := bufio.NewReaderSize(r, 64)
+		// *<number-of-elements>\r\n
+		if !readRESPLength(br, '*') {
+			return false
+		}
+		// $<length>\r\n
+		return readRESPLength(br, '$')
+	}
+}
+
+// readRESPLength reads a line as "<prefix><digits>\r\n".
+func readRESPLength(r *bufio.Reader, prefix byte) bool {
+	const maxDigits = 10
+	if c, err := r.ReadByte(); err != nil || c != prefix {
+		return false
+	}
+	for i := 0; ; i++ {
+		c, err := r.ReadByte()
+		if err != nil {
+			return false
+		}
+		switch {
+		case c >= '0' && c <= '9' && i < maxDigits:
+			continue
+		case c == '\r' && i > 0:
+			c, err = r.ReadByte()
+			return err == nil && c == '\n'
+		}
+		return false
+	}
+}
@@ -0,0 +1,13 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mux
+
+// SSH matches SSH connections, by the protocol version exchange sent by the client.
+// https://www.rfc-editor.org/rfc/rfc4253#section-4.2
+//
+//	SSH-protoversion-softwareversion SP comments CR LF
+func SSH() MatcherFunc {
+	return AnyPrefixMatcher("SSH-")
+}
@@ -19,6 +19,7 @@ import (
 // HandlerShake of TLS
 // type byte	// recordTypeHandshake
 // versions [2]byte
+// To route by the server name or the application protocol, see TLSServerName and TLSALPN.
 func TLS(versions ...int) MatcherFunc {
 	const recordTypeHandshake = 22
 	if len(versions) == 0 {
@@ -0,0 +1,108 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mux
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+var errClientHelloRead = errors.New("net/mux: tls ClientHello read")
+
+// TLSClientHello matches TLS connections by the ClientHello sent by the client,
+// the handshake is parsed only, not terminated, so connections matched can be passed through,
+// or served by a tls.Server as the bytes sniffed are replayed.
+//
+// ClientHelloInfo.Conn is nil, as the ClientHello is parsed from the bytes sniffed.
+func TLSClientHello(match func(hello *tls.ClientHelloInfo) bool) MatcherFunc {
+	return func(_ io.Writer, r io.Reader) bool {
+		hello := ReadClientHello(r)
+		if hello == nil {
+			return false
+		}
+		return match(hello)
+	}
+}
+
+// TLSServerName matches TLS connections by the SNI of the ClientHello,
+// a name is matched exactly, case-insensitively, or by a wildcard in the leftmost label,
+// as "*.example.com" matches "foo.example.com" but neither "example.com" nor "bar.foo.example.com".
+// Connections without SNI are matched by "".
+func TLSServerName(names ...string) MatcherFunc {
+	return TLSClientHello(func(hello *tls.ClientHelloInfo) bool {
+		for _, name := range names {
+			if matchServerName(name, hello.ServerName) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// TLSALPN matches TLS connections offering any of protos by ALPN in the ClientHello, as "h2", "http/1.1".
+func TLSALPN(protos ...string) MatcherFunc {
+	return TLSClientHello(func(hello *tls.ClientHelloInfo) bool {
+		for _, offered := range hello.SupportedProtos {
+			for _, proto := range protos {
+				if offered == proto {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+// ReadClientHello reads and parses a TLS ClientHello from r, nil is returned if r is not started with a ClientHello.
+func ReadClientHello(r io.Reader) *tls.ClientHelloInfo {
+	var hello *tls.ClientHelloInfo
+	err := tls.Server(clientHelloConn{r: r}, &tls.Config{
+		GetConfigForClient: func(info *tls.ClientHelloInfo) (*tls.Config, error) {
+			h := *info
+			h.Conn = nil
+			hello = &h
+			// abort the handshake, as nothing is going to be replied
+			return nil, errClientHelloRead
+		},
+	}).Handshake()
+	if !errors.Is(err, errClientHelloRead) {
+		return nil
+	}
+	return hello
+}
+
+// matchServerName reports whether serverName is matched by pattern, exactly or by a wildcard.
+func matchServerName(pattern, serverName string) bool {
+	pattern = strings.TrimSuffix(pattern, ".")
+	serverName = strings.TrimSuffix(serverName, ".")
+	if strings.EqualFold(pattern, serverName) {
+		return true
+	}
+	suffix, ok := strings.CutPrefix(pattern, "*.")
+	if !ok || suffix == "" {
+		return false
+	}
+	label, rest, ok := strings.Cut(serverName, ".")
+	return ok && label != "" && strings.EqualFold(rest, suffix)
+}
+
+// clientHelloConn is a read-only net.Conn, to feed the ClientHello to a tls.Server,
+// anything written, as alerts, is discarded.
+type clientHelloConn struct {
+	r io.Reader
+}
+
+func (c clientHelloConn) Read(p []byte) (int, error)         { return c.r.Read(p) }
+func (c clientHelloConn) Write(p []byte) (int, error)        { return 0, io.ErrClosedPipe }
+func (c clientHelloConn) Close() error                       { return nil }
+func (c clientHelloConn) LocalAddr() net.Addr                { return nil }
+func (c clientHelloConn) RemoteAddr() net.Addr               { return nil }
+func (c clientHelloConn) SetDeadline(t time.Time) error      { return nil }
+func (c clientHelloConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c clientHelloConn) SetWriteDeadline(t time.Time) error { return nil }
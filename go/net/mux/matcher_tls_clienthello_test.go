@@ -0,0 +1,126 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mux_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	tls_ "github.com/searKing/golang/go/crypto/tls"
+	"github.com/searKing/golang/go/net/mux"
+	"github.com/searKing/golang/go/testing/leakcheck"
+)
+
+// clientHello returns the ClientHello sent by a tls.Client with config.
+func clientHello(t *testing.T, config *tls.Config) string {
+	server, client := net.Pipe()
+	defer server.Close()
+	go func() {
+		defer client.Close()
+		_ = tls.Client(client, config).Handshake()
+	}()
+	_ = server.SetReadDeadline(time.Now().Add(time.Second))
+	var b strings.Builder
+	hello := mux.ReadClientHello(io.TeeReader(server, &b))
+	if hello == nil {
+		t.Fatal("expect ClientHello read")
+	}
+	return b.String()
+}
+
+func TestTLSClientHello(t *testing.T) {
+	defer leakcheck.Check(t)
+	fooHello := clientHello(t, &tls.Config{ServerName: "foo.example.com", NextProtos: []string{"h2", "http/1.1"}})
+	barHello := clientHello(t, &tls.Config{ServerName: "example.com", NextProtos: []string{"http/1.1"}})
+
+	testCases := []struct {
+		name    string
+		matcher mux.Matcher
+		payload string
+		expect  bool
+	}{
+		{"sni-exact", mux.TLSServerName("foo.example.com"), fooHello, true},
+		{"sni-exact-case", mux.TLSServerName("FOO.Example.com"), fooHello, true},
+		{"sni-exact-mismatch", mux.TLSServerName("foo.example.com"), barHello, false},
+		{"sni-wildcard", mux.TLSServerName("*.example.com"), fooHello, true},
+		{"sni-wildcard-apex", mux.TLSServerName("*.example.com"), barHello, false},
+		{"sni-wildcard-nested", mux.TLSServerName("*.com"), fooHello, false},
+		{"sni-any-of", mux.TLSServerName("other.org", "example.com"), barHello, true},
+		{"alpn", mux.TLSALPN("h2"), fooHello, true},
+		{"alpn-mismatch", mux.TLSALPN("h2"), barHello, false},
+		{"not-tls", mux.TLSServerName("foo.example.com"), "GET / HTTP/1.1\r\n\r\n", false},
+		{"truncated", mux.TLSServerName("foo.example.com"), fooHello[:len(fooHello)/2], false},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.matcher.Match(io.Discard, strings.NewReader(tt.payload)); got != tt.expect {
+				t.Errorf("Match() = %t, want %t", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestTLSServerNamePassThrough(t *testing.T) {
+	defer leakcheck.Check(t)
+	l := testListener(t)
+	defer l.Close()
+
+	newTLSConfig := func(cn string) *tls.Config {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := tls_.CreateSelfSignedTLSCertificate(key, []string{"searKing"}, cn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &tls.Config{Certificates: []tls.Certificate{*cert}}
+	}
+	// each backend terminates TLS with its own certificate
+	backend := func(name string) mux.HandlerConn {
+		config := newTLSConfig(name)
+		return mux.HandlerConnFunc(func(c net.Conn) {
+			tc := tls.Server(c, config)
+			defer tc.Close()
+			_, _ = io.WriteString(tc, name)
+		})
+	}
+
+	var mu mux.ServeMux
+	mu.Handle(mux.TLSServerName("foo.example.com"), backend("foo"))
+	mu.Handle(mux.TLSServerName("*.example.org"), backend("bar"))
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go mu.Serve(c)
+		}
+	}()
+
+	for serverName, want := range map[string]string{"foo.example.com": "foo", "bar.example.org": "bar"} {
+		c, err := tls.Dial(l.Addr().Network(), l.Addr().String(),
+			&tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(c)
+		_ = c.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s served by %q, want %q", serverName, got, want)
+		}
+	}
+}
@@ -5,8 +5,10 @@
 package mux
 
 import (
+	"errors"
 	"io"
 	"net"
+	"os"
 
 	io_ "github.com/searKing/golang/go/io"
 )
@@ -15,6 +17,12 @@ import (
 type sniffConn struct {
 	net.Conn
 	sniffer io_.ReadSniffer
+
+	// stats of sniffing, reported to Observer
+	sniffing      bool
+	sniffRead     int  // bytes read by the matcher sniffing
+	sniffedBytes  int  // max bytes read by matchers
+	sniffTimedOut bool // read deadline exceeded while sniffing
 }
 
 func newMuxConn(c net.Conn) *sniffConn {
@@ -35,14 +43,36 @@ func newMuxConn(c net.Conn) *sniffConn {
 // return either err == EOF or err == nil.  The next Read should
 // return 0, EOF.
 func (m *sniffConn) Read(p []byte) (int, error) {
-	return m.sniffer.Read(p)
+	n, err := m.sniffer.Read(p)
+	if m.sniffing {
+		m.sniffRead += n
+		if m.sniffRead > m.sniffedBytes {
+			m.sniffedBytes = m.sniffRead
+		}
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			m.sniffTimedOut = true
+		}
+	}
+	return n, err
 }
 
 func (m *sniffConn) startSniffing() io.Reader {
 	m.sniffer.Sniff(true)
+	m.sniffing = true
+	m.sniffRead = 0
 	return m.sniffer
 }
 
 func (m *sniffConn) doneSniffing() {
 	m.sniffer.Sniff(false)
+	m.sniffing = false
+}
+
+func (m *sniffConn) resetSniffStats() {
+	m.sniffRead, m.sniffedBytes, m.sniffTimedOut = 0, 0, false
+}
+
+// CloseWrite shuts down the writing side of the connection, if supported by the net.Conn wrapped.
+func (m *sniffConn) CloseWrite() error {
+	return closeWrite(m.Conn)
 }
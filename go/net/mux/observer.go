@@ -0,0 +1,48 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mux
+
+import (
+	"net"
+	"strconv"
+	"time"
+)
+
+// Observer observes connections served by a Server, and sniffed by a ServeMux,
+// so that metrics, as which protocol a port is actually serving, can be reported.
+// Methods are called synchronously while serving, so they are expected to return quickly.
+type Observer interface {
+	// ConnStateChanged is called when a connection served by a Server changes state,
+	// from is ConnStateNew, as to, for a new connection.
+	// ConnStateHijacked and ConnStateClosed are terminal states.
+	ConnStateChanged(c net.Conn, from, to ConnState)
+
+	// Sniffed is called after a connection is sniffed by a ServeMux.
+	// route is the name of the route matched, see Named, "" if no route matched.
+	// sniffed is the number of bytes read by matchers before a route matched or all failed,
+	// cost is the time spent in sniffing, and timeout reports whether no route matched
+	// as the read timeout of sniffing exceeded.
+	Sniffed(c net.Conn, route string, sniffed int, cost time.Duration, timeout bool)
+}
+
+// NamedMatcher is a Matcher with a route name, reported to Observer.
+type NamedMatcher struct {
+	Name string
+	Matcher
+}
+
+// Named returns a Matcher with a route name, reported to Observer.
+// Routes not named are reported by the index registered in ServeMux, as "0", "1".
+func Named(name string, pattern Matcher) NamedMatcher {
+	return NamedMatcher{Name: name, Matcher: pattern}
+}
+
+// routeName returns the name of the route registered i-th with pattern.
+func routeName(i int, pattern Matcher) string {
+	if n, ok := pattern.(NamedMatcher); ok && n.Name != "" {
+		return n.Name
+	}
+	return strconv.Itoa(i)
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mux_test
+
+import (
+	"io"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/net/mux"
+	"github.com/searKing/golang/go/testing/leakcheck"
+)
+
+type sniffed struct {
+	route   string
+	sniffed int
+	timeout bool
+}
+
+type recordObserver struct {
+	mu      sync.Mutex
+	states  []string
+	sniffed []sniffed
+}
+
+func (o *recordObserver) ConnStateChanged(c net.Conn, from, to mux.ConnState) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.states = append(o.states, from.String()+"->"+to.String())
+}
+
+func (o *recordObserver) Sniffed(c net.Conn, route string, n int, cost time.Duration, timeout bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.sniffed = append(o.sniffed, sniffed{route: route, sniffed: n, timeout: timeout})
+}
+
+func (o *recordObserver) records() ([]string, []sniffed) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]string(nil), o.states...), append([]sniffed(nil), o.sniffed...)
+}
+
+func TestObserver(t *testing.T) {
+	defer leakcheck.Check(t)
+	errCh := make(chan error, 1)
+	defer func() {
+		select {
+		case err := <-errCh:
+			t.Fatal(err)
+		default:
+		}
+	}()
+
+	var observer recordObserver
+	served := make(chan struct{}, 2)
+	mu := mux.NewServeMux()
+	mu.Observer = &observer
+	mu.SetReadTimeout(100 * time.Millisecond)
+	mu.NotFoundHandler = mux.HandlerConnFunc(func(net.Conn) { served <- struct{}{} })
+	mu.Handle(mux.Named("ssh", mux.SSH()), mux.HandlerConnFunc(func(net.Conn) { served <- struct{}{} }))
+	mu.Handle(mux.HTTP1Fast(), mux.HandlerConnFunc(func(net.Conn) { served <- struct{}{} }))
+
+	srv := mux.NewServer()
+	srv.Handler = mu
+	srv.Observer = &observer
+	defer srv.Close()
+
+	l := testListener(t)
+	go safeServe(errCh, srv, l)
+
+	for _, payload := range []string{"SSH-2.0-OpenSSH\r\n", "GET / HTTP/1.1\r\n", "ab"} {
+		c, err := net.Dial(l.Addr().Network(), l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.WriteString(c, payload)
+		select {
+		case <-served:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%q not served", payload)
+		}
+		_ = c.Close()
+	}
+
+	states, sniffs := observer.records()
+	wantSniffs := []sniffed{
+		{route: "ssh", sniffed: 4},
+		{route: "1", sniffed: 7}, // len("OPTIONS")
+		{route: "", sniffed: 2, timeout: true},
+	}
+	if !reflect.DeepEqual(sniffs, wantSniffs) {
+		t.Errorf("sniffed %+v, want %+v", sniffs, wantSniffs)
+	}
+	if len(states) < 3 || states[0] != "New->New" || states[1] != "New->Active" || states[2] != "Active->Hijacked" {
+		t.Errorf("states %v, want New->New, New->Active, Active->Hijacked", states)
+	}
+}
@@ -51,6 +51,9 @@ type Server struct {
 	// called when a client connection changes state. See the
 	// ConnStateHook type and associated constants for details.
 	ConnStateHook func(net.Conn, ConnState)
+	// Observer specifies an optional observer reported when a client connection changes state.
+	// To observe connections sniffed, set Observer of the ServeMux too.
+	Observer Observer
 	// ErrorLog specifies an optional logger for errors accepting
 	// connections, unexpected behavior from handlers, and
 	// underlying FileSystem errors.
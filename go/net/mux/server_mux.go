@@ -29,7 +29,9 @@ func NotFoundHandler() HandlerConn { return HandlerConnFunc(NotFound) }
 type ServeMux struct {
 	// NotFound replies to the listener with a not found error.
 	NotFoundHandler HandlerConn
-	sniffTimeout    time.Duration
+	// Observer specifies an optional observer reported on every connection sniffed.
+	Observer     Observer
+	sniffTimeout time.Duration
 
 	mu sync.RWMutex
 	m  []muxEntry
@@ -40,6 +42,7 @@ type muxEntry struct {
 	l *net_.NotifyListener
 
 	pattern Matcher
+	name    string // route name reported to Observer
 }
 
 func (e muxEntry) Serve(c net.Conn) {
@@ -105,7 +108,7 @@ func (mux *ServeMux) HandleListener(pattern Matcher) net.Listener {
 		panic("listener: invalid pattern")
 	}
 
-	e := muxEntry{l: net_.NewNotifyListener(), pattern: pattern}
+	e := muxEntry{l: net_.NewNotifyListener(), pattern: pattern, name: routeName(len(mux.m), pattern)}
 	mux.m = append(mux.m, e)
 	return e.l
 }
@@ -121,7 +124,7 @@ func (mux *ServeMux) Handle(pattern Matcher, handler HandlerConn) {
 		panic("listener: nil handler")
 	}
 
-	e := muxEntry{h: handler, pattern: pattern}
+	e := muxEntry{h: handler, pattern: pattern, name: routeName(len(mux.m), pattern)}
 	mux.m = append(mux.m, e)
 	return
 }
@@ -135,17 +138,16 @@ func (mux *ServeMux) HandleFunc(pattern Matcher, handler func(net.Conn)) {
 }
 
 // Find a handler on a handler map.
-func (mux *ServeMux) match(c *sniffConn) (h HandlerConn) {
+func (mux *ServeMux) match(c *sniffConn) (h HandlerConn, route string) {
 	for _, e := range mux.m {
 		c.startSniffing()
 		if e.pattern.Match(c, c) {
 			c.doneSniffing()
-			return e
+			return e, e.name
 		}
 		c.doneSniffing()
 	}
-	return nil
-
+	return nil, ""
 }
 
 // handler is the main implementation of HandlerConn.
@@ -153,11 +155,16 @@ func (mux *ServeMux) Handler(c *sniffConn) (h HandlerConn) {
 	mux.mu.RLock()
 	defer mux.mu.RUnlock()
 
+	start := time.Now()
+	c.resetSniffStats()
 	// set sniff timeout
 	if mux.sniffTimeout > noTimeout {
 		_ = c.SetReadDeadline(time.Now().Add(mux.sniffTimeout))
 	}
-	h = mux.match(c)
+	h, route := mux.match(c)
+	if mux.Observer != nil {
+		mux.Observer.Sniffed(c, route, c.sniffedBytes, time.Since(start), h == nil && c.sniffTimedOut)
+	}
 
 	// unset sniff timeout
 	if mux.sniffTimeout > noTimeout {
@@ -182,7 +189,8 @@ func (mux *ServeMux) Serve(c net.Conn) {
 	}
 
 	h := mux.Handler(muxC)
-	h.Serve(c)
+	// serve with muxC, as bytes sniffed are buffered in muxC
+	h.Serve(muxC)
 }
 
 func (mux *ServeMux) Close() error {
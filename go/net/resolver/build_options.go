@@ -41,3 +41,13 @@ func WithBuildClientConn(v ClientConn) BuildOption {
 		o.ClientConn = v
 	})
 }
+
+// WithBuildNewPicker sets NewPicker in Build.
+// NewPicker returns the Picker used by ResolveOneAddr of the resolver built, one per resolver,
+// ResolveDone is passed to the Picker if it's a DonePicker.
+// The resolver picks randomly if NewPicker is nil.
+func WithBuildNewPicker(v PickerBuilder) BuildOption {
+	return BuildOptionFunc(func(o *Build) {
+		o.NewPicker = v
+	})
+}
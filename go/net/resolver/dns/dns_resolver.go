@@ -13,7 +13,6 @@ import (
 	"sync"
 	"time"
 
-	rand_ "github.com/searKing/golang/go/math/rand"
 	"github.com/searKing/golang/go/net/resolver"
 	time_ "github.com/searKing/golang/go/time"
 )
@@ -73,16 +72,28 @@ var customAuthorityResolver = func(authority string) (netResolver, error) {
 }
 
 // NewBuilder creates a dnsBuilder which is used to factory DNS resolvers.
-func NewBuilder() resolver.Builder {
-	return &dnsBuilder{}
+func NewBuilder(opts ...resolver.BuildOption) resolver.Builder {
+	return NewBuilderWithScheme("dns", opts...)
 }
 
-type dnsBuilder struct{}
+// NewBuilderWithScheme creates a dnsBuilder for scheme, which is used to factory DNS resolvers.
+func NewBuilderWithScheme(scheme string, opts ...resolver.BuildOption) resolver.Builder {
+	return &dnsBuilder{scheme: scheme, opts: opts}
+}
+
+type dnsBuilder struct {
+	scheme string
+	opts   []resolver.BuildOption
+}
 
 // Build creates and starts a DNS resolver that watches the name resolution of the target.
 func (b *dnsBuilder) Build(ctx context.Context, target resolver.Target, opts ...resolver.BuildOption) (resolver.Resolver, error) {
 	var opt resolver.Build
-	opt.ApplyOptions(opts...)
+	opt.ApplyOptions(b.opts...).ApplyOptions(opts...)
+	var picker resolver.Picker
+	if opt.NewPicker != nil {
+		picker = opt.NewPicker()
+	}
 	host, port, err := parseTarget(target.Endpoint, defaultPort)
 	if err != nil {
 		return nil, err
@@ -96,7 +107,8 @@ func (b *dnsBuilder) Build(ctx context.Context, target resolver.Target, opts ...
 			_ = cc.UpdateState(resolver.State{Addresses: addr})
 		}
 		return deadResolver{
-			addrs: addr,
+			picker: picker,
+			addrs:  addr,
 		}, nil
 	}
 
@@ -108,6 +120,7 @@ func (b *dnsBuilder) Build(ctx context.Context, target resolver.Target, opts ...
 		ctx:    ctx,
 		cancel: cancel,
 		cc:     cc,
+		picker: picker,
 		rn:     make(chan struct{}, 1),
 	}
 
@@ -125,9 +138,9 @@ func (b *dnsBuilder) Build(ctx context.Context, target resolver.Target, opts ...
 	return d, nil
 }
 
-// Scheme returns the naming scheme of this resolver builder, which is "dns".
+// Scheme returns the naming scheme of this resolver builder, which is "dns" by default.
 func (b *dnsBuilder) Scheme() string {
-	return "dns"
+	return b.scheme
 }
 
 type netResolver interface {
@@ -143,16 +156,19 @@ type deadResolver struct {
 }
 
 func (d deadResolver) ResolveOneAddr(ctx context.Context, opts ...resolver.ResolveOneAddrOption) (resolver.Address, error) {
-	if len(d.addrs) == 0 {
-		return resolver.Address{}, fmt.Errorf("resolve target, but no addr")
-	}
-	return d.addrs[rand_.Intn(len(d.addrs))], nil
+	return resolver.PickOneAddr(ctx, d.picker, d.addrs, opts...)
 }
 func (d deadResolver) ResolveAddr(ctx context.Context, opts ...resolver.ResolveAddrOption) ([]resolver.Address, error) {
 	return d.addrs, nil
 }
 func (deadResolver) ResolveNow(ctx context.Context, opts ...resolver.ResolveNowOption) {}
 
+func (d deadResolver) ResolveDone(ctx context.Context, doneInfo resolver.DoneInfo, opts ...resolver.ResolveDoneOption) {
+	if p, ok := d.picker.(resolver.DonePicker); ok {
+		p.Done(ctx, doneInfo)
+	}
+}
+
 func (deadResolver) Close() {}
 
 // dnsResolver watches for the name resolution update for a non-IP target.
@@ -163,6 +179,7 @@ type dnsResolver struct {
 	ctx      context.Context
 	cancel   context.CancelFunc
 	cc       resolver.ClientConn
+	picker   resolver.Picker
 	// rn channel is used by ResolveNow() to force an immediate resolution of the target.
 	rn chan struct{}
 	// wg is used to enforce Close() to return after the watcher() goroutine has finished.
@@ -180,10 +197,7 @@ func (d *dnsResolver) ResolveOneAddr(ctx context.Context, opts ...resolver.Resol
 	if err != nil {
 		return resolver.Address{}, err
 	}
-	if len(addrs) == 0 {
-		return resolver.Address{}, fmt.Errorf("resolve target, but no addr")
-	}
-	return addrs[rand_.Intn(len(addrs))], nil
+	return resolver.PickOneAddr(ctx, d.picker, addrs, opts...)
 }
 
 func (d *dnsResolver) ResolveAddr(ctx context.Context, opts ...resolver.ResolveAddrOption) ([]resolver.Address, error) {
@@ -199,6 +213,12 @@ func (d *dnsResolver) ResolveNow(ctx context.Context, opts ...resolver.ResolveNo
 	}
 }
 
+func (d *dnsResolver) ResolveDone(ctx context.Context, doneInfo resolver.DoneInfo, opts ...resolver.ResolveDoneOption) {
+	if p, ok := d.picker.(resolver.DonePicker); ok {
+		p.Done(ctx, doneInfo)
+	}
+}
+
 // Close closes the dnsResolver.
 func (d *dnsResolver) Close() {
 	d.cancel()
@@ -0,0 +1,286 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package health implements a wrapper of resolver.Resolver, which probes every Address actively,
+// and ejects addresses failed consecutively, or with a high error rate reported through ResolveDone,
+// as outlier detection, until reinstated after a backoff.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/searKing/golang/go/net/resolver"
+	time_ "github.com/searKing/golang/go/time"
+)
+
+const (
+	// DefaultInterval is the interval between probes, if not set.
+	DefaultInterval = 10 * time.Second
+	// DefaultTimeout is the timeout of a probe, if not set.
+	DefaultTimeout = time.Second
+	// DefaultMaxConsecutiveFailures is the number of consecutive failures to eject an address, if not set.
+	DefaultMaxConsecutiveFailures = 3
+	// DefaultErrorRateWindow is the window to count requests reported through ResolveDone, if not set.
+	DefaultErrorRateWindow = 30 * time.Second
+	// DefaultErrorRateMinRequests is the minimum number of requests in a window to tell the error rate, if not set.
+	DefaultErrorRateMinRequests = 10
+)
+
+// Resolver wraps a resolver.Resolver, addresses ejected are filtered out from ResolveOneAddr and ResolveAddr.
+// If all addresses are ejected, none is filtered out, as the ejection is never certain.
+type Resolver struct {
+	resolver resolver.Resolver
+
+	prober                 Prober
+	picker                 resolver.Picker
+	interval               time.Duration
+	timeout                time.Duration
+	maxConsecutiveFailures int
+	errorRateThreshold     float64 // error rate in (0, 1] to eject, disabled if 0
+	errorRateWindow        time.Duration
+	errorRateMinRequests   int
+	newBackOff             func() time_.BackOff
+
+	mu    sync.Mutex
+	hosts map[string]*host // keyed by Addr
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// host is the health of an address.
+type host struct {
+	failures     int       // consecutive failures
+	ejectedUntil time.Time // ejected if not zero
+	backoff      time_.BackOff
+
+	windowStart time.Time // window to count requests
+	requests    int
+	errors      int
+}
+
+// NewResolver returns a Resolver wrapping r, probing actively if a Prober is set by WithProber.
+func NewResolver(r resolver.Resolver, opts ...Option) *Resolver {
+	hr := &Resolver{
+		resolver:               r,
+		interval:               DefaultInterval,
+		timeout:                DefaultTimeout,
+		maxConsecutiveFailures: DefaultMaxConsecutiveFailures,
+		errorRateWindow:        DefaultErrorRateWindow,
+		errorRateMinRequests:   DefaultErrorRateMinRequests,
+		newBackOff: func() time_.BackOff {
+			return time_.NewGrpcExponentialBackOff()
+		},
+		hosts: make(map[string]*host),
+	}
+	hr.ApplyOptions(opts...)
+	hr.ctx, hr.cancel = context.WithCancel(context.Background())
+	if hr.prober != nil {
+		hr.wg.Add(1)
+		go hr.probeLoop()
+	}
+	return hr
+}
+
+// ApplyOptions call apply() for all options one by one
+func (r *Resolver) ApplyOptions(options ...Option) *Resolver {
+	for _, opt := range options {
+		if opt == nil {
+			continue
+		}
+		opt.apply(r)
+	}
+	return r
+}
+
+// ResolveOneAddr picks one of addresses not ejected, by the Picker set, or randomly.
+func (r *Resolver) ResolveOneAddr(ctx context.Context, opts ...resolver.ResolveOneAddrOption) (resolver.Address, error) {
+	addrs, err := r.ResolveAddr(ctx)
+	if err != nil {
+		return resolver.Address{}, err
+	}
+	return resolver.PickOneAddr(ctx, r.picker, addrs, opts...)
+}
+
+// ResolveAddr returns addresses resolved, and not ejected.
+func (r *Resolver) ResolveAddr(ctx context.Context, opts ...resolver.ResolveAddrOption) ([]resolver.Address, error) {
+	addrs, err := r.resolver.ResolveAddr(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return r.healthy(addrs), nil
+}
+
+// ResolveNow calls ResolveNow of the Resolver wrapped.
+func (r *Resolver) ResolveNow(ctx context.Context, opts ...resolver.ResolveNowOption) {
+	r.resolver.ResolveNow(ctx, opts...)
+}
+
+// ResolveDone counts requests and errors of the address for outlier detection,
+// and calls ResolveDone of the Picker and the Resolver wrapped, if any.
+func (r *Resolver) ResolveDone(ctx context.Context, doneInfo resolver.DoneInfo, opts ...resolver.ResolveDoneOption) {
+	r.report(doneInfo.Addr.Addr, doneInfo.Err, true)
+	if p, ok := r.picker.(resolver.DonePicker); ok {
+		p.Done(ctx, doneInfo)
+	}
+	if dr, ok := r.resolver.(resolver.ResolveDoneResolver); ok {
+		dr.ResolveDone(ctx, doneInfo, opts...)
+	}
+}
+
+// Close stops probing, and closes the Resolver wrapped.
+func (r *Resolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+	r.resolver.Close()
+}
+
+// Ejected reports whether addr is ejected now.
+func (r *Resolver) Ejected(addr string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.hosts[addr]
+	return ok && r.ejectedLocked(h, time.Now())
+}
+
+func (r *Resolver) healthy(addrs []resolver.Address) []resolver.Address {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	healthy := make([]resolver.Address, 0, len(addrs))
+	for _, addr := range addrs {
+		if h, ok := r.hosts[addr.Addr]; ok && r.ejectedLocked(h, now) {
+			continue
+		}
+		healthy = append(healthy, addr)
+	}
+	if len(healthy) == 0 {
+		// panic mode, all are ejected
+		return addrs
+	}
+	return healthy
+}
+
+// ejectedLocked reports whether h is ejected at now, reinstating it if ejected long enough.
+func (r *Resolver) ejectedLocked(h *host, now time.Time) bool {
+	if h.ejectedUntil.IsZero() {
+		return false
+	}
+	if now.Before(h.ejectedUntil) {
+		return true
+	}
+	// reinstated, failures are counted again
+	h.ejectedUntil = time.Time{}
+	h.failures = 0
+	h.windowStart, h.requests, h.errors = time.Time{}, 0, 0
+	return false
+}
+
+// report counts a result of a probe, or a request if passive, of addr.
+func (r *Resolver) report(addr string, err error, passive bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	h, ok := r.hosts[addr]
+	if !ok {
+		h = &host{}
+		r.hosts[addr] = h
+	}
+	if r.ejectedLocked(h, now) {
+		return
+	}
+
+	if err == nil {
+		h.failures = 0
+	} else {
+		h.failures++
+	}
+	if r.maxConsecutiveFailures > 0 && h.failures >= r.maxConsecutiveFailures {
+		r.ejectLocked(h, now)
+		return
+	}
+	if !passive || r.errorRateThreshold <= 0 {
+		// a probe succeeded, ejections before are forgiven
+		if err == nil && !passive && h.backoff != nil {
+			h.backoff.Reset()
+		}
+		return
+	}
+
+	if h.windowStart.IsZero() || now.Sub(h.windowStart) > r.errorRateWindow {
+		h.windowStart, h.requests, h.errors = now, 0, 0
+	}
+	h.requests++
+	if err != nil {
+		h.errors++
+	}
+	if h.requests >= r.errorRateMinRequests && float64(h.errors)/float64(h.requests) >= r.errorRateThreshold {
+		r.ejectLocked(h, now)
+	}
+}
+
+func (r *Resolver) ejectLocked(h *host, now time.Time) {
+	if h.backoff == nil {
+		h.backoff = r.newBackOff()
+	}
+	d, _ := h.backoff.NextBackOff()
+	h.ejectedUntil = now.Add(d)
+}
+
+func (r *Resolver) probeLoop() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.probeAll()
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probeAll probes all addresses resolved concurrently, and forgets addresses not resolved any more.
+func (r *Resolver) probeAll() {
+	addrs, err := r.resolver.ResolveAddr(r.ctx)
+	if err != nil {
+		return
+	}
+	r.forget(addrs)
+
+	var wg sync.WaitGroup
+	for _, addr := range addrs {
+		addr := addr
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+			defer cancel()
+			err := r.prober.Probe(ctx, addr)
+			if r.ctx.Err() != nil {
+				return
+			}
+			r.report(addr.Addr, err, false)
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *Resolver) forget(addrs []resolver.Address) {
+	alive := make(map[string]struct{}, len(addrs))
+	for _, addr := range addrs {
+		alive[addr.Addr] = struct{}{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for addr := range r.hosts {
+		if _, ok := alive[addr]; !ok {
+			delete(r.hosts, addr)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package health
+
+import (
+	"time"
+
+	"github.com/searKing/golang/go/net/resolver"
+	time_ "github.com/searKing/golang/go/time"
+)
+
+// An Option sets options.
+type Option interface {
+	apply(*Resolver)
+}
+
+// OptionFunc wraps a function that modifies Resolver into an
+// implementation of the Option interface.
+type OptionFunc func(*Resolver)
+
+func (f OptionFunc) apply(do *Resolver) {
+	f(do)
+}
+
+// WithProber probes every address by prober actively, every interval, and times out in timeout,
+// DefaultInterval and DefaultTimeout if not positive.
+func WithProber(prober Prober, interval, timeout time.Duration) Option {
+	return OptionFunc(func(r *Resolver) {
+		r.prober = prober
+		if interval > 0 {
+			r.interval = interval
+		}
+		if timeout > 0 {
+			r.timeout = timeout
+		}
+	})
+}
+
+// WithPicker picks by picker in ResolveOneAddr, randomly if not set.
+func WithPicker(picker resolver.Picker) Option {
+	return OptionFunc(func(r *Resolver) {
+		r.picker = picker
+	})
+}
+
+// WithMaxConsecutiveFailures ejects an address failed n times consecutively, by probes or requests,
+// ejection by consecutive failures is disabled if n is not positive.
+func WithMaxConsecutiveFailures(n int) Option {
+	return OptionFunc(func(r *Resolver) {
+		r.maxConsecutiveFailures = n
+	})
+}
+
+// WithErrorRate ejects an address whose error rate of requests reported through ResolveDone in window
+// reaches threshold, in (0, 1], once minRequests are reported at least.
+// DefaultErrorRateWindow and DefaultErrorRateMinRequests if not positive.
+func WithErrorRate(threshold float64, window time.Duration, minRequests int) Option {
+	return OptionFunc(func(r *Resolver) {
+		r.errorRateThreshold = threshold
+		if window > 0 {
+			r.errorRateWindow = window
+		}
+		if minRequests > 0 {
+			r.errorRateMinRequests = minRequests
+		}
+	})
+}
+
+// WithBackOff returns the duration an address is ejected for by newBackOff, a BackOff per address,
+// which goes on every time the address ejected, and is reset by a probe succeeded.
+// time_.NewGrpcExponentialBackOff is used if not set.
+func WithBackOff(newBackOff func() time_.BackOff) Option {
+	return OptionFunc(func(r *Resolver) {
+		if newBackOff != nil {
+			r.newBackOff = newBackOff
+		}
+	})
+}
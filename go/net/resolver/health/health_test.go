@@ -0,0 +1,169 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package health_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/net/resolver"
+	"github.com/searKing/golang/go/net/resolver/health"
+	time_ "github.com/searKing/golang/go/time"
+)
+
+type staticResolver []resolver.Address
+
+func (r staticResolver) ResolveOneAddr(ctx context.Context, opts ...resolver.ResolveOneAddrOption) (resolver.Address, error) {
+	return r[0], nil
+}
+func (r staticResolver) ResolveAddr(ctx context.Context, opts ...resolver.ResolveAddrOption) ([]resolver.Address, error) {
+	return r, nil
+}
+func (r staticResolver) ResolveNow(ctx context.Context, opts ...resolver.ResolveNowOption) {}
+func (r staticResolver) Close()                                                            {}
+
+func addrsOf(t *testing.T, r *health.Resolver) string {
+	t.Helper()
+	addrs, err := r.ResolveAddr(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s []string
+	for _, addr := range addrs {
+		s = append(s, addr.Addr)
+	}
+	return strings.Join(s, ",")
+}
+
+func constantBackOff(d time.Duration) func() time_.BackOff {
+	return func() time_.BackOff {
+		return time_.NewExponentialBackOff(
+			time_.WithExponentialBackOffOptionInitialInterval(d),
+			time_.WithExponentialBackOffOptionRandomizationFactor(0),
+			time_.WithExponentialBackOffOptionMultiplier(1))
+	}
+}
+
+func TestResolver_Probe(t *testing.T) {
+	var mu sync.Mutex
+	down := map[string]bool{"b": true}
+	prober := health.ProberFunc(func(ctx context.Context, addr resolver.Address) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if down[addr.Addr] {
+			return errors.New("down")
+		}
+		return nil
+	})
+	r := health.NewResolver(staticResolver{{Addr: "a"}, {Addr: "b"}},
+		health.WithProber(prober, 10*time.Millisecond, 0),
+		health.WithMaxConsecutiveFailures(2),
+		health.WithBackOff(constantBackOff(50*time.Millisecond)))
+	defer r.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for !r.Ejected("b") {
+		if time.Now().After(deadline) {
+			t.Fatal("b is not ejected")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got, want := addrsOf(t, r), "a"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for i := 0; i < 10; i++ {
+		addr, err := r.ResolveOneAddr(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr.Addr != "a" {
+			t.Fatalf("picked ejected %q", addr.Addr)
+		}
+	}
+
+	mu.Lock()
+	down["b"] = false
+	mu.Unlock()
+	time.Sleep(100 * time.Millisecond)
+	if got, want := addrsOf(t, r), "a,b"; got != want {
+		t.Errorf("reinstated, got %q, want %q", got, want)
+	}
+}
+
+func TestResolver_ResolveDone(t *testing.T) {
+	r := health.NewResolver(staticResolver{{Addr: "a"}, {Addr: "b"}},
+		health.WithMaxConsecutiveFailures(0),
+		health.WithErrorRate(0.5, time.Minute, 4),
+		health.WithBackOff(constantBackOff(time.Hour)))
+	defer r.Close()
+
+	ctx := context.Background()
+	errs := []error{nil, errors.New("x"), errors.New("x"), nil}
+	for _, err := range errs {
+		r.ResolveDone(ctx, resolver.DoneInfo{Addr: resolver.Address{Addr: "b"}, Err: err})
+		r.ResolveDone(ctx, resolver.DoneInfo{Addr: resolver.Address{Addr: "a"}})
+	}
+	if got, want := addrsOf(t, r), "a"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// panic mode
+	for i := 0; i < 4; i++ {
+		r.ResolveDone(ctx, resolver.DoneInfo{Addr: resolver.Address{Addr: "a"}, Err: errors.New("x")})
+	}
+	if got, want := addrsOf(t, r), "a,b"; got != want {
+		t.Errorf("all ejected, got %q, want %q", got, want)
+	}
+}
+
+func TestTCPProber(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := health.TCPProber().Probe(ctx, resolver.Address{Addr: addr}); err != nil {
+		t.Errorf("probe listening: %s", err)
+	}
+	l.Close()
+	if err := health.TCPProber().Probe(ctx, resolver.Address{Addr: addr}); err == nil {
+		t.Errorf("probe closed, want error")
+	}
+}
+
+func TestHTTPProber(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	addr := resolver.Address{Addr: strings.TrimPrefix(srv.URL, "http://")}
+	ctx := context.Background()
+	if err := health.HTTPProber(nil, "http", "/healthz").Probe(ctx, addr); err != nil {
+		t.Errorf("probe /healthz: %s", err)
+	}
+	if err := health.HTTPProber(nil, "http", "/down").Probe(ctx, addr); err == nil {
+		t.Errorf("probe /down, want error")
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/searKing/golang/go/net/resolver"
+)
+
+// Prober probes an Address actively, a nil error means healthy.
+type Prober interface {
+	Probe(ctx context.Context, addr resolver.Address) error
+}
+
+// The ProberFunc type is an adapter to allow the use of
+// ordinary functions as Prober.
+type ProberFunc func(ctx context.Context, addr resolver.Address) error
+
+// Probe calls f(ctx, addr).
+func (f ProberFunc) Probe(ctx context.Context, addr resolver.Address) error {
+	return f(ctx, addr)
+}
+
+// TCPProber returns a Prober probes by connecting to the Addr, healthy if connected.
+func TCPProber() Prober {
+	return ProberFunc(func(ctx context.Context, addr resolver.Address) error {
+		var d net.Dialer
+		c, err := d.DialContext(ctx, "tcp", addr.Addr)
+		if err != nil {
+			return err
+		}
+		return c.Close()
+	})
+}
+
+// HTTPProber returns a Prober probes by GET scheme://Addr/path, as "http", "/healthz",
+// healthy if replied with a status code of 2xx or 3xx.
+// http.DefaultClient is used if client is nil.
+func HTTPProber(client *http.Client, scheme, path string) Prober {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return ProberFunc(func(ctx context.Context, addr resolver.Address) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+addr.Addr+path, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+			return fmt.Errorf("health: probe %s: unhealthy status %s", req.URL, resp.Status)
+		}
+		return nil
+	})
+}
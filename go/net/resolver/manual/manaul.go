@@ -11,10 +11,11 @@ import (
 )
 
 // NewBuilderWithScheme creates a new test resolver builder with the given scheme.
-func NewBuilderWithScheme(scheme string) *Resolver {
+func NewBuilderWithScheme(scheme string, opts ...resolver.BuildOption) *Resolver {
 	r := &Resolver{
 		ResolveNowCallback: func(ctx context.Context, opts ...resolver.ResolveNowOption) {},
 		scheme:             scheme,
+		opts:               opts,
 	}
 	r.ResolveOneAddrCallback = func(ctx context.Context, addrs []resolver.Address, opts ...resolver.ResolveOneAddrOption) (resolver.Address, error) {
 		return resolver.PickFirst(ctx, addrs)
@@ -36,12 +37,14 @@ type Resolver struct {
 	// be built.
 	ResolveNowCallback func(ctx context.Context, opts ...resolver.ResolveNowOption)
 	scheme             string
+	opts               []resolver.BuildOption
 
 	// Addresses is the latest set of resolved addresses for the target.
 	Addresses []resolver.Address
 
 	// Fields actually belong to the resolver.
 	CC             resolver.ClientConn
+	picker         resolver.Picker
 	bootstrapState *resolver.State
 }
 
@@ -52,8 +55,13 @@ func (r *Resolver) InitialState(s resolver.State) {
 }
 
 // Build returns itself for Resolver, because it's both a builder and a resolver.
-func (r *Resolver) Build(ctx context.Context, target resolver.Target, cc resolver.ClientConn, opts ...resolver.ResolveNowOption) (resolver.Resolver, error) {
-	r.CC = cc
+func (r *Resolver) Build(ctx context.Context, target resolver.Target, opts ...resolver.BuildOption) (resolver.Resolver, error) {
+	var opt resolver.Build
+	opt.ApplyOptions(r.opts...).ApplyOptions(opts...)
+	r.CC = opt.ClientConn
+	if opt.NewPicker != nil {
+		r.picker = opt.NewPicker()
+	}
 	if r.bootstrapState != nil {
 		r.UpdateState(*r.bootstrapState)
 	}
@@ -65,8 +73,11 @@ func (r *Resolver) Scheme() string {
 	return r.scheme
 }
 
-// ResolveOneAddr is a noop for Resolver.
+// ResolveOneAddr picks from Addresses by the Picker built, or returns the first one.
 func (r *Resolver) ResolveOneAddr(ctx context.Context, opts ...resolver.ResolveOneAddrOption) (resolver.Address, error) {
+	if r.picker != nil {
+		return resolver.PickOneAddr(ctx, r.picker, r.Addresses, opts...)
+	}
 	return r.Addresses[0], nil
 }
 
@@ -80,10 +91,20 @@ func (r *Resolver) ResolveNow(ctx context.Context, opts ...resolver.ResolveNowOp
 	r.ResolveNowCallback(ctx, opts...)
 }
 
+// ResolveDone is passed to the Picker built if it's a resolver.DonePicker.
+func (r *Resolver) ResolveDone(ctx context.Context, doneInfo resolver.DoneInfo, opts ...resolver.ResolveDoneOption) {
+	if p, ok := r.picker.(resolver.DonePicker); ok {
+		p.Done(ctx, doneInfo)
+	}
+}
+
 // Close is a noop for Resolver.
 func (*Resolver) Close() {}
 
-// UpdateState calls CC.UpdateState.
+// UpdateState sets Addresses, and calls CC.UpdateState if CC is not nil.
 func (r *Resolver) UpdateState(s resolver.State) {
-	_ = r.CC.UpdateState(s)
+	r.Addresses = s.Addresses
+	if r.CC != nil {
+		_ = r.CC.UpdateState(s)
+	}
 }
@@ -13,33 +13,46 @@ import (
 const scheme = "passthrough"
 
 func init() {
-	resolver.Register(&passthroughBuilder{})
+	resolver.Register(NewBuilderWithScheme(scheme))
 }
 
-type passthroughBuilder struct{}
+// NewBuilderWithScheme returns a builder of passthrough resolvers for scheme,
+// which resolve the endpoint of the target as the only address.
+func NewBuilderWithScheme(scheme string, opts ...resolver.BuildOption) resolver.Builder {
+	return &passthroughBuilder{scheme: scheme, opts: opts}
+}
+
+type passthroughBuilder struct {
+	scheme string
+	opts   []resolver.BuildOption
+}
 
-func (*passthroughBuilder) Build(ctx context.Context, target resolver.Target, opts ...resolver.BuildOption) (resolver.Resolver, error) {
+func (b *passthroughBuilder) Build(ctx context.Context, target resolver.Target, opts ...resolver.BuildOption) (resolver.Resolver, error) {
 	var opt resolver.Build
-	opt.ApplyOptions(opts...)
+	opt.ApplyOptions(b.opts...).ApplyOptions(opts...)
 	r := &passthroughResolver{
 		target: target,
 		cc:     opt.ClientConn,
 	}
+	if opt.NewPicker != nil {
+		r.picker = opt.NewPicker()
+	}
 	r.start()
 	return r, nil
 }
 
-func (*passthroughBuilder) Scheme() string {
-	return scheme
+func (b *passthroughBuilder) Scheme() string {
+	return b.scheme
 }
 
 type passthroughResolver struct {
 	target resolver.Target
 	cc     resolver.ClientConn
+	picker resolver.Picker
 }
 
 func (r *passthroughResolver) ResolveOneAddr(ctx context.Context, opts ...resolver.ResolveOneAddrOption) (resolver.Address, error) {
-	return resolver.Address{Addr: r.target.Endpoint}, nil
+	return resolver.PickOneAddr(ctx, r.picker, []resolver.Address{{Addr: r.target.Endpoint}}, opts...)
 }
 
 func (r *passthroughResolver) ResolveAddr(ctx context.Context, opts ...resolver.ResolveAddrOption) ([]resolver.Address, error) {
@@ -48,6 +61,12 @@ func (r *passthroughResolver) ResolveAddr(ctx context.Context, opts ...resolver.
 
 func (r *passthroughResolver) ResolveNow(ctx context.Context, opts ...resolver.ResolveNowOption) {}
 
+func (r *passthroughResolver) ResolveDone(ctx context.Context, doneInfo resolver.DoneInfo, opts ...resolver.ResolveDoneOption) {
+	if p, ok := r.picker.(resolver.DonePicker); ok {
+		p.Done(ctx, doneInfo)
+	}
+}
+
 func (r *passthroughResolver) start() {
 	if r.cc != nil {
 		_ = r.cc.UpdateState(resolver.State{Addresses: []resolver.Address{{Addr: r.target.Endpoint}}})
@@ -7,6 +7,9 @@ package resolver
 import (
 	"context"
 	"errors"
+	"fmt"
+
+	rand_ "github.com/searKing/golang/go/math/rand"
 )
 
 var (
@@ -33,3 +36,28 @@ type PickerFunc func(ctx context.Context, addrs []Address, opts ...PickOption) (
 func (f PickerFunc) Pick(ctx context.Context, addrs []Address, opts ...PickOption) (Address, error) {
 	return f(ctx, addrs, opts...)
 }
+
+// PickerBuilder returns a new Picker, as NewPicker of Build.
+type PickerBuilder func() Picker
+
+// PickOneAddr picks an Address of addrs by picker, with the PickOptions of opts,
+// or randomly if picker is nil, as ResolveOneAddr of a Resolver does.
+func PickOneAddr(ctx context.Context, picker Picker, addrs []Address, opts ...ResolveOneAddrOption) (Address, error) {
+	if len(addrs) == 0 {
+		return Address{}, fmt.Errorf("resolve target, but no addr")
+	}
+	if picker != nil {
+		var opt resolveOneAddr
+		opt.ApplyOptions(opts...)
+		return picker.Pick(ctx, addrs, opt.Picker...)
+	}
+	return addrs[rand_.Intn(len(addrs))], nil
+}
+
+// DonePicker is a Picker that is informed when the RPC on the Address picked is done,
+// so that loads of addresses, as requests in flight and latency, can be tracked.
+// ResolveDone of a Resolver picking by a DonePicker is expected to call Done.
+type DonePicker interface {
+	Picker
+	Done(ctx context.Context, doneInfo DoneInfo)
+}
@@ -0,0 +1,191 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package resolver
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	rand_ "github.com/searKing/golang/go/math/rand"
+)
+
+// DefaultPeakEWMADecay is the decay of latency tracked by NewPeakEWMAPicker, if not set.
+const DefaultPeakEWMADecay = 10 * time.Second
+
+// addrLoad is the load of an Address, keyed by Addr.
+type addrLoad struct {
+	inflight int64
+	ewma     float64   // peak EWMA of latency, in nanoseconds
+	stamp    time.Time // time ewma updated
+}
+
+// loadTracker tracks loads of addresses picked, by requests in flight and latency reported by Done.
+type loadTracker struct {
+	mu    sync.Mutex
+	loads map[string]*addrLoad
+	decay time.Duration
+	now   func() time.Time
+}
+
+func newLoadTracker(decay time.Duration) *loadTracker {
+	return &loadTracker{loads: make(map[string]*addrLoad), decay: decay, now: time.Now}
+}
+
+// pick picks an address by choose, and counts a request in flight on it.
+func (t *loadTracker) pick(addrs []Address, choose func(loads []*addrLoad) int) (Address, error) {
+	if len(addrs) == 0 {
+		return Address{}, ErrNoAddrAvailable
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pruneLocked(addrs)
+	loads := make([]*addrLoad, len(addrs))
+	for i, addr := range addrs {
+		loads[i] = t.loadLocked(addr.Addr)
+	}
+	i := choose(loads)
+	loads[i].inflight++
+	return addrs[i], nil
+}
+
+// done counts a request finished, and tracks the latency.
+func (t *loadTracker) done(doneInfo DoneInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.loads[doneInfo.Addr.Addr]
+	if !ok {
+		return
+	}
+	if l.inflight > 0 {
+		l.inflight--
+	}
+	if doneInfo.Duration <= 0 {
+		return
+	}
+	// peak EWMA, the latency is taken at once if higher, or decays exponentially to it.
+	now := t.now()
+	rtt := float64(doneInfo.Duration)
+	if l.stamp.IsZero() || rtt > l.ewma {
+		l.ewma = rtt
+	} else {
+		w := math.Exp(-float64(now.Sub(l.stamp)) / float64(t.decay))
+		l.ewma = l.ewma*w + rtt*(1-w)
+	}
+	l.stamp = now
+}
+
+func (t *loadTracker) loadLocked(addr string) *addrLoad {
+	l, ok := t.loads[addr]
+	if !ok {
+		l = &addrLoad{}
+		t.loads[addr] = l
+	}
+	return l
+}
+
+// pruneLocked drops idle addresses not resolved any more, if too many are tracked.
+func (t *loadTracker) pruneLocked(addrs []Address) {
+	if len(t.loads) <= 2*len(addrs) {
+		return
+	}
+	alive := make(map[string]struct{}, len(addrs))
+	for _, addr := range addrs {
+		alive[addr.Addr] = struct{}{}
+	}
+	for addr, l := range t.loads {
+		if _, ok := alive[addr]; !ok && l.inflight == 0 {
+			delete(t.loads, addr)
+		}
+	}
+}
+
+// twoChoices returns two distinct random indexes in [0, n), or 0, 0 if n is 1.
+func twoChoices(n int) (int, int) {
+	if n == 1 {
+		return 0, 0
+	}
+	i := rand_.Intn(n)
+	j := rand_.Intn(n - 1)
+	if j >= i {
+		j++
+	}
+	return i, j
+}
+
+type loadPicker struct {
+	tracker *loadTracker
+	choose  func(loads []*addrLoad) int
+}
+
+func (p *loadPicker) Pick(ctx context.Context, addrs []Address, opts ...PickOption) (Address, error) {
+	return p.tracker.pick(addrs, p.choose)
+}
+
+func (p *loadPicker) Done(ctx context.Context, doneInfo DoneInfo) {
+	p.tracker.done(doneInfo)
+}
+
+// NewLeastRequestPicker returns a DonePicker picks the address with the fewest requests in flight,
+// ties are broken randomly.
+func NewLeastRequestPicker() DonePicker {
+	return &loadPicker{
+		tracker: newLoadTracker(DefaultPeakEWMADecay),
+		choose: func(loads []*addrLoad) int {
+			best, ties := 0, 1
+			for i := 1; i < len(loads); i++ {
+				switch {
+				case loads[i].inflight < loads[best].inflight:
+					best, ties = i, 1
+				case loads[i].inflight == loads[best].inflight:
+					// reservoir sampling among ties
+					ties++
+					if rand_.Intn(ties) == 0 {
+						best = i
+					}
+				}
+			}
+			return best
+		},
+	}
+}
+
+// NewP2CPicker returns a DonePicker of power of two choices,
+// picks the one with fewer requests in flight of two addresses chosen randomly.
+func NewP2CPicker() DonePicker {
+	return &loadPicker{
+		tracker: newLoadTracker(DefaultPeakEWMADecay),
+		choose: func(loads []*addrLoad) int {
+			i, j := twoChoices(len(loads))
+			if loads[j].inflight < loads[i].inflight {
+				return j
+			}
+			return i
+		},
+	}
+}
+
+// NewPeakEWMAPicker returns a DonePicker of power of two choices by peak EWMA of latency,
+// picks the one with lower cost of two addresses chosen randomly, cost is latency * (requests in flight + 1).
+// The latency, reported by Done, is taken at once if higher, or moves to lower ones in decay,
+// DefaultPeakEWMADecay if decay is not positive.
+// Addresses never reported are picked first.
+func NewPeakEWMAPicker(decay time.Duration) DonePicker {
+	if decay <= 0 {
+		decay = DefaultPeakEWMADecay
+	}
+	return &loadPicker{
+		tracker: newLoadTracker(decay),
+		choose: func(loads []*addrLoad) int {
+			i, j := twoChoices(len(loads))
+			cost := func(l *addrLoad) float64 { return l.ewma * float64(l.inflight+1) }
+			if cost(loads[j]) < cost(loads[i]) {
+				return j
+			}
+			return i
+		},
+	}
+}
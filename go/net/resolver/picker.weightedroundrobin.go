@@ -0,0 +1,91 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package resolver
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	rand_ "github.com/searKing/golang/go/math/rand"
+)
+
+// AddressWeight returns the weight carried in the Metadata of addr, 1 if not carried, 0 if disabled.
+// Metadata carries weight by a method Weight() int, or by a key "weight" of
+// map[string]any, as decoded from JSON or YAML, or map[string]string.
+func AddressWeight(addr Address) int {
+	var w any
+	switch md := addr.Metadata.(type) {
+	case interface{ Weight() int }:
+		w = md.Weight()
+	case map[string]any:
+		w = md["weight"]
+	case map[string]string:
+		w = md["weight"]
+	}
+	switch w := w.(type) {
+	case int:
+		return clampWeight(int64(w))
+	case int64:
+		return clampWeight(w)
+	case uint64:
+		return clampWeight(int64(w))
+	case float64:
+		return clampWeight(int64(w))
+	case string:
+		if n, err := strconv.ParseInt(w, 10, 64); err == nil {
+			return clampWeight(n)
+		}
+	}
+	return 1
+}
+
+func clampWeight(w int64) int {
+	if w < 0 {
+		return 0
+	}
+	return int(w)
+}
+
+type wrrPicker struct {
+	mu      sync.Mutex
+	current map[string]int // current weights of smooth weighted round robin
+}
+
+// NewWeightedRoundRobinPicker returns a Picker of smooth weighted round robin, as nginx does,
+// weights are carried in Address Metadata, see AddressWeight.
+// Addresses of weight 0 are never picked, unless all weigh 0.
+func NewWeightedRoundRobinPicker() Picker {
+	return &wrrPicker{current: make(map[string]int)}
+}
+
+func (p *wrrPicker) Pick(ctx context.Context, addrs []Address, opts ...PickOption) (Address, error) {
+	if len(addrs) == 0 {
+		return Address{}, ErrNoAddrAvailable
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.current) > 2*len(addrs) {
+		p.current = make(map[string]int, len(addrs))
+	}
+
+	best, total := -1, 0
+	for i, addr := range addrs {
+		w := AddressWeight(addr)
+		if w == 0 {
+			continue
+		}
+		total += w
+		p.current[addr.Addr] += w
+		if best < 0 || p.current[addr.Addr] > p.current[addrs[best].Addr] {
+			best = i
+		}
+	}
+	if best < 0 {
+		return addrs[rand_.Intn(len(addrs))], nil
+	}
+	p.current[addrs[best].Addr] -= total
+	return addrs[best], nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package resolver_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/net/resolver"
+	"github.com/searKing/golang/go/net/resolver/manual"
+)
+
+type weight int
+
+func (w weight) Weight() int { return int(w) }
+
+func TestWeightedRoundRobinPicker(t *testing.T) {
+	ctx := context.Background()
+	addrs := []resolver.Address{
+		{Addr: "a", Metadata: weight(5)},
+		{Addr: "b", Metadata: map[string]any{"weight": float64(1)}},
+		{Addr: "c", Metadata: map[string]string{"weight": "1"}},
+		{Addr: "d", Metadata: map[string]any{"weight": 0}},
+	}
+	p := resolver.NewWeightedRoundRobinPicker()
+	var got string
+	for i := 0; i < 7; i++ {
+		addr, err := p.Pick(ctx, addrs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got += addr.Addr
+	}
+	// smooth weighted round robin of nginx
+	if want := "aabacaa"; got != want {
+		t.Errorf("picked %q, want %q", got, want)
+	}
+
+	if _, err := p.Pick(ctx, nil); err != resolver.ErrNoAddrAvailable {
+		t.Errorf("expect ErrNoAddrAvailable, got %v", err)
+	}
+}
+
+func TestAddressWeight(t *testing.T) {
+	testCases := []struct {
+		metadata any
+		expect   int
+	}{
+		{nil, 1},
+		{"weight", 1},
+		{weight(3), 3},
+		{map[string]any{"weight": 2}, 2},
+		{map[string]any{"weight": int64(-1)}, 0},
+		{map[string]any{"weight": "x"}, 1},
+		{map[string]string{"weight": "4"}, 4},
+	}
+	for i, test := range testCases {
+		if got := resolver.AddressWeight(resolver.Address{Metadata: test.metadata}); got != test.expect {
+			t.Errorf("#%d: AddressWeight(%v) = %d, want %d", i, test.metadata, got, test.expect)
+		}
+	}
+}
+
+func TestLeastRequestPicker(t *testing.T) {
+	ctx := context.Background()
+	addrs := []resolver.Address{{Addr: "a"}, {Addr: "b"}, {Addr: "c"}}
+	for name, p := range map[string]resolver.DonePicker{
+		"least-request": resolver.NewLeastRequestPicker(),
+		"p2c":           resolver.NewP2CPicker(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			// requests in flight are spread
+			inflight := map[string]int{}
+			for i := 0; i < 30; i++ {
+				addr, err := p.Pick(ctx, addrs)
+				if err != nil {
+					t.Fatal(err)
+				}
+				inflight[addr.Addr]++
+			}
+			for _, addr := range addrs {
+				if n := inflight[addr.Addr]; n < 5 || n > 15 {
+					t.Errorf("%s has %d requests in flight, want about 10", addr.Addr, n)
+				}
+			}
+		})
+	}
+
+	// "a" is released, so picked first
+	p := resolver.NewLeastRequestPicker()
+	for i := 0; i < 3; i++ {
+		_, _ = p.Pick(ctx, addrs)
+	}
+	p.Done(ctx, resolver.DoneInfo{Addr: resolver.Address{Addr: "b"}})
+	if addr, _ := p.Pick(ctx, addrs); addr.Addr != "b" {
+		t.Errorf("picked %q, want %q with fewest requests in flight", addr.Addr, "b")
+	}
+}
+
+func TestPeakEWMAPicker(t *testing.T) {
+	ctx := context.Background()
+	addrs := []resolver.Address{{Addr: "fast"}, {Addr: "slow"}}
+	p := resolver.NewPeakEWMAPicker(time.Minute)
+
+	// warm up, both reported once
+	for range addrs {
+		picked, _ := p.Pick(ctx, addrs)
+		d := time.Millisecond
+		if picked.Addr == "slow" {
+			d = 100 * time.Millisecond
+		}
+		p.Done(ctx, resolver.DoneInfo{Addr: picked, Duration: d})
+	}
+
+	for i := 0; i < 10; i++ {
+		picked, _ := p.Pick(ctx, addrs)
+		if picked.Addr != "fast" {
+			t.Fatalf("#%d: picked %q, want %q", i, picked.Addr, "fast")
+		}
+		p.Done(ctx, resolver.DoneInfo{Addr: picked, Duration: time.Millisecond})
+	}
+
+	// a peak is taken at once
+	p.Done(ctx, resolver.DoneInfo{Addr: resolver.Address{Addr: "fast"}, Duration: time.Second})
+	if picked, _ := p.Pick(ctx, addrs); picked.Addr != "slow" {
+		t.Errorf("picked %q after a peak of latency, want %q", picked.Addr, "slow")
+	}
+}
+
+func TestResolveOneAddrWithPicker(t *testing.T) {
+	ctx := context.Background()
+	addrs := []resolver.Address{{Addr: "a"}, {Addr: "b"}, {Addr: "c"}}
+	r := manual.NewBuilderWithScheme("least-request-test",
+		resolver.WithBuildNewPicker(func() resolver.Picker { return resolver.NewLeastRequestPicker() }))
+	r.InitialState(resolver.State{Addresses: addrs})
+	resolver.Register(r)
+	target := "least-request-test:///test"
+
+	// requests in flight are spread by the picker of the resolver registered
+	inflight := map[string]int{}
+	var picked []resolver.Address
+	for range addrs {
+		addr, err := resolver.ResolveOneAddr(ctx, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inflight[addr.Addr]++
+		picked = append(picked, addr)
+	}
+	for _, addr := range addrs {
+		if n := inflight[addr.Addr]; n != 1 {
+			t.Errorf("%s has %d requests in flight, want 1", addr.Addr, n)
+		}
+	}
+
+	// ResolveDone is passed to the picker, so the address released is picked first
+	if err := resolver.ResolveDone(ctx, target, resolver.DoneInfo{Addr: picked[1]}); err != nil {
+		t.Fatal(err)
+	}
+	if addr, err := resolver.ResolveOneAddr(ctx, target); err != nil || addr.Addr != picked[1].Addr {
+		t.Errorf("ResolveOneAddr() = %q, %v, want %q with fewest requests in flight", addr.Addr, err, picked[1].Addr)
+	}
+}
+
+func TestResolveOneAddrWithPickOption(t *testing.T) {
+	ctx := context.Background()
+	var picked int
+	pickOpt := resolver.PickOptionFunc(func(*resolver.Pick) { picked++ })
+	r := manual.NewBuilderWithScheme("pick-option-test",
+		resolver.WithBuildNewPicker(func() resolver.Picker {
+			return resolver.PickerFunc(func(ctx context.Context, addrs []resolver.Address, opts ...resolver.PickOption) (resolver.Address, error) {
+				var pick resolver.Pick
+				pick.ApplyOptions(opts...)
+				return addrs[0], nil
+			})
+		}))
+	r.InitialState(resolver.State{Addresses: []resolver.Address{{Addr: "a"}}})
+	resolver.Register(r)
+
+	// PickOptions of ResolveOneAddr are passed to the picker
+	if _, err := resolver.ResolveOneAddr(ctx, "pick-option-test:///test",
+		resolver.ResolveOneAddrOptionWithPickerOption(pickOpt)); err != nil {
+		t.Fatal(err)
+	}
+	if picked != 1 {
+		t.Errorf("PickOption applied %d times, want 1", picked)
+	}
+}
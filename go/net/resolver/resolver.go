@@ -20,6 +20,10 @@ var ErrBadResolverState = errors.New("bad resolver state")
 //go:generate go-option -type "Build"
 type Build struct {
 	ClientConn ClientConn
+	// NewPicker returns the Picker used by ResolveOneAddr of the resolver built, one per resolver,
+	// ResolveDone is passed to the Picker if it's a DonePicker.
+	// The resolver picks randomly if NewPicker is nil.
+	NewPicker PickerBuilder
 }
 
 // Builder is the interface that must be implemented by a database
@@ -28,6 +32,10 @@ type Build struct {
 // Database drivers may implement DriverContext for access
 // to contexts and to parse the name only once for a pool of connections,
 // instead of once per connection.
+//
+// BuildOptions passed to the constructor of a Builder, such as NewBuilderWithScheme of the resolvers
+// implemented, are applied to every resolver built before the BuildOptions of Build,
+// so that a Picker can be set per scheme by WithBuildNewPicker.
 type Builder interface {
 	// Build creates a new resolver for the given target.
 	//
@@ -121,6 +129,10 @@ type Address struct {
 
 	// ResolveLoad is the load received from resolver.
 	ResolveLoad any
+
+	// Metadata is the information associated with Addr, which may be used
+	// to make load balancing decision, as weight, see AddressWeight.
+	Metadata any
 }
 
 // DoneInfo contains additional information for done.
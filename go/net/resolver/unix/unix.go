@@ -9,20 +9,25 @@ import (
 	"context"
 	"fmt"
 
-	rand_ "github.com/searKing/golang/go/math/rand"
 	"github.com/searKing/golang/go/net/resolver"
 )
 
 const unixScheme = "unix"
 const unixAbstractScheme = "unix-abstract"
 
+// NewBuilderWithScheme returns a builder of unix resolvers for scheme, addresses are abstract if scheme is "unix-abstract".
+func NewBuilderWithScheme(scheme string, opts ...resolver.BuildOption) resolver.Builder {
+	return &builder{scheme: scheme, opts: opts}
+}
+
 type builder struct {
 	scheme string
+	opts   []resolver.BuildOption
 }
 
 func (b *builder) Build(ctx context.Context, target resolver.Target, opts ...resolver.BuildOption) (resolver.Resolver, error) {
 	var opt resolver.Build
-	opt.ApplyOptions(opts...)
+	opt.ApplyOptions(b.opts...).ApplyOptions(opts...)
 	cc := opt.ClientConn
 	if target.Authority != "" {
 		return nil, fmt.Errorf("invalid (non-empty) authority: %v", target.Authority)
@@ -36,7 +41,11 @@ func (b *builder) Build(ctx context.Context, target resolver.Target, opts ...res
 	if cc != nil {
 		_ = cc.UpdateState(resolver.State{Addresses: []resolver.Address{{Addr: addr}}})
 	}
-	return &nopResolver{Addresses: []resolver.Address{{Addr: addr}}}, nil
+	r := &nopResolver{Addresses: []resolver.Address{{Addr: addr}}}
+	if opt.NewPicker != nil {
+		r.picker = opt.NewPicker()
+	}
+	return r, nil
 }
 
 func (b *builder) Scheme() string {
@@ -46,13 +55,12 @@ func (b *builder) Scheme() string {
 type nopResolver struct {
 	// Addresses is the latest set of resolved addresses for the target.
 	Addresses []resolver.Address
+
+	picker resolver.Picker
 }
 
 func (r *nopResolver) ResolveOneAddr(ctx context.Context, opts ...resolver.ResolveOneAddrOption) (resolver.Address, error) {
-	if len(r.Addresses) == 0 {
-		return resolver.Address{}, fmt.Errorf("resolve target, but no addr")
-	}
-	return r.Addresses[rand_.Intn(len(r.Addresses))], nil
+	return resolver.PickOneAddr(ctx, r.picker, r.Addresses, opts...)
 }
 
 func (r *nopResolver) ResolveAddr(ctx context.Context, opts ...resolver.ResolveAddrOption) ([]resolver.Address, error) {
@@ -61,9 +69,15 @@ func (r *nopResolver) ResolveAddr(ctx context.Context, opts ...resolver.ResolveA
 
 func (*nopResolver) ResolveNow(ctx context.Context, opts ...resolver.ResolveNowOption) {}
 
+func (r *nopResolver) ResolveDone(ctx context.Context, doneInfo resolver.DoneInfo, opts ...resolver.ResolveDoneOption) {
+	if p, ok := r.picker.(resolver.DonePicker); ok {
+		p.Done(ctx, doneInfo)
+	}
+}
+
 func (*nopResolver) Close() {}
 
 func init() {
-	resolver.Register(&builder{scheme: unixScheme})
-	resolver.Register(&builder{scheme: unixAbstractScheme})
+	resolver.Register(NewBuilderWithScheme(unixScheme))
+	resolver.Register(NewBuilderWithScheme(unixAbstractScheme))
 }
@@ -17,13 +17,14 @@ func NewClientFunc(onOpenHandler OnOpenHandler,
 	onMsgReadHandler OnMsgReadHandler,
 	onMsgHandleHandler OnMsgHandleHandler,
 	onCloseHandler OnCloseHandler,
-	onErrorHandler OnErrorHandler) *Client {
+	onErrorHandler OnErrorHandler,
+	opts ...ServerOption) *Client {
 	return &Client{
-		Server: NewServerFunc(onOpenHandler, onMsgReadHandler, onMsgHandleHandler, onCloseHandler, onErrorHandler),
+		Server: NewServerFunc(onOpenHandler, onMsgReadHandler, onMsgHandleHandler, onCloseHandler, onErrorHandler, opts...),
 	}
 }
-func NewClient(h Handler) *Client {
-	return NewClientFunc(h, h, h, h, h)
+func NewClient(h Handler, opts ...ServerOption) *Client {
+	return NewClientFunc(h, h, h, h, h, opts...)
 }
 
 // Deprecated: use DialAndServe instead.
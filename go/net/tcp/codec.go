@@ -0,0 +1,94 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrFrameTooLarge is returned when a frame exceeds the codec's MaxFrameSize.
+var ErrFrameTooLarge = errors.New("tcp: frame too large")
+
+// A FrameCodec splits a byte stream into frames and frames payloads back onto it.
+type FrameCodec interface {
+	// ReadFrame reads the next frame from r and returns its payload.
+	// The payload aliases either r's buffer or buf, which is grown if too small,
+	// so it is only valid until the next call of ReadFrame on r.
+	ReadFrame(r *bufio.Reader, buf []byte) ([]byte, error)
+	// WriteFrame writes p to w as one frame.
+	WriteFrame(w io.Writer, p []byte) error
+}
+
+// readFull reads exactly n bytes from r, zero-copy out of r's buffer if they fit there,
+// or into buf otherwise.
+func readFull(r *bufio.Reader, n int, buf []byte) ([]byte, error) {
+	if n <= r.Size() {
+		p, err := r.Peek(n)
+		if err != nil {
+			if err == io.EOF && len(p) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		// Discard never reads as n bytes have been buffered, p stays valid.
+		_, _ = r.Discard(n)
+		return p, nil
+	}
+	// grow buf as data arrives, rather than trusting n upfront
+	buf = buf[:0]
+	for len(buf) < n {
+		if len(buf) == cap(buf) {
+			buf = append(buf, 0)[:len(buf)]
+		}
+		m := cap(buf)
+		if m > n {
+			m = n
+		}
+		k, err := io.ReadFull(r, buf[len(buf):m])
+		buf = buf[:len(buf)+k]
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func checkFrameSize(n int, max int) error {
+	if max > 0 && n > max {
+		return ErrFrameTooLarge
+	}
+	return nil
+}
+
+// maxPooledFrameBufSize bounds buffers kept in frameBufPool, so that a rare huge frame
+// does not pin its memory.
+const maxPooledFrameBufSize = 64 << 10
+
+var frameBufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 4<<10)
+		return &b
+	},
+}
+
+// writeFrame writes header, p and trailer to w with a single Write, through a pooled buffer.
+func writeFrame(w io.Writer, header, p, trailer []byte) error {
+	bufp := frameBufPool.Get().(*[]byte)
+	buf := append((*bufp)[:0], header...)
+	buf = append(buf, p...)
+	buf = append(buf, trailer...)
+	_, err := w.Write(buf)
+	if cap(buf) <= maxPooledFrameBufSize {
+		*bufp = buf
+		frameBufPool.Put(bufp)
+	}
+	return err
+}
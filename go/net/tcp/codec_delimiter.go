@@ -0,0 +1,83 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// ErrFrameDelimiter is returned when writing a payload containing the codec's delimiter.
+var ErrFrameDelimiter = errors.New("tcp: frame contains delimiter")
+
+// DelimiterCodec frames payloads by terminating each of them with a delimiter, such as a line feed.
+type DelimiterCodec struct {
+	// Delimiter terminates every frame, "\n" if empty.
+	Delimiter []byte
+	// MaxFrameSize limits the payload size in bytes, delimiter excluded, unlimited if non-positive.
+	MaxFrameSize int
+}
+
+// NewDelimiterCodec returns a DelimiterCodec terminating frames with delim.
+func NewDelimiterCodec(delim []byte, maxFrameSize int) *DelimiterCodec {
+	return &DelimiterCodec{Delimiter: delim, MaxFrameSize: maxFrameSize}
+}
+
+func (c *DelimiterCodec) delimiter() []byte {
+	if len(c.Delimiter) > 0 {
+		return c.Delimiter
+	}
+	return []byte{'\n'}
+}
+
+func (c *DelimiterCodec) ReadFrame(r *bufio.Reader, buf []byte) ([]byte, error) {
+	delim := c.delimiter()
+	last := delim[len(delim)-1]
+
+	// fast path, the whole frame is in r's buffer
+	line, err := r.ReadSlice(last)
+	if err == nil && bytes.HasSuffix(line, delim) {
+		frame := line[:len(line)-len(delim)]
+		if err := checkFrameSize(len(frame), c.MaxFrameSize); err != nil {
+			return nil, err
+		}
+		return frame, nil
+	}
+
+	buf = buf[:0]
+	for {
+		buf = append(buf, line...)
+		if err == nil && bytes.HasSuffix(buf, delim) {
+			frame := buf[:len(buf)-len(delim)]
+			if err := checkFrameSize(len(frame), c.MaxFrameSize); err != nil {
+				return nil, err
+			}
+			return frame, nil
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			if err == io.EOF && len(buf) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if c.MaxFrameSize > 0 && len(buf) > c.MaxFrameSize+len(delim) {
+			return nil, ErrFrameTooLarge
+		}
+		line, err = r.ReadSlice(last)
+	}
+}
+
+func (c *DelimiterCodec) WriteFrame(w io.Writer, p []byte) error {
+	if err := checkFrameSize(len(p), c.MaxFrameSize); err != nil {
+		return err
+	}
+	delim := c.delimiter()
+	if bytes.Contains(p, delim) {
+		return ErrFrameDelimiter
+	}
+	return writeFrame(w, nil, p, delim)
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// FixedLengthCodec frames payloads with a fixed size header holding the payload length,
+// the header itself excluded.
+type FixedLengthCodec struct {
+	// ByteOrder of the length header, binary.BigEndian if nil.
+	ByteOrder binary.ByteOrder
+	// HeaderSize is the size of the length header in bytes: 1, 2, 4 or 8, 4 if zero.
+	HeaderSize int
+	// MaxFrameSize limits the payload size in bytes, unlimited if non-positive.
+	MaxFrameSize int
+}
+
+// NewFixedLengthCodec returns a FixedLengthCodec with headerSize bytes of length header in order.
+func NewFixedLengthCodec(order binary.ByteOrder, headerSize int, maxFrameSize int) *FixedLengthCodec {
+	return &FixedLengthCodec{ByteOrder: order, HeaderSize: headerSize, MaxFrameSize: maxFrameSize}
+}
+
+func (c *FixedLengthCodec) byteOrder() binary.ByteOrder {
+	if c.ByteOrder != nil {
+		return c.ByteOrder
+	}
+	return binary.BigEndian
+}
+
+func (c *FixedLengthCodec) headerSize() int {
+	if c.HeaderSize != 0 {
+		return c.HeaderSize
+	}
+	return 4
+}
+
+func (c *FixedLengthCodec) ReadFrame(r *bufio.Reader, buf []byte) ([]byte, error) {
+	size := c.headerSize()
+	header, err := readFull(r, size, nil)
+	if err != nil {
+		return nil, err
+	}
+	var n uint64
+	order := c.byteOrder()
+	switch size {
+	case 1:
+		n = uint64(header[0])
+	case 2:
+		n = uint64(order.Uint16(header))
+	case 4:
+		n = uint64(order.Uint32(header))
+	case 8:
+		n = order.Uint64(header)
+	default:
+		return nil, fmt.Errorf("tcp: unsupported frame header size %d", size)
+	}
+	if n > uint64(math.MaxInt) {
+		return nil, ErrFrameTooLarge
+	}
+	if err := checkFrameSize(int(n), c.MaxFrameSize); err != nil {
+		return nil, err
+	}
+	return readFull(r, int(n), buf)
+}
+
+func (c *FixedLengthCodec) WriteFrame(w io.Writer, p []byte) error {
+	if err := checkFrameSize(len(p), c.MaxFrameSize); err != nil {
+		return err
+	}
+	var header [8]byte
+	size := c.headerSize()
+	n := uint64(len(p))
+	order := c.byteOrder()
+	switch size {
+	case 1:
+		header[0] = uint8(n)
+	case 2:
+		order.PutUint16(header[:], uint16(n))
+	case 4:
+		order.PutUint32(header[:], uint32(n))
+	case 8:
+		order.PutUint64(header[:], n)
+	default:
+		return fmt.Errorf("tcp: unsupported frame header size %d", size)
+	}
+	if size < 8 && n >= 1<<(8*size) {
+		return ErrFrameTooLarge
+	}
+	return writeFrame(w, header[:size], p, nil)
+}
+
+// UvarintCodec frames payloads with a uvarint length prefix, the wire format of
+// protobuf's delimited messages.
+type UvarintCodec struct {
+	// MaxFrameSize limits the payload size in bytes, unlimited if non-positive.
+	MaxFrameSize int
+}
+
+// NewUvarintCodec returns an UvarintCodec limiting payloads to maxFrameSize bytes.
+func NewUvarintCodec(maxFrameSize int) *UvarintCodec {
+	return &UvarintCodec{MaxFrameSize: maxFrameSize}
+}
+
+func (c *UvarintCodec) ReadFrame(r *bufio.Reader, buf []byte) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, fmt.Errorf("tcp: read frame length: %w", err)
+	}
+	if n > uint64(math.MaxInt) {
+		return nil, ErrFrameTooLarge
+	}
+	if err := checkFrameSize(int(n), c.MaxFrameSize); err != nil {
+		return nil, err
+	}
+	return readFull(r, int(n), buf)
+}
+
+func (c *UvarintCodec) WriteFrame(w io.Writer, p []byte) error {
+	if err := checkFrameSize(len(p), c.MaxFrameSize); err != nil {
+		return err
+	}
+	var header [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(header[:], uint64(len(p)))
+	return writeFrame(w, header[:n], p, nil)
+}
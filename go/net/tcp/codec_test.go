@@ -0,0 +1,140 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/net/tcp"
+)
+
+func TestFrameCodec(t *testing.T) {
+	codecs := map[string]tcp.FrameCodec{
+		"big endian":    tcp.NewFixedLengthCodec(binary.BigEndian, 4, 0),
+		"little endian": tcp.NewFixedLengthCodec(binary.LittleEndian, 2, 0),
+		"uvarint":       tcp.NewUvarintCodec(0),
+		"line":          &tcp.DelimiterCodec{},
+		"crlf":          tcp.NewDelimiterCodec([]byte("\r\n"), 0),
+	}
+	// larger than the reader's buffer, so read through buf rather than zero-copy
+	large := strings.Repeat("0123456789", 2000)
+	frames := []string{"hello", "", "a\rb", large, "world"}
+
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			var stream bytes.Buffer
+			for _, f := range frames {
+				if err := codec.WriteFrame(&stream, []byte(f)); err != nil {
+					t.Fatalf("WriteFrame(%.10q): %v", f, err)
+				}
+			}
+
+			r := bufio.NewReaderSize(&stream, 4<<10)
+			var buf []byte
+			for _, f := range frames {
+				got, err := codec.ReadFrame(r, buf)
+				if err != nil {
+					t.Fatalf("ReadFrame: %v", err)
+				}
+				if string(got) != f {
+					t.Fatalf("ReadFrame = %.10q(%d), want %.10q(%d)", got, len(got), f, len(f))
+				}
+			}
+			if _, err := codec.ReadFrame(r, buf); err != io.EOF {
+				t.Errorf("ReadFrame at end of stream = %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestFrameCodecMaxFrameSize(t *testing.T) {
+	codecs := map[string]tcp.FrameCodec{
+		"fixed length": tcp.NewFixedLengthCodec(nil, 0, 8),
+		"uvarint":      tcp.NewUvarintCodec(8),
+		"delimiter":    tcp.NewDelimiterCodec(nil, 8),
+	}
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			var stream bytes.Buffer
+			if err := codec.WriteFrame(&stream, []byte("123456789")); !errors.Is(err, tcp.ErrFrameTooLarge) {
+				t.Errorf("WriteFrame = %v, want ErrFrameTooLarge", err)
+			}
+			// write oversize frames with an unlimited peer codec
+			var peer tcp.FrameCodec
+			switch codec.(type) {
+			case *tcp.FixedLengthCodec:
+				peer = tcp.NewFixedLengthCodec(nil, 0, 0)
+			case *tcp.UvarintCodec:
+				peer = tcp.NewUvarintCodec(0)
+			case *tcp.DelimiterCodec:
+				peer = tcp.NewDelimiterCodec(nil, 0)
+			}
+			if err := peer.WriteFrame(&stream, bytes.Repeat([]byte("x"), 10000)); err != nil {
+				t.Fatalf("WriteFrame: %v", err)
+			}
+			if _, err := codec.ReadFrame(bufio.NewReaderSize(&stream, 4<<10), nil); !errors.Is(err, tcp.ErrFrameTooLarge) {
+				t.Errorf("ReadFrame = %v, want ErrFrameTooLarge", err)
+			}
+		})
+	}
+}
+
+func TestDelimiterCodecWriteDelimiter(t *testing.T) {
+	err := tcp.NewDelimiterCodec(nil, 0).WriteFrame(io.Discard, []byte("a\nb"))
+	if !errors.Is(err, tcp.ErrFrameDelimiter) {
+		t.Errorf("WriteFrame = %v, want ErrFrameDelimiter", err)
+	}
+}
+
+func TestFrameHandler(t *testing.T) {
+	codec := tcp.NewUvarintCodec(1 << 20)
+	srv := tcp.NewServer(tcp.NewFrameHandlerFunc(codec, nil, nil,
+		tcp.OnMsgHandleHandlerFunc(func(w io.Writer, msg interface{}) error {
+			// echo, upper cased
+			_, err := w.Write(bytes.ToUpper(msg.([]byte)))
+			return err
+		}), nil, nil))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go srv.Serve(ln)
+	defer srv.Shutdown(context.Background())
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	frames := []string{"hello", strings.Repeat("frame", 3000), "world"}
+	for _, f := range frames {
+		if err := codec.WriteFrame(conn, []byte(f)); err != nil {
+			t.Fatalf("WriteFrame: %v", err)
+		}
+	}
+	r := bufio.NewReader(conn)
+	for _, f := range frames {
+		got, err := codec.ReadFrame(r, nil)
+		if err != nil {
+			t.Fatalf("ReadFrame: %v", err)
+		}
+		if want := strings.ToUpper(f); string(got) != want {
+			t.Errorf("ReadFrame = %.10q(%d), want %.10q(%d)", got, len(got), want, len(want))
+		}
+	}
+}
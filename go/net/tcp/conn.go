@@ -10,6 +10,7 @@ import (
 	"io"
 	"net"
 	"runtime"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -47,7 +48,18 @@ type conn struct {
 
 	// werr is set to the first write error to rwc.
 	// It is set via checkConnErrorWriter{w}, where bufw writes.
-	werr error
+	werr   error
+	werrMu sync.Mutex // guards werr, written by sendLoop too
+
+	// sendq queues writes if the server's SendQueueSize is positive,
+	// written out by sendLoop until sendStop is closed.
+	sendq     chan []byte
+	sendStop  chan struct{}
+	sendDone  chan struct{}
+	flushOnce sync.Once
+
+	// limited is set if the connection holds a slot of the server's MaxConns.
+	limited bool
 
 	// r is bufr's read source. It's a wrapper around rwc that provides
 	// io.LimitedReader-style limiting (while reading request headers)
@@ -58,14 +70,27 @@ type conn struct {
 	curState struct{ atomic uint64 } // packed (unixtime<<8|uint8(ConnState))
 }
 
+// finalFlush writes out the send queue, before the connection is closed.
 func (c *conn) finalFlush() {
+	if c.sendq == nil {
+		return
+	}
+	c.flushOnce.Do(func() {
+		close(c.sendStop)
+		<-c.sendDone
+	})
 }
 
 // Close the connection.
 func (c *conn) close() error {
 	err := c.server.onCloseHandler.OnClose(c.w, c.r)
+	c.finalFlush()
 	c.rwc.Close()
 	c.r.abortPendingRead()
+	if c.limited {
+		c.limited = false
+		c.server.releaseConn()
+	}
 	return err
 }
 
@@ -147,19 +172,21 @@ func isCommonNetReadError(err error) bool {
 
 // onMsgRead next request from connection.
 func (c *conn) readRequest(ctx context.Context) (req interface{}, err error) {
-
-	var (
-		wholeReqDeadline time.Time // or zero if none
-		hdrDeadline      time.Time // or zero if none
-	)
-	t0 := time.Now()
-	if d := c.server.readTimeout(); d != 0 {
-		hdrDeadline = t0.Add(d)
+	if c.server.shuttingDown() {
+		return nil, ErrServerClosed
 	}
-	if d := c.server.ReadTimeout; d != 0 {
-		wholeReqDeadline = t0.Add(d)
+	if st, _ := c.getState(); st == StateActive {
+		c.setState(c.rwc, StateIdle)
 	}
-	c.rwc.SetReadDeadline(hdrDeadline)
+	// wait for the next message up to the idle timeout,
+	// beginMessage extends the deadline once its first byte is read.
+	if d := c.server.idleTimeout(); d != 0 {
+		c.rwc.SetReadDeadline(time.Now().Add(d))
+	} else {
+		c.rwc.SetReadDeadline(time.Time{})
+	}
+	c.r.setIdle(true)
+	defer c.r.setIdle(false)
 	if d := c.server.WriteTimeout; d != 0 {
 		defer func() {
 			c.rwc.SetWriteDeadline(time.Now().Add(d))
@@ -172,19 +199,31 @@ func (c *conn) readRequest(ctx context.Context) (req interface{}, err error) {
 		if c.r.hitReadLimit() {
 			return nil, errTooLarge
 		}
+		if c.server.shuttingDown() && isCommonNetReadError(err) {
+			// woken up by Shutdown
+			return nil, ErrServerClosed
+		}
 		return nil, err
 	}
 
 	c.r.setInfiniteReadLimit()
-
-	// Adjust the read deadline if necessary.
-	if !hdrDeadline.Equal(wholeReqDeadline) {
-		c.rwc.SetReadDeadline(wholeReqDeadline)
+	// the message may have been buffered by the handler already
+	if st, _ := c.getState(); st != StateActive {
+		c.setState(c.rwc, StateActive)
 	}
-
 	return req, nil
 }
 
+// beginMessage is called once the first byte of a message is read.
+func (c *conn) beginMessage() {
+	c.setState(c.rwc, StateActive)
+	if d := c.server.readTimeout(); d != 0 {
+		c.rwc.SetReadDeadline(time.Now().Add(d))
+	} else {
+		c.rwc.SetReadDeadline(time.Time{})
+	}
+}
+
 // Serve a new connection.
 func (c *conn) serve(ctx context.Context) {
 	c.remoteAddr = c.rwc.RemoteAddr().String()
@@ -207,16 +246,18 @@ func (c *conn) serve(ctx context.Context) {
 	c.cancelCtx = cancelCtx
 	defer cancelCtx()
 
-	// wrap original conn itself with buffer
-	c.r = &connReader{conn: c}
-	c.w = &checkConnErrorWriter{c: c}
+	if n := c.server.SendQueueSize; n > 0 {
+		c.sendq = make(chan []byte, n)
+		c.sendStop = make(chan struct{})
+		c.sendDone = make(chan struct{})
+		go c.sendLoop()
+	}
 
 	// read and handle the msg
 	dispatch.NewDispatch(dispatch.ReaderFunc(func(ctx context.Context) (interface{}, error) {
 		msg, err := c.readRequest(ctx)
-		if c.r.remain != c.server.initialReadLimitSize() {
-			// If we read any bytes off the wire, we're active.
-			c.setState(c.rwc, StateActive)
+		if err == ErrServerClosed {
+			return nil, err // drained, don't reply
 		}
 		if err = c.server.CheckError(c.w, c.r, err); err != nil {
 			if isCommonNetReadError(err) {
@@ -242,10 +283,72 @@ type checkConnErrorWriter struct {
 }
 
 func (w checkConnErrorWriter) Write(p []byte) (n int, err error) {
-	n, err = w.c.rwc.Write(p)
-	if err != nil && w.c.werr == nil {
-		w.c.werr = err
-		w.c.cancelCtx()
+	if w.c.sendq != nil {
+		return w.c.enqueue(p)
+	}
+	return w.c.write(p)
+}
+
+func (c *conn) write(p []byte) (n int, err error) {
+	if d := c.server.WriteIdleTimeout; d != 0 {
+		c.rwc.SetWriteDeadline(time.Now().Add(d))
+	}
+	n, err = c.rwc.Write(p)
+	if err != nil {
+		c.werrMu.Lock()
+		if c.werr == nil {
+			c.werr = err
+			c.cancelCtx()
+		}
+		c.werrMu.Unlock()
 	}
 	return
 }
+
+func (c *conn) writeError() error {
+	c.werrMu.Lock()
+	defer c.werrMu.Unlock()
+	return c.werr
+}
+
+// enqueue queues p to be written by sendLoop, blocking while the queue is full.
+// A write error is returned by the Write following it.
+func (c *conn) enqueue(p []byte) (n int, err error) {
+	if err := c.writeError(); err != nil {
+		return 0, err
+	}
+	// p may be reused by the caller once Write returns
+	b := append([]byte(nil), p...)
+	select {
+	case c.sendq <- b:
+		return len(p), nil
+	case <-c.sendStop:
+		return 0, net.ErrClosed
+	}
+}
+
+// sendLoop writes out the send queue, until sendStop is closed and the queue is drained.
+func (c *conn) sendLoop() {
+	defer close(c.sendDone)
+	send := func(p []byte) {
+		// drop the queue once broken
+		if c.writeError() == nil {
+			c.write(p)
+		}
+	}
+	for {
+		select {
+		case p := <-c.sendq:
+			send(p)
+		case <-c.sendStop:
+			for {
+				select {
+				case p := <-c.sendq:
+					send(p)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
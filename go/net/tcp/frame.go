@@ -0,0 +1,175 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/searKing/golang/go/util/object"
+)
+
+// A Frame is the payload of one frame read by a FrameCodec, handed to OnMsgRead.
+// It aliases the connection's read buffers, so its Bytes are only valid until the
+// next frame is read, that is, until OnMsgHandle of the msg returns.
+type Frame struct {
+	b []byte
+	r bytes.Reader
+}
+
+func (f *Frame) reset(b []byte) {
+	f.b = b
+	f.r.Reset(b)
+}
+
+// Bytes returns the whole payload of the frame, without copying.
+func (f *Frame) Bytes() []byte { return f.b }
+
+// Len returns the number of unread bytes of the frame.
+func (f *Frame) Len() int { return f.r.Len() }
+
+func (f *Frame) Read(p []byte) (n int, err error) { return f.r.Read(p) }
+
+func (f *Frame) WriteTo(w io.Writer) (n int64, err error) { return f.r.WriteTo(w) }
+
+// FrameBytesReadHandler returns the payload of a Frame as msg, without copying.
+// The msg is only valid until OnMsgHandle of it returns.
+var FrameBytesReadHandler = OnMsgReadHandlerFunc(func(r io.Reader) (msg interface{}, err error) {
+	if f, ok := r.(*Frame); ok {
+		return f.Bytes(), nil
+	}
+	return io.ReadAll(r)
+})
+
+// frameWriter writes every p as one frame.
+type frameWriter struct {
+	w     io.Writer
+	codec FrameCodec
+}
+
+// NewFrameWriter returns a Writer writing every p of Write to w as one frame.
+func NewFrameWriter(w io.Writer, codec FrameCodec) io.Writer {
+	return &frameWriter{w: w, codec: codec}
+}
+
+func (w *frameWriter) Write(p []byte) (n int, err error) {
+	if err := w.codec.WriteFrame(w.w, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+var frameReaderPool sync.Pool
+
+func newFrameReader(r io.Reader) *bufio.Reader {
+	if v := frameReaderPool.Get(); v != nil {
+		br := v.(*bufio.Reader)
+		br.Reset(r)
+		return br
+	}
+	return bufio.NewReaderSize(r, 4<<10)
+}
+
+func putFrameReader(br *bufio.Reader) {
+	br.Reset(nil)
+	frameReaderPool.Put(br)
+}
+
+// frameConn is the read state of a connection, reused across frames.
+type frameConn struct {
+	br    *bufio.Reader
+	buf   []byte
+	frame Frame
+}
+
+// frameHandler splits the byte stream of each connection into frames with codec.
+type frameHandler struct {
+	codec FrameCodec
+
+	onOpenHandler      OnOpenHandler
+	onMsgReadHandler   OnMsgReadHandler
+	onMsgHandleHandler OnMsgHandleHandler
+	onCloseHandler     OnCloseHandler
+	onErrorHandler     OnErrorHandler
+
+	conns sync.Map // map[io.Reader]*frameConn
+}
+
+// NewFrameHandlerFunc returns a Handler splitting the byte stream of each connection into
+// frames with codec.
+// onMsgRead is called once per frame, with r being a *Frame holding exactly one payload,
+// FrameBytesReadHandler if nil; every Write issued to w by onMsgHandle, onClose and onError
+// goes out as one frame.
+func NewFrameHandlerFunc(codec FrameCodec,
+	onOpen OnOpenHandler,
+	onMsgRead OnMsgReadHandler,
+	onMsgHandle OnMsgHandleHandler,
+	onClose OnCloseHandler,
+	onError OnErrorHandler) Handler {
+	return &frameHandler{
+		codec:              codec,
+		onOpenHandler:      object.RequireNonNullElse(onOpen, NopOnOpenHandler).(OnOpenHandler),
+		onMsgReadHandler:   object.RequireNonNullElse(onMsgRead, FrameBytesReadHandler).(OnMsgReadHandler),
+		onMsgHandleHandler: object.RequireNonNullElse(onMsgHandle, NopOnMsgHandleHandler).(OnMsgHandleHandler),
+		onCloseHandler:     object.RequireNonNullElse(onClose, NopOnCloseHandler).(OnCloseHandler),
+		onErrorHandler:     object.RequireNonNullElse(onError, NopOnErrorHandler).(OnErrorHandler),
+	}
+}
+
+// NewFrameHandler returns a Handler splitting the byte stream of each connection into
+// frames with codec, see NewFrameHandlerFunc.
+func NewFrameHandler(codec FrameCodec, h Handler) Handler {
+	return NewFrameHandlerFunc(codec, h, h, h, h, h)
+}
+
+func (h *frameHandler) writer(w io.Writer) io.Writer {
+	if w == nil {
+		return nil
+	}
+	return NewFrameWriter(w, h.codec)
+}
+
+func (h *frameHandler) OnOpen(conn net.Conn) error { return h.onOpenHandler.OnOpen(conn) }
+
+func (h *frameHandler) OnMsgRead(r io.Reader) (msg interface{}, err error) {
+	var fc *frameConn
+	if v, ok := h.conns.Load(r); ok {
+		fc = v.(*frameConn)
+	} else {
+		br := newFrameReader(r)
+		// frames fitting in br are read out of it without copying,
+		// buf only ever grows past br's size so it never aliases br.
+		fc = &frameConn{br: br, buf: make([]byte, 0, br.Size())}
+		h.conns.Store(r, fc)
+	}
+
+	frame, err := h.codec.ReadFrame(fc.br, fc.buf)
+	if err != nil {
+		return nil, err
+	}
+	if cap(frame) > cap(fc.buf) {
+		fc.buf = frame[:0]
+	}
+	fc.frame.reset(frame)
+	return h.onMsgReadHandler.OnMsgRead(&fc.frame)
+}
+
+func (h *frameHandler) OnMsgHandle(w io.Writer, msg interface{}) error {
+	return h.onMsgHandleHandler.OnMsgHandle(h.writer(w), msg)
+}
+
+func (h *frameHandler) OnClose(w io.Writer, r io.Reader) error {
+	if v, ok := h.conns.LoadAndDelete(r); ok {
+		putFrameReader(v.(*frameConn).br)
+	}
+	return h.onCloseHandler.OnClose(h.writer(w), r)
+}
+
+func (h *frameHandler) OnError(w io.Writer, r io.Reader, err error) error {
+	return h.onErrorHandler.OnError(h.writer(w), r, err)
+}
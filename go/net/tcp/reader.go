@@ -26,6 +26,7 @@ type connReader struct {
 	inRead  bool
 	aborted bool  // set true before conn.rwc deadline is set to past
 	remain  int64 // bytes remaining
+	idle    bool  // waiting for the first byte of the next message
 }
 
 func (cr *connReader) lock() {
@@ -107,6 +108,7 @@ func (cr *connReader) abortPendingRead() {
 }
 
 func (cr *connReader) setReadLimit(remain int64) { cr.remain = remain }
+func (cr *connReader) setIdle(idle bool)         { cr.lock(); cr.idle = idle; cr.unlock() }
 func (cr *connReader) setInfiniteReadLimit()     { cr.remain = maxInt64 }
 func (cr *connReader) hitReadLimit() bool        { return cr.remain <= 0 }
 
@@ -157,8 +159,15 @@ func (cr *connReader) Read(p []byte) (n int, err error) {
 		cr.handleReadError(err)
 	}
 	cr.remain -= int64(n)
+	began := cr.idle && n > 0
+	if began {
+		cr.idle = false
+	}
 	cr.unlock()
 
 	cr.cond.Broadcast()
+	if began {
+		cr.conn.beginMessage()
+	}
 	return n, err
 }
@@ -0,0 +1,360 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/searKing/golang/go/net/tcp"
+)
+
+const (
+	// DefaultMaxFrameSize is the default limit of frames read and written,
+	// the default limit of messages read by a tcp.Server too.
+	DefaultMaxFrameSize = tcp.DefaultMaxBytes
+	// DefaultKeepaliveInterval is the default interval between pings of a Client.
+	DefaultKeepaliveInterval = 30 * time.Second
+	// DefaultKeepaliveTimeout is the default time a Client waits for a pong.
+	DefaultKeepaliveTimeout = 10 * time.Second
+)
+
+// aLongTimeAgo is a non-zero time, far in the past, used for
+// immediate cancellation of network operations.
+var aLongTimeAgo = time.Unix(1, 0)
+
+var (
+	// ErrClientClosed is returned by Calls of a Client closed by Close.
+	ErrClientClosed = errors.New("rpc: client closed")
+	// ErrKeepaliveTimeout is returned by Calls of a Client whose connection was closed
+	// for missing a keepalive pong.
+	ErrKeepaliveTimeout = errors.New("rpc: keepalive timeout")
+)
+
+// An Error is an error returned by the Handler of the Server.
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// result ends a stream of a Client.
+type result struct {
+	typ  frameType
+	body []byte
+}
+
+// Client issues concurrent Calls over a single connection.
+type Client struct {
+	conn  net.Conn
+	codec tcp.FrameCodec
+
+	maxFrameSize      int
+	keepaliveInterval time.Duration
+	keepaliveTimeout  time.Duration
+
+	wmu chan struct{} // serializes frame writes, a semaphore so that a write waiting for it can be canceled
+
+	mu      sync.Mutex
+	nextID  uint32
+	pending map[uint32]chan result
+	err     error // set once closed
+	done    chan struct{}
+}
+
+// NewClient returns a Client issuing Calls over conn, taking it over.
+func NewClient(conn net.Conn, opts ...ClientOption) *Client {
+	c := &Client{
+		conn:              conn,
+		maxFrameSize:      DefaultMaxFrameSize,
+		keepaliveInterval: DefaultKeepaliveInterval,
+		keepaliveTimeout:  DefaultKeepaliveTimeout,
+		wmu:               make(chan struct{}, 1),
+		pending:           make(map[uint32]chan result),
+		done:              make(chan struct{}),
+	}
+	c.ApplyOptions(opts...)
+	c.codec = tcp.NewFixedLengthCodec(binary.BigEndian, 4, c.maxFrameSize)
+	go c.readLoop()
+	if c.keepaliveInterval > 0 {
+		go c.keepalive()
+	}
+	return c
+}
+
+// Dial connects to the address on the named network and returns a Client over the connection.
+func Dial(ctx context.Context, network, address string, opts ...ClientOption) (*Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn, opts...), nil
+}
+
+func (c *Client) ApplyOptions(options ...ClientOption) *Client {
+	for _, opt := range options {
+		if opt == nil {
+			continue
+		}
+		opt.apply(c)
+	}
+	return c
+}
+
+// Call sends req and waits for its response.
+// The remaining time of ctx is sent along, bounding the context of the Handler; if ctx is
+// done first, the stream is canceled and ctx.Err() is returned.
+// An error returned by the Handler is returned as an *Error.
+func (c *Client) Call(ctx context.Context, req []byte) ([]byte, error) {
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		if timeout = time.Until(deadline); timeout <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+	}
+	r, err := c.roundTrip(ctx, frameRequest, appendRequest(nil, timeout, req))
+	if err != nil {
+		return nil, err
+	}
+	switch r.typ {
+	case frameResponse:
+		return r.body, nil
+	case frameError:
+		return nil, &Error{Message: string(r.body)}
+	default:
+		return nil, fmt.Errorf("rpc: unexpected %s frame in response to a request", r.typ)
+	}
+}
+
+// Ping sends a ping and waits for its pong.
+func (c *Client) Ping(ctx context.Context) error {
+	r, err := c.roundTrip(ctx, framePing, nil)
+	if err != nil {
+		return err
+	}
+	if r.typ != framePong {
+		return fmt.Errorf("rpc: unexpected %s frame in response to a ping", r.typ)
+	}
+	return nil
+}
+
+// Close closes the connection, failing all pending Calls with ErrClientClosed.
+func (c *Client) Close() error {
+	return c.closeWithError(ErrClientClosed)
+}
+
+// Done returns a channel that's closed once the connection is closed.
+func (c *Client) Done() <-chan struct{} { return c.done }
+
+// Err returns why the connection is closed, nil if still open.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *Client) roundTrip(ctx context.Context, typ frameType, body []byte) (result, error) {
+	id, ch, err := c.register()
+	if err != nil {
+		return result{}, err
+	}
+	if err := c.writeFrame(ctx, typ, id, body); err != nil {
+		c.unregister(id)
+		return result{}, err
+	}
+	select {
+	case r := <-ch:
+		return r, nil
+	case <-ctx.Done():
+		if c.unregister(id) && typ == frameRequest {
+			// best effort, the server drops a response to a stream canceled
+			go c.writeFrame(context.Background(), frameCancel, id, nil)
+		}
+		return result{}, ctx.Err()
+	case <-c.done:
+		return result{}, c.Err()
+	}
+}
+
+// register allocates a stream id and the channel its result is delivered to.
+func (c *Client) register() (uint32, chan result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return 0, nil, c.err
+	}
+	for {
+		c.nextID++
+		if _, used := c.pending[c.nextID]; c.nextID != 0 && !used {
+			break
+		}
+	}
+	ch := make(chan result, 1)
+	c.pending[c.nextID] = ch
+	return c.nextID, ch, nil
+}
+
+// unregister forgets the stream id, reporting whether it was still pending.
+func (c *Client) unregister(id uint32) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.pending[id]
+	delete(c.pending, id)
+	return ok
+}
+
+// writeFrame writes a frame, bounded by the deadline of ctx and canceled once ctx is done.
+// The connection is closed if the frame is written partly, as the frames following can't be parsed.
+func (c *Client) writeFrame(ctx context.Context, typ frameType, id uint32, body []byte) error {
+	p := appendFrame(make([]byte, 0, frameHeaderLen+len(body)), typ, id, body)
+	select {
+	case c.wmu <- struct{}{}:
+		defer func() { <-c.wmu }()
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.done:
+		return c.Err()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	deadline, _ := ctx.Deadline()
+	_ = c.conn.SetWriteDeadline(deadline)
+	w := &countWriter{w: c.conn}
+	err := c.writeFrameCancelable(ctx, w, p)
+	if err == nil {
+		return nil
+	}
+	if w.n == 0 && (ctx.Err() != nil || os.IsTimeout(err)) {
+		// nothing written, the connection is still in sync
+		_ = c.conn.SetWriteDeadline(time.Time{})
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return context.DeadlineExceeded
+	}
+	if errors.Is(err, tcp.ErrFrameTooLarge) {
+		return err
+	}
+	// the connection is broken, maybe in the middle of a frame
+	c.closeWithError(err)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// writeFrameCancelable writes p to w as a frame, the write in progress is unblocked once ctx is done.
+func (c *Client) writeFrameCancelable(ctx context.Context, w io.Writer, p []byte) error {
+	if ctx.Done() == nil {
+		return c.codec.WriteFrame(w, p)
+	}
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			_ = c.conn.SetWriteDeadline(aLongTimeAgo)
+		case <-stop:
+		}
+	}()
+	err := c.codec.WriteFrame(w, p)
+	close(stop)
+	<-stopped
+	return err
+}
+
+// countWriter counts the bytes written to w.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+func (c *Client) readLoop() {
+	br := bufio.NewReader(c.conn)
+	// frames fitting in br are read out of it without copying,
+	// buf only ever grows past br's size so it never aliases br.
+	buf := make([]byte, 0, br.Size())
+	for {
+		p, err := c.codec.ReadFrame(br, buf)
+		if err != nil {
+			c.closeWithError(err)
+			return
+		}
+		if cap(p) > cap(buf) {
+			buf = p[:0]
+		}
+		f, err := parseFrame(p)
+		if err != nil {
+			c.closeWithError(err)
+			return
+		}
+		switch f.typ {
+		case framePing:
+			go c.writeFrame(context.Background(), framePong, f.streamID, append([]byte(nil), f.body...))
+		case frameResponse, frameError, framePong:
+			c.mu.Lock()
+			ch, ok := c.pending[f.streamID]
+			delete(c.pending, f.streamID)
+			c.mu.Unlock()
+			if ok {
+				// p is reused by the next read
+				ch <- result{typ: f.typ, body: append([]byte(nil), f.body...)}
+			}
+		default:
+			c.closeWithError(fmt.Errorf("rpc: unexpected %s frame from server", f.typ))
+			return
+		}
+	}
+}
+
+func (c *Client) keepalive() {
+	ticker := time.NewTicker(c.keepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), c.keepaliveTimeout)
+		err := c.Ping(ctx)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) {
+			c.closeWithError(ErrKeepaliveTimeout)
+			return
+		}
+	}
+}
+
+// closeWithError closes the connection once, recording err as the reason.
+func (c *Client) closeWithError(err error) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil
+	}
+	c.err = err
+	close(c.done)
+	c.mu.Unlock()
+	return c.conn.Close()
+}
@@ -0,0 +1,29 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package rpc multiplexes concurrent requests and responses over a single tcp connection.
+//
+// Every frame is length prefixed by a big endian tcp.FixedLengthCodec, and starts with a
+// one byte type and the big endian uint32 id of the stream it belongs to:
+//
+//	+--------+--------+-----------+------+
+//	| length | type   | stream id | body |
+//	| 4 B    | 1 B    | 4 B       |      |
+//	+--------+--------+-----------+------+
+//
+// A Client opens a stream per Call with a REQUEST frame, carrying the remaining time of
+// the Call's context, and the Server ends it with a RESPONSE or an ERROR frame. A Call
+// whose context is done before sends a CANCEL frame, canceling the context of the
+// Handler. PING frames, answered with PONG frames, keep the connection alive.
+//
+// Server implements tcp.Handler, to be served by a tcp.Server:
+//
+//	srv := tcp.NewServer(rpc.NewServer(rpc.HandlerFunc(func(ctx context.Context, req []byte) ([]byte, error) {
+//		return req, nil
+//	})))
+//	go srv.Serve(ln)
+//
+//	cli, err := rpc.Dial(ctx, "tcp", ln.Addr().String())
+//	resp, err := cli.Call(ctx, []byte("hello"))
+package rpc
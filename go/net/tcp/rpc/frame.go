@@ -0,0 +1,88 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// frameType identifies the kind of frame.
+type frameType uint8
+
+const (
+	frameRequest  frameType = iota + 1 // body is the timeout in ns as int64 followed by the request
+	frameResponse                      // body is the response
+	frameError                         // body is the error message
+	frameCancel                        // cancels the stream, no body
+	framePing                          // body is echoed by a framePong
+	framePong                          // body echoes the framePing
+)
+
+func (t frameType) String() string {
+	switch t {
+	case frameRequest:
+		return "REQUEST"
+	case frameResponse:
+		return "RESPONSE"
+	case frameError:
+		return "ERROR"
+	case frameCancel:
+		return "CANCEL"
+	case framePing:
+		return "PING"
+	case framePong:
+		return "PONG"
+	default:
+		return fmt.Sprintf("frameType(%d)", t)
+	}
+}
+
+// frameHeaderLen is the size of the frame header: the type followed by the stream id in big endian.
+const frameHeaderLen = 5
+
+// timeoutLen is the size of the timeout leading the body of a frameRequest.
+const timeoutLen = 8
+
+// frame is a message exchanged in a stream, carried as the payload of a tcp.FrameCodec frame.
+type frame struct {
+	typ      frameType
+	streamID uint32
+	body     []byte
+}
+
+// appendFrame appends the wire form of a frame to b.
+func appendFrame(b []byte, typ frameType, streamID uint32, body []byte) []byte {
+	b = append(b, byte(typ), 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[len(b)-4:], streamID)
+	return append(b, body...)
+}
+
+// parseFrame parses p, the body of the frame aliases p.
+func parseFrame(p []byte) (frame, error) {
+	if len(p) < frameHeaderLen {
+		return frame{}, fmt.Errorf("rpc: frame too short: %d bytes", len(p))
+	}
+	return frame{
+		typ:      frameType(p[0]),
+		streamID: binary.BigEndian.Uint32(p[1:frameHeaderLen]),
+		body:     p[frameHeaderLen:],
+	}, nil
+}
+
+// appendRequest appends the body of a frameRequest to b.
+func appendRequest(b []byte, timeout time.Duration, req []byte) []byte {
+	b = binary.BigEndian.AppendUint64(b, uint64(timeout))
+	return append(b, req...)
+}
+
+// parseRequest parses the body of a frameRequest, a non-positive timeout means none.
+func parseRequest(body []byte) (timeout time.Duration, req []byte, err error) {
+	if len(body) < timeoutLen {
+		return 0, nil, fmt.Errorf("rpc: request frame too short: %d bytes", len(body))
+	}
+	return time.Duration(binary.BigEndian.Uint64(body)), body[timeoutLen:], nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import "time"
+
+// A ClientOption sets options of a Client.
+type ClientOption interface {
+	apply(*Client)
+}
+
+// ClientOptionFunc wraps a function that modifies Client into an
+// implementation of the ClientOption interface.
+type ClientOptionFunc func(*Client)
+
+func (f ClientOptionFunc) apply(c *Client) {
+	f(c)
+}
+
+// WithClientMaxFrameSize limits frames read and written to n bytes, DefaultMaxFrameSize if not positive.
+func WithClientMaxFrameSize(n int) ClientOption {
+	return ClientOptionFunc(func(c *Client) {
+		if n > 0 {
+			c.maxFrameSize = n
+		}
+	})
+}
+
+// WithClientKeepalive pings the server every interval, closing the connection with
+// ErrKeepaliveTimeout if no pong is received within timeout.
+// Zero keeps DefaultKeepaliveInterval and DefaultKeepaliveTimeout, a negative interval
+// disables keepalive pings.
+func WithClientKeepalive(interval, timeout time.Duration) ClientOption {
+	return ClientOptionFunc(func(c *Client) {
+		if interval != 0 {
+			c.keepaliveInterval = interval
+		}
+		if timeout > 0 {
+			c.keepaliveTimeout = timeout
+		}
+	})
+}
+
+// A ServerOption sets options of a Server.
+type ServerOption interface {
+	apply(*Server)
+}
+
+// ServerOptionFunc wraps a function that modifies Server into an
+// implementation of the ServerOption interface.
+type ServerOptionFunc func(*Server)
+
+func (f ServerOptionFunc) apply(srv *Server) {
+	f(srv)
+}
+
+// WithServerMaxFrameSize limits frames read and written to n bytes, DefaultMaxFrameSize if not positive.
+func WithServerMaxFrameSize(n int) ServerOption {
+	return ServerOptionFunc(func(srv *Server) {
+		if n > 0 {
+			srv.maxFrameSize = n
+		}
+	})
+}
+
+// WithServerMaxConcurrentStreams limits the requests handled concurrently on a connection to n,
+// requests over the limit failing with ErrTooManyStreams, unlimited if not positive.
+func WithServerMaxConcurrentStreams(n int) ServerOption {
+	return ServerOptionFunc(func(srv *Server) {
+		srv.maxConcurrentStreams = n
+	})
+}
@@ -0,0 +1,201 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/net/tcp"
+	"github.com/searKing/golang/go/net/tcp/rpc"
+)
+
+func serve(t *testing.T, h rpc.HandlerFunc, opts ...rpc.ServerOption) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := tcp.NewServer(rpc.NewServer(h, opts...))
+	go srv.Serve(ln)
+	t.Cleanup(func() { ln.Close() })
+	return ln.Addr().String()
+}
+
+func dial(t *testing.T, addr string, opts ...rpc.ClientOption) *rpc.Client {
+	t.Helper()
+	cli, err := rpc.Dial(context.Background(), "tcp", addr, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cli.Close() })
+	return cli
+}
+
+func TestClientCall(t *testing.T) {
+	addr := serve(t, func(ctx context.Context, req []byte) ([]byte, error) {
+		if string(req) == "fail" {
+			return nil, errors.New("failed")
+		}
+		// answer out of order
+		time.Sleep(time.Duration(len(req)%5) * time.Millisecond)
+		return append([]byte("echo "), req...), nil
+	})
+	cli := dial(t, addr)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := fmt.Sprintf("request %d", i)
+			resp, err := cli.Call(context.Background(), []byte(req))
+			if err != nil {
+				t.Errorf("Call(%q): %v", req, err)
+				return
+			}
+			if want := "echo " + req; string(resp) != want {
+				t.Errorf("Call(%q) = %q, want %q", req, resp, want)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	_, err := cli.Call(context.Background(), []byte("fail"))
+	var rpcErr *rpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Message != "failed" {
+		t.Errorf("Call = %v, want *rpc.Error failed", err)
+	}
+	if err := cli.Ping(context.Background()); err != nil {
+		t.Errorf("Ping: %v", err)
+	}
+}
+
+func TestClientCallCancel(t *testing.T) {
+	handlerDone := make(chan error, 2)
+	addr := serve(t, func(ctx context.Context, req []byte) ([]byte, error) {
+		<-ctx.Done()
+		handlerDone <- ctx.Err()
+		return nil, ctx.Err()
+	})
+	cli := dial(t, addr)
+
+	// the deadline is propagated to the handler
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := cli.Call(ctx, []byte("deadline")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Call = %v, want DeadlineExceeded", err)
+	}
+	select {
+	case <-handlerDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler not canceled once the deadline exceeded")
+	}
+
+	// the cancellation is sent to the handler
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := cli.Call(ctx, []byte("cancel")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Call = %v, want Canceled", err)
+	}
+	select {
+	case err := <-handlerDone:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("handler ctx.Err() = %v, want Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler not canceled by the cancel frame")
+	}
+}
+
+func TestServerMaxConcurrentStreams(t *testing.T) {
+	release := make(chan struct{})
+	addr := serve(t, func(ctx context.Context, req []byte) ([]byte, error) {
+		<-release
+		return req, nil
+	}, rpc.WithServerMaxConcurrentStreams(1))
+	cli := dial(t, addr)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := cli.Call(context.Background(), []byte("first"))
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if _, err := cli.Call(context.Background(), []byte("second")); err == nil || err.Error() != rpc.ErrTooManyStreams.Error() {
+		t.Errorf("Call = %v, want %v", err, rpc.ErrTooManyStreams)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Call: %v", err)
+	}
+}
+
+func TestClientKeepaliveTimeout(t *testing.T) {
+	// a server that never answers
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+
+	cli := dial(t, ln.Addr().String(), rpc.WithClientKeepalive(20*time.Millisecond, 20*time.Millisecond))
+	select {
+	case <-cli.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not closed for missing pongs")
+	}
+	if err := cli.Err(); !errors.Is(err, rpc.ErrKeepaliveTimeout) {
+		t.Errorf("Err() = %v, want ErrKeepaliveTimeout", err)
+	}
+	if _, err := cli.Call(context.Background(), nil); !errors.Is(err, rpc.ErrKeepaliveTimeout) {
+		t.Errorf("Call = %v, want ErrKeepaliveTimeout", err)
+	}
+}
+
+func TestClientCallWriteTimeout(t *testing.T) {
+	// a server that stops reading
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	cli := rpc.NewClient(conn, rpc.WithClientKeepalive(0, 0))
+	defer cli.Close()
+
+	// nothing written, the connection is kept
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := cli.Call(ctx, []byte("blocked")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Call = %v, want DeadlineExceeded", err)
+	}
+	if err := cli.Err(); err != nil {
+		t.Fatalf("Err() = %v, want nil as no frame is written partly", err)
+	}
+
+	// a frame written partly, the connection is closed
+	go func() { _, _ = peer.Read(make([]byte, 3)) }()
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := cli.Call(ctx, []byte("partly")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Call = %v, want DeadlineExceeded", err)
+	}
+	select {
+	case <-cli.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not closed for a frame written partly")
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"runtime"
+	"sync"
+
+	"github.com/searKing/golang/go/net/tcp"
+)
+
+// ErrTooManyStreams is returned to Calls over the limit of concurrent streams of a connection.
+var ErrTooManyStreams = errors.New("rpc: too many concurrent streams")
+
+// A Handler responds to a request.
+// ctx is canceled once the Call is canceled, its deadline is exceeded or the
+// connection is closed.
+type Handler interface {
+	ServeRPC(ctx context.Context, req []byte) (resp []byte, err error)
+}
+
+// The HandlerFunc type is an adapter to allow the use of
+// ordinary functions as RPC handlers.
+type HandlerFunc func(ctx context.Context, req []byte) (resp []byte, err error)
+
+// ServeRPC calls f(ctx, req).
+func (f HandlerFunc) ServeRPC(ctx context.Context, req []byte) (resp []byte, err error) {
+	return f(ctx, req)
+}
+
+// Server serves the streams of every connection with a Handler, each in its own goroutine.
+// Server implements tcp.Handler.
+type Server struct {
+	handler Handler
+
+	maxFrameSize         int
+	maxConcurrentStreams int
+
+	codec    tcp.FrameCodec
+	frames   tcp.Handler
+	sessions sync.Map // map[io.Reader]*session
+}
+
+// NewServer returns a Server serving streams with h.
+func NewServer(h Handler, opts ...ServerOption) *Server {
+	srv := &Server{handler: h, maxFrameSize: DefaultMaxFrameSize}
+	srv.ApplyOptions(opts...)
+	srv.codec = tcp.NewFixedLengthCodec(binary.BigEndian, 4, srv.maxFrameSize)
+	srv.frames = tcp.NewFrameHandlerFunc(srv.codec, nil, nil, nil, nil, nil)
+	return srv
+}
+
+func (srv *Server) ApplyOptions(options ...ServerOption) *Server {
+	for _, opt := range options {
+		if opt == nil {
+			continue
+		}
+		opt.apply(srv)
+	}
+	return srv
+}
+
+// session is the state of a connection.
+type session struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	wmu sync.Mutex // serializes frame writes
+
+	mu      sync.Mutex
+	streams map[uint32]context.CancelFunc
+}
+
+// inbound is a frame read from a connection.
+type inbound struct {
+	sess *session
+	frame
+}
+
+func (srv *Server) OnOpen(conn net.Conn) error { return nil }
+
+func (srv *Server) OnMsgRead(r io.Reader) (msg interface{}, err error) {
+	var sess *session
+	if v, ok := srv.sessions.Load(r); ok {
+		sess = v.(*session)
+	} else {
+		ctx, cancel := context.WithCancel(context.Background())
+		sess = &session{ctx: ctx, cancel: cancel, streams: make(map[uint32]context.CancelFunc)}
+		srv.sessions.Store(r, sess)
+	}
+
+	msg, err = srv.frames.OnMsgRead(r)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parseFrame(msg.([]byte))
+	if err != nil {
+		return nil, err
+	}
+	// the frame is reused by the next read, while its stream may still be served
+	f.body = append([]byte(nil), f.body...)
+	return &inbound{sess: sess, frame: f}, nil
+}
+
+func (srv *Server) OnMsgHandle(w io.Writer, msg interface{}) error {
+	in := msg.(*inbound)
+	sess := in.sess
+	switch in.typ {
+	case frameRequest:
+		timeout, req, err := parseRequest(in.body)
+		if err != nil {
+			return err
+		}
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(sess.ctx, timeout)
+		} else {
+			ctx, cancel = context.WithCancel(sess.ctx)
+		}
+		sess.mu.Lock()
+		if srv.maxConcurrentStreams > 0 && len(sess.streams) >= srv.maxConcurrentStreams {
+			sess.mu.Unlock()
+			cancel()
+			return srv.writeFrame(w, sess, frameError, in.streamID, []byte(ErrTooManyStreams.Error()))
+		}
+		sess.streams[in.streamID] = cancel
+		sess.mu.Unlock()
+		go srv.serveStream(ctx, w, sess, in.streamID, req)
+		return nil
+	case frameCancel:
+		sess.mu.Lock()
+		cancel, ok := sess.streams[in.streamID]
+		delete(sess.streams, in.streamID)
+		sess.mu.Unlock()
+		if ok {
+			cancel()
+		}
+		return nil
+	case framePing:
+		return srv.writeFrame(w, sess, framePong, in.streamID, in.body)
+	case framePong:
+		return nil
+	default:
+		return fmt.Errorf("rpc: unexpected %s frame from client", in.typ)
+	}
+}
+
+func (srv *Server) OnClose(w io.Writer, r io.Reader) error {
+	if v, ok := srv.sessions.LoadAndDelete(r); ok {
+		v.(*session).cancel()
+	}
+	return srv.frames.OnClose(w, r)
+}
+
+func (srv *Server) OnError(w io.Writer, r io.Reader, err error) error { return err }
+
+func (srv *Server) serveStream(ctx context.Context, w io.Writer, sess *session, id uint32, req []byte) {
+	resp, err := srv.serve(ctx, req)
+
+	sess.mu.Lock()
+	cancel, ok := sess.streams[id]
+	delete(sess.streams, id)
+	sess.mu.Unlock()
+	if !ok {
+		// canceled by the client, which no longer waits for a response
+		return
+	}
+	cancel()
+	if err != nil {
+		_ = srv.writeFrame(w, sess, frameError, id, []byte(err.Error()))
+		return
+	}
+	_ = srv.writeFrame(w, sess, frameResponse, id, resp)
+}
+
+func (srv *Server) serve(ctx context.Context, req []byte) (resp []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			log.Printf("rpc: panic serving request: %v\n%s", r, buf)
+			err = fmt.Errorf("rpc: panic serving request: %v", r)
+		}
+	}()
+	return srv.handler.ServeRPC(ctx, req)
+}
+
+func (srv *Server) writeFrame(w io.Writer, sess *session, typ frameType, id uint32, body []byte) error {
+	p := appendFrame(make([]byte, 0, frameHeaderLen+len(body)), typ, id, body)
+	if typ == frameResponse && len(p) > srv.maxFrameSize {
+		// keep the connection alive, the client gets to know why
+		p = appendFrame(nil, frameError, id, []byte(fmt.Sprintf("rpc: response too large: %d bytes", len(body))))
+	}
+	sess.wmu.Lock()
+	defer sess.wmu.Unlock()
+	return srv.codec.WriteFrame(w, p)
+}
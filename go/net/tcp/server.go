@@ -30,17 +30,19 @@ func NewServerFunc(
 	onMsgRead OnMsgReadHandler,
 	onMsgHandle OnMsgHandleHandler,
 	onClose OnCloseHandler,
-	onError OnErrorHandler) *Server {
-	return &Server{
+	onError OnErrorHandler,
+	opts ...ServerOption) *Server {
+	srv := &Server{
 		onOpenHandler:      object.RequireNonNullElse(onOpen, NopOnOpenHandler).(OnOpenHandler),
 		onMsgReadHandler:   object.RequireNonNullElse(onMsgRead, NopOnMsgReadHandler).(OnMsgReadHandler),
 		onMsgHandleHandler: object.RequireNonNullElse(onMsgHandle, NopOnMsgHandleHandler).(OnMsgHandleHandler),
 		onCloseHandler:     object.RequireNonNullElse(onClose, NopOnCloseHandler).(OnCloseHandler),
 		onErrorHandler:     object.RequireNonNullElse(onError, NopOnErrorHandler).(OnErrorHandler),
 	}
+	return srv.ApplyOptions(opts...)
 }
-func NewServer(h Handler) *Server {
-	return NewServerFunc(h, h, h, h, h)
+func NewServer(h Handler, opts ...ServerOption) *Server {
+	return NewServerFunc(h, h, h, h, h, opts...)
 }
 
 type Server struct {
@@ -53,14 +55,29 @@ type Server struct {
 
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
-	MaxBytes     int
+	// IdleTimeout is the maximum amount of time to wait for the
+	// next message. If IdleTimeout is zero, the value of
+	// ReadTimeout is used. If both are zero, there is no timeout.
+	IdleTimeout time.Duration
+	// WriteIdleTimeout is the maximum amount of time a single write
+	// may block, the peer not reading. Zero means no timeout.
+	WriteIdleTimeout time.Duration
+	MaxBytes         int
+
+	// MaxConns limits the number of connections served concurrently,
+	// Accept waits for a connection to close beyond. Zero means no limit.
+	MaxConns int
+	// SendQueueSize is the number of writes queued per connection, written out by
+	// a goroutine of its own, so that a slow peer does not block the handlers until
+	// the queue is full. Zero means writes are unqueued.
+	SendQueueSize int
 
 	ErrorLog *log.Logger
 
 	mu         sync.Mutex
 	listeners  map[*net.Listener]struct{}
 	activeConn map[*conn]struct{}
+	connSem    chan struct{} // limits connections to MaxConns
 	doneChan   chan struct{}
 	onShutdown []func()
 
@@ -99,6 +116,11 @@ func (srv *Server) Serve(l net.Listener) error {
 	l = &onceCloseListener{Listener: l}
 	defer l.Close()
 
+	if !srv.trackListener(&l, true) {
+		return ErrServerClosed
+	}
+	defer srv.trackListener(&l, false)
+
 	// how long to sleep on accept failure
 	var tempDelay = time_.NewExponentialBackOff(
 		time_.WithExponentialBackOffOptionInitialInterval(5*time.Millisecond),
@@ -107,8 +129,13 @@ func (srv *Server) Serve(l net.Listener) error {
 		time_.WithExponentialBackOffOptionMaxElapsedCount(-1))
 	ctx := context.WithValue(context.Background(), ServerContextKey, srv)
 	for {
+		// wait for a connection slot, if limited
+		if !srv.acquireConn() {
+			return ErrServerClosed
+		}
 		rw, e := l.Accept()
 		if e != nil {
+			srv.releaseConn()
 			// return if server is cancaled, means normally close
 			select {
 			case <-srv.getDoneChan():
@@ -134,6 +161,7 @@ func (srv *Server) Serve(l net.Listener) error {
 
 		// takeover the connect
 		c := srv.newConn(rw)
+		c.limited = srv.MaxConns > 0
 		// Handle websocket On
 		err := srv.onOpenHandler.OnOpen(c.rwc)
 		if err = srv.CheckError(c.w, c.r, err); err != nil {
@@ -145,6 +173,57 @@ func (srv *Server) Serve(l net.Listener) error {
 	}
 }
 
+// trackListener adds or removes a net.Listener to the set of tracked
+// listeners, closed by Shutdown.
+// It reports whether the server is still up (not Shutdown).
+func (srv *Server) trackListener(ln *net.Listener, add bool) bool {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.listeners == nil {
+		srv.listeners = make(map[*net.Listener]struct{})
+	}
+	if add {
+		if srv.shuttingDown() {
+			return false
+		}
+		srv.listeners[ln] = struct{}{}
+	} else {
+		delete(srv.listeners, ln)
+	}
+	return true
+}
+
+// acquireConn waits for a connection slot if MaxConns is set,
+// reporting false if the server is shut down meanwhile.
+func (srv *Server) acquireConn() bool {
+	if srv.MaxConns <= 0 {
+		return true
+	}
+	srv.mu.Lock()
+	if srv.connSem == nil {
+		srv.connSem = make(chan struct{}, srv.MaxConns)
+	}
+	sem := srv.connSem
+	srv.mu.Unlock()
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-srv.getDoneChan():
+		return false
+	}
+}
+
+// releaseConn frees a connection slot taken by acquireConn.
+func (srv *Server) releaseConn() {
+	if srv.MaxConns <= 0 {
+		return
+	}
+	srv.mu.Lock()
+	sem := srv.connSem
+	srv.mu.Unlock()
+	<-sem
+}
+
 func (srv *Server) trackConn(c *conn, add bool) {
 	srv.mu.Lock()
 	defer srv.mu.Unlock()
@@ -164,6 +243,8 @@ func (srv *Server) newConn(rwc net.Conn) *conn {
 		server: srv,
 		rwc:    rwc,
 	}
+	c.r = &connReader{conn: c}
+	c.w = &checkConnErrorWriter{c: c}
 	return c
 }
 
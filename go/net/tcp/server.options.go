@@ -0,0 +1,80 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp
+
+import "time"
+
+// A ServerOption sets options of a Server.
+type ServerOption interface {
+	apply(*Server)
+}
+
+// ServerOptionFunc wraps a function that modifies Server into an
+// implementation of the ServerOption interface.
+type ServerOptionFunc func(*Server)
+
+func (f ServerOptionFunc) apply(srv *Server) {
+	f(srv)
+}
+
+func (srv *Server) ApplyOptions(options ...ServerOption) *Server {
+	for _, opt := range options {
+		if opt == nil {
+			continue
+		}
+		opt.apply(srv)
+	}
+	return srv
+}
+
+// WithServerReadTimeout sets ReadTimeout, the maximum duration for reading a message.
+func WithServerReadTimeout(d time.Duration) ServerOption {
+	return ServerOptionFunc(func(srv *Server) {
+		srv.ReadTimeout = d
+	})
+}
+
+// WithServerWriteTimeout sets WriteTimeout, the maximum duration for handling a message
+// once read, before timing out writes.
+func WithServerWriteTimeout(d time.Duration) ServerOption {
+	return ServerOptionFunc(func(srv *Server) {
+		srv.WriteTimeout = d
+	})
+}
+
+// WithServerIdleTimeout sets IdleTimeout, the maximum amount of time to wait for the next message.
+func WithServerIdleTimeout(d time.Duration) ServerOption {
+	return ServerOptionFunc(func(srv *Server) {
+		srv.IdleTimeout = d
+	})
+}
+
+// WithServerWriteIdleTimeout sets WriteIdleTimeout, the maximum amount of time a single write may block.
+func WithServerWriteIdleTimeout(d time.Duration) ServerOption {
+	return ServerOptionFunc(func(srv *Server) {
+		srv.WriteIdleTimeout = d
+	})
+}
+
+// WithServerMaxBytes sets MaxBytes, the limit of a message read, DefaultMaxBytes if not positive.
+func WithServerMaxBytes(n int) ServerOption {
+	return ServerOptionFunc(func(srv *Server) {
+		srv.MaxBytes = n
+	})
+}
+
+// WithServerMaxConns sets MaxConns, the limit of connections served concurrently.
+func WithServerMaxConns(n int) ServerOption {
+	return ServerOptionFunc(func(srv *Server) {
+		srv.MaxConns = n
+	})
+}
+
+// WithServerSendQueueSize sets SendQueueSize, the number of writes queued per connection.
+func WithServerSendQueueSize(n int) ServerOption {
+	return ServerOptionFunc(func(srv *Server) {
+		srv.SendQueueSize = n
+	})
+}
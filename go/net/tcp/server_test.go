@@ -0,0 +1,162 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcp_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/net/tcp"
+)
+
+// lineServer serves line delimited messages with handle, returning its address.
+func lineServer(t *testing.T, handle tcp.OnMsgHandleHandlerFunc, onClose tcp.OnCloseHandlerFunc, opts ...tcp.ServerOption) (*tcp.Server, string) {
+	t.Helper()
+	var onCloseHandler tcp.OnCloseHandler
+	if onClose != nil {
+		onCloseHandler = onClose
+	}
+	srv := tcp.NewServer(tcp.NewFrameHandlerFunc(tcp.NewDelimiterCodec(nil, 0), nil, nil, handle, onCloseHandler, nil), opts...)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() { ln.Close() })
+	return srv, ln.Addr().String()
+}
+
+var echo = tcp.OnMsgHandleHandlerFunc(func(w io.Writer, msg interface{}) error {
+	_, err := w.Write(msg.([]byte))
+	return err
+})
+
+func dialLine(t *testing.T, addr string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return conn, bufio.NewReader(conn)
+}
+
+func TestServerIdleTimeout(t *testing.T) {
+	var closed atomic.Int32
+	_, addr := lineServer(t, echo, func(w io.Writer, r io.Reader) error {
+		closed.Add(1)
+		return nil
+	}, tcp.WithServerIdleTimeout(50*time.Millisecond))
+
+	conn, r := dialLine(t, addr)
+	fmt.Fprintf(conn, "ping\n")
+	if line, err := r.ReadString('\n'); err != nil || line != "ping\n" {
+		t.Fatalf("ReadString = %q, %v", line, err)
+	}
+	// idle from now on
+	if _, err := r.ReadString('\n'); err != io.EOF {
+		t.Errorf("ReadString of an idle connection = %v, want io.EOF", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n := closed.Load(); n != 1 {
+		t.Errorf("OnClose called %d times, want 1", n)
+	}
+}
+
+func TestServerMaxConns(t *testing.T) {
+	_, addr := lineServer(t, echo, nil, tcp.WithServerMaxConns(1))
+
+	first, r1 := dialLine(t, addr)
+	fmt.Fprintf(first, "first\n")
+	if line, err := r1.ReadString('\n'); err != nil || line != "first\n" {
+		t.Fatalf("ReadString = %q, %v", line, err)
+	}
+
+	// queued in the backlog, not served until the first one is closed
+	second, r2 := dialLine(t, addr)
+	fmt.Fprintf(second, "second\n")
+	_ = second.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if line, err := r2.ReadString('\n'); err == nil {
+		t.Fatalf("ReadString = %q, beyond MaxConns", line)
+	}
+
+	first.Close()
+	_ = second.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if line, err := r2.ReadString('\n'); err != nil || line != "second\n" {
+		t.Fatalf("ReadString = %q, %v", line, err)
+	}
+}
+
+func TestServerSendQueue(t *testing.T) {
+	const n = 100
+	_, addr := lineServer(t, func(w io.Writer, msg interface{}) error {
+		for i := 0; i < n; i++ {
+			if _, err := fmt.Fprintf(w, "%s %d", msg, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil, tcp.WithServerSendQueueSize(4))
+
+	conn, r := dialLine(t, addr)
+	fmt.Fprintf(conn, "msg\n")
+	for i := 0; i < n; i++ {
+		line, err := r.ReadString('\n')
+		if want := fmt.Sprintf("msg %d\n", i); err != nil || line != want {
+			t.Fatalf("ReadString = %q, %v, want %q", line, err, want)
+		}
+	}
+}
+
+func TestServerShutdown(t *testing.T) {
+	handling := make(chan struct{})
+	srv, addr := lineServer(t, func(w io.Writer, msg interface{}) error {
+		close(handling)
+		time.Sleep(200 * time.Millisecond)
+		_, err := w.Write(msg.([]byte))
+		return err
+	}, func(w io.Writer, r io.Reader) error {
+		_, err := w.Write([]byte("bye"))
+		return err
+	})
+
+	busy, busyR := dialLine(t, addr)
+	_, idleR := dialLine(t, addr)
+	fmt.Fprintf(busy, "slow\n")
+	<-handling
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Errorf("Shutdown returned after %v, before the handler finished", d)
+	}
+
+	// the busy connection finished its message, both got notified
+	for _, want := range []string{"slow\n", "bye\n"} {
+		if line, err := busyR.ReadString('\n'); err != nil || line != want {
+			t.Errorf("busy ReadString = %q, %v, want %q", line, err, want)
+		}
+	}
+	if line, err := idleR.ReadString('\n'); err != nil || line != "bye\n" {
+		t.Errorf("idle ReadString = %q, %v, want %q", line, err, "bye\n")
+	}
+
+	// no longer accepting
+	if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+		conn.Close()
+		t.Errorf("Dial after Shutdown succeeded")
+	}
+}
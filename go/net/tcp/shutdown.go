@@ -11,6 +11,14 @@ import (
 
 var shutdownPollInterval = 500 * time.Millisecond
 
+// Shutdown gracefully shuts down the server without interrupting any
+// active connections. Shutdown works by first closing all open
+// listeners, then letting every connection finish the message it is
+// handling, notify its OnClose handler and close, and then waiting
+// for all connections to be closed.
+// If the provided context expires before the shutdown is complete,
+// Shutdown returns the context's error, otherwise it returns any
+// error returned from closing the Server's underlying Listener(s).
 func (srv *Server) Shutdown(ctx context.Context) error {
 	srv.inShutdown.Store(true)
 
@@ -42,28 +50,28 @@ func (srv *Server) RegisterOnShutdown(f func()) {
 	srv.mu.Unlock()
 }
 
-// closeIdleConns closes all idle connections and reports whether the
-// server is quiescent.
+// closeIdleConns wakes up all connections waiting for their next message, which
+// notify their OnClose handlers and close, and reports whether all connections
+// are closed, so that the server is quiescent.
 func (srv *Server) closeIdleConns() bool {
 	srv.mu.Lock()
 	defer srv.mu.Unlock()
 	quiescent := true
 	for c := range srv.activeConn {
+		quiescent = false
 		st, unixSec := c.getState()
-		// Issue 22682: treat StateNew connections as if
-		// they're idle if we haven't read the first request's
-		// header in over 5 seconds.
-		if st == StateNew && unixSec < time.Now().Unix()-5 {
+		// StateNew connections have not read any byte of their
+		// first message yet, they turn StateActive on the first one.
+		if st == StateNew {
 			st = StateIdle
 		}
 		if st != StateIdle || unixSec == 0 {
 			// Assume unixSec == 0 means it's a very new
 			// connection, without state set yet.
-			quiescent = false
 			continue
 		}
-		c.rwc.Close()
-		delete(srv.activeConn, c)
+		// a busy connection notices the shutdown on its own, once back idle
+		c.rwc.SetReadDeadline(aLongTimeAgo)
 	}
 	return quiescent
 }
@@ -0,0 +1,34 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package os
+
+import "os"
+
+// WithLockedFile opens the named file for reading and writing, holds an exclusive lock on it
+// by LockFile and calls f.
+// If the file does not exist, it is created with mode 0644 (before umask).
+func WithLockedFile(name string, f func(f *os.File) error) error {
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := LockFile(file); err != nil {
+		return &os.PathError{Op: "lock", Path: name, Err: err}
+	}
+	defer UnlockFile(file)
+	return f(file)
+}
+
+// ReplaceFile replaces the whole content of f with data and commits it to stable storage.
+func ReplaceFile(f *os.File, data []byte) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(data, 0); err != nil {
+		return err
+	}
+	return f.Sync()
+}
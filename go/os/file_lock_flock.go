@@ -0,0 +1,28 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package os
+
+import (
+	"os"
+	"syscall"
+)
+
+// LockFile blocks until an exclusive advisory lock on f is held.
+// flock locks belong to the open file description, so two opens within one process exclude each other as well.
+func LockFile(f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+// UnlockFile releases the lock on f held by LockFile.
+func UnlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
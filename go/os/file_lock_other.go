@@ -0,0 +1,23 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package os
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// LockFile is not supported on this platform, an error is always returned.
+func LockFile(f *os.File) error {
+	return fmt.Errorf("file lock is not supported on %s", runtime.GOOS)
+}
+
+// UnlockFile is not supported on this platform, an error is always returned.
+func UnlockFile(f *os.File) error {
+	return fmt.Errorf("file lock is not supported on %s", runtime.GOOS)
+}
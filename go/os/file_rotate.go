@@ -5,6 +5,7 @@
 package os
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -84,11 +85,38 @@ type RotateFile struct {
 	// name means file path rotated
 	PostRotateHandler func(name string)
 
+	// Compressor compresses rotated files on a separate goroutine, the rotated file is replaced by
+	// the compressed one, named with the Ext of Compressor appended, see GzipCompressor.
+	// take effects if only Compressor is not nil and RotateMode is RotateModeNew.
+	Compressor Compressor
+
+	// ManifestPath is the path of the manifest, which records rotated files as segments in order,
+	// with byte ranges and time ranges, see ReadRotateFileManifest.
+	// ManifestPath should not match RotateFileGlob, or it will be cleaned.
+	// take effects if only ManifestPath is not empty and RotateMode is RotateModeNew.
+	ManifestPath string
+
+	// Archiver archives rotated files on a separate goroutine, after compressed, such as uploading to a blob bucket.
+	// take effects if only Archiver is not nil and RotateMode is RotateModeNew.
+	Archiver RotateFileArchiver
+
+	// ArchiveErrorHandler called if rotated file failed to be compressed, recorded into the manifest or archived
+	// name means file path rotated
+	ArchiveErrorHandler func(name string, err error)
+
 	cleaning      atomic.Bool
 	mu            sync.Mutex
 	usingSeq      int // file rotated by size limit meet
 	usingFilePath string
 	usingFile     *os.File
+	usingSince    time.Time // time the using file was opened
+
+	archiveMu     sync.Mutex
+	archiving     bool                // archive goroutine is running
+	archiveQueue  []RotateFileSegment // rotated files waiting to be archived
+	archiveWg     sync.WaitGroup
+	archiveOffset int64 // offset of the next segment
+	archiveLoaded bool  // archiveOffset is loaded from the manifest
 }
 
 func NewRotateFile(layout string) *RotateFile {
@@ -153,7 +181,36 @@ func (f *RotateFile) WriteAt(b []byte, off int64) (n int, err error) {
 // Close satisfies the io.Closer interface. You must
 // call this method if you performed any writes to
 // the object.
+// Close is shorthand for CloseContext(context.Background()).
 func (f *RotateFile) Close() error {
+	return f.CloseContext(context.Background())
+}
+
+// CloseContext closes the using file, then waits for files rotated to be archived until ctx is done,
+// returns ctx.Err() if not all archived.
+// The using file is not archived, as it's reopened and appended to by a RotateFile started later,
+// it's archived once rotated.
+func (f *RotateFile) CloseContext(ctx context.Context) error {
+	err := f.closeUsingFile()
+
+	// wait for rotated files to be archived
+	archived := make(chan struct{})
+	go func() {
+		defer close(archived)
+		f.archiveWg.Wait()
+	}()
+	select {
+	case <-archived:
+		return err
+	case <-ctx.Done():
+		if err != nil {
+			return err
+		}
+		return ctx.Err()
+	}
+}
+
+func (f *RotateFile) closeUsingFile() error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
@@ -323,9 +380,13 @@ func (f *RotateFile) getWriterLocked(bailOnRotateFail, forceRotate bool) (out io
 		_ = f.usingFile.Close()
 		f.usingFile = nil
 	}
+	if f.usingFilePath != "" && f.usingFilePath != newName {
+		f.archiveAsyncLocked(f.usingFilePath, f.usingSince)
+	}
 	f.usingFile = newFile
 	f.usingFilePath = newName
 	f.usingSeq = newSeq
+	f.usingSince = time.Now()
 	if f.PostRotateHandler != nil {
 		f.PostRotateHandler(f.usingFilePath)
 	}
@@ -337,7 +398,12 @@ func (f *RotateFile) getWriterLocked(bailOnRotateFail, forceRotate bool) (out io
 func (f *RotateFile) rotateLocked(newName string) (*os.File, error) {
 	var err error
 	// if we got here, then we need to create a file
-	switch f.RotateMode {
+	mode := f.RotateMode
+	if f.usingFilePath == "" || f.usingFilePath == newName {
+		// nothing to copy on startup or reopen
+		mode = RotateModeNew
+	}
+	switch mode {
 	case RotateModeCopyRename:
 		// for which open the file, and write file not by RotateFile
 		// CopyRenameFileAll = RenameFileAll(src->dst) + OpenFile(src)
@@ -446,6 +512,18 @@ func nextSeqFileName(name string, seq int) (string, int) {
 	// A new file has been requested. Instead of just using the
 	// regular strftime pattern, we create a new file name using
 	// generational names such as "foo.1", "foo.2", "foo.3", etc
+	if seq == 0 {
+		// seq 0 is name itself, not "foo.0"
+		nf, err := LockAll(name)
+		if err == nil {
+			_ = nf.Close()
+			return name, 0
+		}
+		if !os.IsExist(err) {
+			return name, 0
+		}
+		seq = 1
+	}
 	nf, seqUsed, err := NextFile(name+".*", seq)
 	if err != nil {
 		return name, seq
@@ -0,0 +1,233 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package os
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Compressor compresses rotated files of RotateFile, such as GzipCompressor.
+type Compressor interface {
+	// Ext returns the extension appended to the compressed file, such as ".gz".
+	Ext() string
+	// NewWriter returns a WriteCloser that compresses data written into w,
+	// Close must be called to flush.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// GzipCompressor compresses in gzip format.
+type GzipCompressor struct {
+	// Level is the compression level, gzip.DefaultCompression if 0, see gzip.NewWriterLevel.
+	Level int
+}
+
+// Ext returns ".gz"
+func (c GzipCompressor) Ext() string { return ".gz" }
+
+// NewWriter returns a gzip writer of w.
+func (c GzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+
+// RotateFileArchiver archives rotated files of RotateFile, such as uploading to a blob bucket.
+type RotateFileArchiver interface {
+	// Archive archives the file named name.
+	Archive(ctx context.Context, name string) error
+}
+
+// RotateFileSegment is a file rotated by RotateFile, recorded in the manifest.
+type RotateFileSegment struct {
+	Name      string    `json:"name"`       // file path of the segment, compressed if Compressor is set
+	Offset    int64     `json:"offset"`     // offset of the first byte of the segment, in all bytes written into segments
+	Size      int64     `json:"size"`       // bytes written into the segment, before compressed
+	StartTime time.Time `json:"start_time"` // time the segment was opened
+	EndTime   time.Time `json:"end_time"`   // time the segment was rotated
+}
+
+// ReadRotateFileManifest returns segments recorded in the manifest named name, in the order rotated.
+// Segments cleaned are removed from the manifest when a segment is recorded next time.
+func ReadRotateFileManifest(name string) ([]RotateFileSegment, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var segments []RotateFileSegment
+	if err := json.Unmarshal(data, &segments); err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
+// archiveAsyncLocked archives the file rotated on a separate goroutine, in the order rotated.
+// archiveAsyncLocked requires that f.mu is held.
+func (f *RotateFile) archiveAsyncLocked(name string, since time.Time) {
+	if f.RotateMode != RotateModeNew || (f.Compressor == nil && f.ManifestPath == "" && f.Archiver == nil) {
+		return
+	}
+	f.archiveMu.Lock()
+	defer f.archiveMu.Unlock()
+	f.archiveQueue = append(f.archiveQueue, RotateFileSegment{Name: name, StartTime: since, EndTime: time.Now()})
+	if f.archiving {
+		return
+	}
+	f.archiving = true
+	f.archiveWg.Add(1)
+	go f.serializedArchive()
+}
+
+// archive files rotated
+// expect run on a separate goroutine
+func (f *RotateFile) serializedArchive() {
+	defer f.archiveWg.Done()
+	for {
+		f.archiveMu.Lock()
+		if len(f.archiveQueue) == 0 {
+			f.archiving = false
+			f.archiveMu.Unlock()
+			return
+		}
+		segment := f.archiveQueue[0]
+		f.archiveQueue = f.archiveQueue[1:]
+		f.archiveMu.Unlock()
+
+		name := segment.Name
+		if err := f.archive(segment); err != nil && f.ArchiveErrorHandler != nil {
+			f.ArchiveErrorHandler(name, err)
+		}
+	}
+}
+
+// archive compresses, records and archives the segment rotated.
+func (f *RotateFile) archive(segment RotateFileSegment) error {
+	fi, err := os.Stat(segment.Name)
+	if err != nil {
+		return err
+	}
+	segment.Size = fi.Size()
+	if f.Compressor != nil {
+		name, err := compressFile(segment.Name, f.Compressor)
+		if err != nil {
+			return err
+		}
+		segment.Name = name
+	}
+
+	if !f.archiveLoaded {
+		f.archiveLoaded = true
+		if f.ManifestPath != "" {
+			segments, err := ReadRotateFileManifest(f.ManifestPath)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			if len(segments) > 0 {
+				last := segments[len(segments)-1]
+				f.archiveOffset = last.Offset + last.Size
+			}
+		}
+	}
+	segment.Offset = f.archiveOffset
+	f.archiveOffset += segment.Size
+
+	if f.ManifestPath != "" {
+		if err := recordRotateFileManifest(f.ManifestPath, segment); err != nil {
+			return err
+		}
+	}
+	if f.Archiver != nil {
+		return f.Archiver.Archive(context.Background(), segment.Name)
+	}
+	return nil
+}
+
+// recordRotateFileManifest appends segment to the manifest named name,
+// and removes segments cleaned already.
+func recordRotateFileManifest(name string, segment RotateFileSegment) error {
+	segments, err := ReadRotateFileManifest(name)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	var kept []RotateFileSegment
+	for _, s := range segments {
+		if _, err := os.Stat(s.Name); err == nil {
+			kept = append(kept, s)
+		}
+	}
+	kept = append(kept, segment)
+	data, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteRenameAll(name, data)
+}
+
+// compressFile compresses the file named name into a new file, named with the Ext of c appended,
+// or with a seq inserted before the Ext if exists already, such as "foo.1.gz", then removes the file.
+// The file is compressed into a hidden temp file first, which matches no RotateFileGlob,
+// so that no reader can find a compressed file unfinished beside the file.
+// compressFile returns the name of the compressed file.
+func compressFile(name string, c Compressor) (string, error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+	if err := compressTo(tmp, src, c); err != nil {
+		_ = tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	// keep mod time, as rotated files are cleaned and sorted by mod time
+	_ = os.Chtimes(tmpName, fi.ModTime(), fi.ModTime())
+
+	dstName := name + c.Ext()
+	for seq := 1; ; seq++ {
+		if _, err := os.Lstat(dstName); os.IsNotExist(err) {
+			break
+		}
+		dstName = fmt.Sprintf("%s.%d%s", name, seq, c.Ext())
+	}
+	if err := os.Rename(tmpName, dstName); err != nil {
+		return "", err
+	}
+	_ = src.Close()
+	return dstName, os.Remove(name)
+}
+
+func compressTo(dst io.Writer, src io.Reader, c Compressor) error {
+	w, err := c.NewWriter(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package os_test
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	os_ "github.com/searKing/golang/go/os"
+)
+
+type archiver struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (a *archiver) Archive(ctx context.Context, name string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.names = append(a.names, name)
+	return nil
+}
+
+func TestRotateFileArchive(t *testing.T) {
+	dir := t.TempDir()
+	var ar archiver
+	file := os_.NewRotateFile("test.2006-01-02.log")
+	file.FilePathPrefix = dir + string(filepath.Separator)
+	file.Compressor = os_.GzipCompressor{}
+	file.ManifestPath = filepath.Join(dir, "manifest.json")
+	file.Archiver = &ar
+	file.ArchiveErrorHandler = func(name string, err error) { t.Errorf("archive %s: %v", name, err) }
+
+	const rotates = 3
+	for i := 0; i <= rotates; i++ {
+		if _, err := file.WriteString(strings.Repeat(fmt.Sprint(i), i+1)); err != nil {
+			t.Fatal(err)
+		}
+		if i < rotates {
+			if err := file.Rotate(true); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	segments, err := os_.ReadRotateFileManifest(file.ManifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != rotates {
+		t.Fatalf("got %d segments, want %d", len(segments), rotates)
+	}
+	var offset int64
+	for i, segment := range segments {
+		if segment.Offset != offset || segment.Size != int64(i+1) {
+			t.Errorf("#%d: segment [%d, +%d), want [%d, +%d)", i, segment.Offset, segment.Size, offset, i+1)
+		}
+		offset += segment.Size
+		if segment.EndTime.Before(segment.StartTime) {
+			t.Errorf("#%d: segment ends at %v before starts at %v", i, segment.EndTime, segment.StartTime)
+		}
+		if !strings.HasSuffix(segment.Name, ".gz") {
+			t.Fatalf("#%d: segment %s is not compressed", i, segment.Name)
+		}
+		if got, want := readGzip(t, segment.Name), strings.Repeat(fmt.Sprint(i), i+1); got != want {
+			t.Errorf("#%d: segment content %q, want %q", i, got, want)
+		}
+		if _, err := os.Stat(strings.TrimSuffix(segment.Name, ".gz")); !os.IsNotExist(err) {
+			t.Errorf("#%d: segment uncompressed is not removed: %v", i, err)
+		}
+		if i >= len(ar.names) || ar.names[i] != segment.Name {
+			t.Errorf("#%d: archived %v, want %s", i, ar.names, segment.Name)
+		}
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(dir, ".*.tmp")); len(tmps) > 0 {
+		t.Errorf("temp files of compression are not removed: %v", tmps)
+	}
+}
+
+func readGzip(t *testing.T, name string) string {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+type blockingArchiver struct{ release chan struct{} }
+
+func (a blockingArchiver) Archive(ctx context.Context, name string) error {
+	<-a.release
+	return nil
+}
+
+func TestRotateFileCloseContext(t *testing.T) {
+	dir := t.TempDir()
+	ar := blockingArchiver{release: make(chan struct{})}
+	file := os_.NewRotateFile("test.2006-01-02.log")
+	file.FilePathPrefix = dir + string(filepath.Separator)
+	file.Archiver = ar
+
+	if _, err := file.WriteString("0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Rotate(true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := file.CloseContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("CloseContext() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	// the file is usable while archiving, as the lock is not held
+	if err := file.Rotate(false); err != nil {
+		t.Fatal(err)
+	}
+	close(ar.release)
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
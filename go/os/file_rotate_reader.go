@@ -0,0 +1,408 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package os
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	filepath_ "github.com/searKing/golang/go/path/filepath"
+)
+
+// DefaultRotateFileFollowInterval is the interval RotateFileReader polls for data appended or files rotated,
+// if FollowInterval is not set.
+const DefaultRotateFileFollowInterval = 200 * time.Millisecond
+
+// RotateFileReader reads files rotated by RotateFile back as one logical stream,
+// files matching RotateFileGlob are read in the order rotated, ordered by the time formatted by
+// FilePathRotateLayout and the seq appended, or by mod time if not formatted by FilePathRotateLayout.
+// Files compressed by Compressor are decompressed if a Decompressor of the Ext is set, gzip is supported by default.
+//
+// In RotateModeCopyRename and RotateModeCopyTruncate, a rotated file is a copy of the file before, so bytes
+// read from the file before are skipped in the rotated one.
+// In Follow mode, a file truncated in place is read again from the beginning, as tail -F does.
+type RotateFileReader struct {
+	RotateMode           RotateMode
+	FilePathPrefix       string // FilePath = FilePathPrefix + now.Format(filePathRotateLayout)
+	FilePathRotateLayout string // Time layout to format rotate file
+	RotateFileGlob       string // file glob to read
+
+	// Follow keeps waiting for data appended and files rotated at the end of the last file, as tail -F does,
+	// instead of returning io.EOF.
+	Follow bool
+	// FollowInterval is the interval to poll for data appended or files rotated,
+	// DefaultRotateFileFollowInterval if not set.
+	FollowInterval time.Duration
+
+	// Decompressors decompress files by Ext, such as ".gz".
+	// take effects if only Decompressors is not nil, or files ends with ".gz" are decompressed by gzip.
+	Decompressors map[string]func(r io.Reader) (io.Reader, error)
+
+	mu        sync.Mutex
+	closeOnce sync.Once
+	closed    chan struct{}
+	seekTime  time.Time // read from the file containing seekTime, if not zero
+	seekEnd   bool      // read from the end of the last file
+
+	cur    *rotateSegment // file reading
+	file   *os.File
+	r      io.Reader // file, or decompressed
+	offset int64     // bytes read from cur
+}
+
+// NewRotateFileReader returns a RotateFileReader reads files rotated by f.
+func NewRotateFileReader(f *RotateFile) *RotateFileReader {
+	return &RotateFileReader{
+		RotateMode:           f.RotateMode,
+		FilePathPrefix:       f.FilePathPrefix,
+		FilePathRotateLayout: f.FilePathRotateLayout,
+		RotateFileGlob:       f.RotateFileGlob,
+	}
+}
+
+// rotateSegment is a file rotated, ordered by (t, seq)
+type rotateSegment struct {
+	name string
+	t    time.Time // time formatted in name, or mod time
+	seq  int       // seq appended to name, as "foo.1"
+	ext  string    // ext of compressed file, as ".gz"
+}
+
+func (s *rotateSegment) before(o *rotateSegment) bool {
+	if !s.t.Equal(o.t) {
+		return s.t.Before(o.t)
+	}
+	return s.seq < o.seq
+}
+
+// Read reads from files rotated in order, see io.Reader.
+// In Follow mode, Read blocks until data is available or Close is called.
+func (r *RotateFileReader) Read(p []byte) (n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	for {
+		if r.isClosed() {
+			return 0, io.EOF
+		}
+		if r.cur == nil {
+			ok, err := r.openFirstLocked()
+			if err != nil {
+				return 0, err
+			}
+			if !ok {
+				if !r.Follow {
+					return 0, io.EOF
+				}
+				r.waitLocked()
+				continue
+			}
+		}
+
+		n, err = r.r.Read(p)
+		r.offset += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		// EOF of the file reading
+		next, err := r.nextSegmentLocked()
+		if err != nil {
+			return 0, err
+		}
+		if next != nil {
+			// drain data appended before rotated
+			n, err = r.r.Read(p)
+			r.offset += int64(n)
+			if n > 0 {
+				return n, nil
+			}
+			if err != nil && err != io.EOF {
+				return 0, err
+			}
+			if err := r.openLocked(next, r.skipOnRotateLocked()); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if !r.Follow {
+			return 0, io.EOF
+		}
+		// truncated in place, read from the beginning
+		if r.r == io.Reader(r.file) {
+			if fi, err := r.file.Stat(); err == nil && fi.Size() < r.offset {
+				if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+					return 0, err
+				}
+				r.offset = 0
+				continue
+			}
+		}
+		r.waitLocked()
+	}
+}
+
+// SeekTime sets the next Read to read from the beginning of the file containing t,
+// that's the last file formatted with a time not after t.
+func (r *RotateFileReader) SeekTime(t time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeFileLocked()
+	r.seekTime = t
+	r.seekEnd = false
+	return nil
+}
+
+// SeekEnd sets the next Read to read from the end of the last file, as tail -f does.
+func (r *RotateFileReader) SeekEnd() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeFileLocked()
+	r.seekTime = time.Time{}
+	r.seekEnd = true
+	return nil
+}
+
+// Close closes the file reading, and unblocks Read in Follow mode.
+func (r *RotateFileReader) Close() error {
+	r.closeOnce.Do(func() {
+		r.mu.Lock()
+		if r.closed == nil {
+			r.closed = make(chan struct{})
+		}
+		close(r.closed)
+		r.mu.Unlock()
+	})
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closeFileLocked()
+}
+
+func (r *RotateFileReader) isClosed() bool {
+	if r.closed == nil {
+		r.closed = make(chan struct{})
+	}
+	select {
+	case <-r.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitLocked waits for FollowInterval, or Close.
+// waitLocked requires that r.mu is held, and releases it during waiting.
+func (r *RotateFileReader) waitLocked() {
+	interval := r.FollowInterval
+	if interval <= 0 {
+		interval = DefaultRotateFileFollowInterval
+	}
+	closed := r.closed
+	r.mu.Unlock()
+	defer r.mu.Lock()
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-closed:
+	}
+}
+
+// openFirstLocked opens the file to read first, seeked by SeekTime or SeekEnd.
+func (r *RotateFileReader) openFirstLocked() (bool, error) {
+	segments, err := r.segments()
+	if err != nil {
+		return false, err
+	}
+	if len(segments) == 0 {
+		return false, nil
+	}
+	first := segments[0]
+	switch {
+	case r.seekEnd:
+		first = segments[len(segments)-1]
+	case !r.seekTime.IsZero():
+		for _, s := range segments {
+			if s.t.After(r.seekTime) {
+				break
+			}
+			// the first one of files formatted with the same time, as "foo", "foo.1", "foo.2"
+			if !s.t.Equal(first.t) {
+				first = s
+			}
+		}
+	}
+	if err := r.openLocked(first, 0); err != nil {
+		return false, err
+	}
+	if r.seekEnd {
+		r.seekEnd = false
+		if r.r == io.Reader(r.file) {
+			r.offset, err = r.file.Seek(0, io.SeekEnd)
+			if err != nil {
+				return false, err
+			}
+		} else {
+			r.offset, err = io.Copy(io.Discard, r.r)
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+	r.seekTime = time.Time{}
+	return true, nil
+}
+
+// skipOnRotateLocked returns bytes to skip in the file rotated next, as it's a copy of the file reading.
+func (r *RotateFileReader) skipOnRotateLocked() int64 {
+	switch r.RotateMode {
+	case RotateModeCopyRename, RotateModeCopyTruncate:
+		return r.offset
+	default:
+		return 0
+	}
+}
+
+// nextSegmentLocked returns the file rotated next to the file reading, nil if not rotated yet.
+func (r *RotateFileReader) nextSegmentLocked() (*rotateSegment, error) {
+	segments, err := r.segments()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range segments {
+		if r.cur.before(s) {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *RotateFileReader) openLocked(s *rotateSegment, skip int64) error {
+	r.closeFileLocked()
+	file, err := os.Open(s.name)
+	if err != nil {
+		return err
+	}
+	var rd io.Reader = file
+	if s.ext != "" {
+		rd, err = r.decompressors()[s.ext](file)
+		if err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	r.cur, r.file, r.r, r.offset = s, file, rd, 0
+	if skip > 0 {
+		r.offset, err = io.CopyN(io.Discard, rd, skip)
+		if err != nil && err != io.EOF {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *RotateFileReader) closeFileLocked() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.cur, r.file, r.r, r.offset = nil, nil, nil, 0
+	return err
+}
+
+func (r *RotateFileReader) decompressors() map[string]func(r io.Reader) (io.Reader, error) {
+	if r.Decompressors != nil {
+		return r.Decompressors
+	}
+	return map[string]func(r io.Reader) (io.Reader, error){
+		GzipCompressor{}.Ext(): func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	}
+}
+
+// segments returns regular files matching RotateFileGlob, in the order rotated.
+func (r *RotateFileReader) segments() ([]*rotateSegment, error) {
+	decompressors := r.decompressors()
+	// names globbed are cleaned
+	var prefix string
+	if r.FilePathPrefix != "" {
+		prefix = filepath.Clean(r.FilePathPrefix)
+		if os.IsPathSeparator(r.FilePathPrefix[len(r.FilePathPrefix)-1]) {
+			prefix += string(filepath.Separator)
+		}
+	}
+	var segments []*rotateSegment
+	_, err := filepath_.GlobFunc(r.FilePathPrefix+r.RotateFileGlob, func(name string) bool {
+		fi, err := os.Lstat(name)
+		if err != nil || !fi.Mode().IsRegular() {
+			return false
+		}
+		s := &rotateSegment{name: name, t: fi.ModTime()}
+		base := strings.TrimPrefix(filepath.Clean(name), prefix)
+		for ext := range decompressors {
+			if strings.HasSuffix(base, ext) {
+				base = strings.TrimSuffix(base, ext)
+				s.ext = ext
+				break
+			}
+		}
+		if t, err := time.ParseInLocation(r.FilePathRotateLayout, base, time.Local); err == nil {
+			s.t = t
+		} else if i := strings.LastIndex(base, "."); i >= 0 {
+			// foo.1
+			if seq, err := strconv.Atoi(base[i+1:]); err == nil {
+				if t, err := time.ParseInLocation(r.FilePathRotateLayout, base[:i], time.Local); err == nil {
+					s.t, s.seq = t, seq
+				}
+			}
+		}
+		segments = append(segments, s)
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	segments = dropCompressedTwins(segments)
+	sort.SliceStable(segments, func(i, j int) bool { return segments[i].before(segments[j]) })
+	return segments, nil
+}
+
+// dropCompressedTwins drops the uncompressed segments with a compressed sibling of the same (t, seq),
+// as the file is removed only after it has been compressed.
+func dropCompressedTwins(segments []*rotateSegment) []*rotateSegment {
+	type key struct {
+		t   int64
+		seq int
+	}
+	compressed := make(map[key]bool)
+	for _, s := range segments {
+		if s.ext != "" {
+			compressed[key{s.t.UnixNano(), s.seq}] = true
+		}
+	}
+	if len(compressed) == 0 {
+		return segments
+	}
+	kept := segments[:0]
+	for _, s := range segments {
+		if s.ext == "" && compressed[key{s.t.UnixNano(), s.seq}] {
+			continue
+		}
+		kept = append(kept, s)
+	}
+	return kept
+}
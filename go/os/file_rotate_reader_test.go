@@ -0,0 +1,208 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package os_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	os_ "github.com/searKing/golang/go/os"
+)
+
+func newTestRotateFile(t *testing.T, mode os_.RotateMode) *os_.RotateFile {
+	file := os_.NewRotateFile("test.2006-01-02.log")
+	file.FilePathPrefix = t.TempDir() + string(filepath.Separator)
+	file.RotateMode = mode
+	t.Cleanup(func() { _ = file.Close() })
+	return file
+}
+
+func writeRotate(t *testing.T, file *os_.RotateFile, s string, rotate bool) {
+	t.Helper()
+	if _, err := file.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+	if rotate {
+		if err := file.Rotate(true); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readFollow reads from r until want read or timeout
+func readFollow(t *testing.T, r io.Reader, want string) {
+	t.Helper()
+	var got bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		for got.Len() < len(want) {
+			n, err := r.Read(buf)
+			got.Write(buf[:n])
+			if err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("read %q: %v", got.String(), err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("read timeout, want %q", want)
+	}
+	if got.String() != want {
+		t.Fatalf("read %q, want %q", got.String(), want)
+	}
+}
+
+func TestRotateFileReader(t *testing.T) {
+	file := newTestRotateFile(t, os_.RotateModeNew)
+	file.Compressor = os_.GzipCompressor{}
+	writeRotate(t, file, "0", true)
+	writeRotate(t, file, "11", true)
+	writeRotate(t, file, "222", true)
+	writeRotate(t, file, "3333", false)
+	if err := file.Close(); err != nil { // wait for compression
+		t.Fatal(err)
+	}
+
+	r := os_.NewRotateFileReader(file)
+	defer r.Close()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0112223333"; string(got) != want {
+		t.Errorf("read %q, want %q", got, want)
+	}
+}
+
+func TestRotateFileReaderFollow(t *testing.T) {
+	for _, mode := range []os_.RotateMode{os_.RotateModeNew, os_.RotateModeCopyRename, os_.RotateModeCopyTruncate} {
+		file := newTestRotateFile(t, mode)
+		writeRotate(t, file, "a", false)
+
+		r := os_.NewRotateFileReader(file)
+		r.Follow = true
+		r.FollowInterval = time.Millisecond
+		readFollow(t, r, "a")
+
+		writeRotate(t, file, "b", true)
+		writeRotate(t, file, "c", false)
+		readFollow(t, r, "bc")
+		writeRotate(t, file, "d", true)
+		writeRotate(t, file, "e", false)
+		readFollow(t, r, "de")
+
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := r.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+			t.Errorf("mode %d: Read after Close = %d, %v, want 0, EOF", mode, n, err)
+		}
+	}
+}
+
+func TestRotateFileReaderTruncatedInPlace(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(name, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := &os_.RotateFileReader{FilePathPrefix: dir + string(filepath.Separator), RotateFileGlob: "*.log", Follow: true, FollowInterval: time.Millisecond}
+	defer r.Close()
+	readFollow(t, r, "abc")
+	if err := os.WriteFile(name, []byte("d"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	readFollow(t, r, "d")
+}
+
+func TestRotateFileReaderCompressing(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "test.2023-01-02.log")
+	// the file is removed only after the compressed one is renamed into place
+	if err := os.WriteFile(name, []byte("0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name+".gz", gzipBytes(t, "0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name+".1", []byte("11"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var decompressed int
+	r := &os_.RotateFileReader{FilePathPrefix: dir + string(filepath.Separator), FilePathRotateLayout: "test.2006-01-02.log", RotateFileGlob: "test.*"}
+	r.Decompressors = map[string]func(r io.Reader) (io.Reader, error){
+		".gz": func(r io.Reader) (io.Reader, error) {
+			decompressed++
+			return gzip.NewReader(r)
+		},
+	}
+	defer r.Close()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "011"; string(got) != want {
+		t.Errorf("read %q, want %q", got, want)
+	}
+	if decompressed != 1 {
+		t.Errorf("decompressed %d files, want 1, the compressed twin read instead of the file removed later", decompressed)
+	}
+}
+
+func gzipBytes(t *testing.T, s string) []byte {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestRotateFileReader_SeekTime(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"app.2023-01-01T10":    "10",
+		"app.2023-01-01T11":    "11",
+		"app.2023-01-01T12":    "12",
+		"app.2023-01-01T11.1":  "11.1",
+		"app.2023-01-01T12.gz": "", // undecodable, but skipped
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := &os_.RotateFileReader{
+		FilePathPrefix:       dir + string(filepath.Separator) + "app.",
+		FilePathRotateLayout: "2006-01-02T15",
+		RotateFileGlob:       "*",
+		Decompressors:        map[string]func(r io.Reader) (io.Reader, error){},
+	}
+	defer r.Close()
+	if err := r.SeekTime(time.Date(2023, 1, 1, 11, 30, 0, 0, time.Local)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1111.112"; string(got) != want {
+		t.Errorf("read %q, want %q", got, want)
+	}
+}
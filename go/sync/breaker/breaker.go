@@ -0,0 +1,329 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package breaker implements the Circuit Breaker pattern, which fails fast the calls to a dependency
+// failing, instead of hammering it by every caller.
+//
+// A Breaker is closed at first, and trips to open once the counts of requests in a rolling window are ready to trip.
+// An open Breaker rejects all requests until the open timeout elapsed, then turns half-open, and lets
+// limited requests through as trials, closed again if all succeed, or open again if any fails.
+// See: https://learn.microsoft.com/en-us/azure/architecture/patterns/circuit-breaker
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrOpenState is returned when the Breaker is open.
+	ErrOpenState = errors.New("breaker: circuit breaker is open")
+	// ErrTooManyRequests is returned when the Breaker is half-open and the trial requests are exhausted.
+	ErrTooManyRequests = errors.New("breaker: too many requests in half-open state")
+)
+
+const (
+	// DefaultWindow is the length of the rolling window to count requests, if not set.
+	DefaultWindow = 10 * time.Second
+	// DefaultWindowBuckets is the number of buckets the rolling window is split into, if not set.
+	DefaultWindowBuckets = 10
+	// DefaultOpenTimeout is the duration of the open state, if not set.
+	DefaultOpenTimeout = 60 * time.Second
+	// DefaultHalfOpenMaxRequests is the number of trial requests in the half-open state, if not set.
+	DefaultHalfOpenMaxRequests = 1
+)
+
+// State is the state of a Breaker.
+type State int
+
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	default:
+		return fmt.Sprintf("unknown state: %d", s)
+	}
+}
+
+// Counts holds the numbers of requests done in the rolling window, and the consecutive ones.
+type Counts struct {
+	Requests             uint32
+	Successes            uint32
+	Failures             uint32
+	ConsecutiveSuccesses uint32
+	ConsecutiveFailures  uint32
+}
+
+// FailureRate returns the rate of failures in requests done, 0 if no request.
+func (c Counts) FailureRate() float64 {
+	if c.Requests == 0 {
+		return 0
+	}
+	return float64(c.Failures) / float64(c.Requests)
+}
+
+// ReadyToTripByFailureRate returns a ReadyToTrip trips once failures rate reaches rate,
+// with minRequests done at least in the rolling window.
+func ReadyToTripByFailureRate(minRequests uint32, rate float64) func(counts Counts) bool {
+	return func(counts Counts) bool {
+		return counts.Requests >= minRequests && counts.FailureRate() >= rate
+	}
+}
+
+// ReadyToTripByConsecutiveFailures returns a ReadyToTrip trips once n failures consecutively.
+func ReadyToTripByConsecutiveFailures(n uint32) func(counts Counts) bool {
+	return func(counts Counts) bool {
+		return counts.ConsecutiveFailures >= n
+	}
+}
+
+// DefaultReadyToTrip trips once half of at least 10 requests failed in the rolling window,
+// or 5 requests failed consecutively.
+func DefaultReadyToTrip(counts Counts) bool {
+	return ReadyToTripByFailureRate(10, 0.5)(counts) || ReadyToTripByConsecutiveFailures(5)(counts)
+}
+
+// Breaker is a circuit breaker, safe for concurrent use.
+type Breaker struct {
+	name                string
+	window              time.Duration
+	windowBuckets       int
+	openTimeout         time.Duration
+	halfOpenMaxRequests uint32
+	readyToTrip         func(counts Counts) bool
+	onStateChange       func(name string, from, to State)
+
+	mu         sync.Mutex
+	state      State
+	generation uint64 // increased on every state change, to ignore requests done of the previous state
+	expiry     time.Time
+	counts     Counts
+	buckets    []bucket
+	halfOpen   uint32 // trial requests in flight or done in the half-open state
+
+	changes   []stateChange // state changes to be reported to onStateChange
+	reporting bool          // changes are being reported to onStateChange, by one goroutine
+}
+
+type stateChange struct{ from, to State }
+
+type bucket struct {
+	slot      int64 // index of time slot of width window/windowBuckets since the unix epoch
+	requests  uint32
+	successes uint32
+	failures  uint32
+}
+
+// New returns a Breaker named name, closed at first.
+func New(name string, opts ...Option) *Breaker {
+	b := &Breaker{
+		name:                name,
+		window:              DefaultWindow,
+		windowBuckets:       DefaultWindowBuckets,
+		openTimeout:         DefaultOpenTimeout,
+		halfOpenMaxRequests: DefaultHalfOpenMaxRequests,
+		readyToTrip:         DefaultReadyToTrip,
+	}
+	b.ApplyOptions(opts...)
+	b.buckets = make([]bucket, b.windowBuckets)
+	return b
+}
+
+// ApplyOptions call apply() for all options one by one
+func (b *Breaker) ApplyOptions(options ...Option) *Breaker {
+	for _, opt := range options {
+		if opt == nil {
+			continue
+		}
+		opt.apply(b)
+	}
+	return b
+}
+
+// Name returns the name of the Breaker.
+func (b *Breaker) Name() string { return b.name }
+
+// State returns the current state of the Breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.unlock()
+	return b.currentStateLocked(time.Now())
+}
+
+// Counts returns the counts of the current state of the Breaker.
+func (b *Breaker) Counts() Counts {
+	b.mu.Lock()
+	defer b.unlock()
+	now := time.Now()
+	b.currentStateLocked(now)
+	return b.countsLocked(now)
+}
+
+// Allow checks whether a request can proceed, ErrOpenState or ErrTooManyRequests returned if not.
+// If allowed, done must be called once the request is done, reporting whether it succeeded.
+func (b *Breaker) Allow() (done func(success bool), err error) {
+	b.mu.Lock()
+	defer b.unlock()
+	now := time.Now()
+	state := b.currentStateLocked(now)
+	switch state {
+	case StateOpen:
+		return nil, ErrOpenState
+	case StateHalfOpen:
+		if b.halfOpen >= b.halfOpenMaxRequests {
+			return nil, ErrTooManyRequests
+		}
+		b.halfOpen++
+	}
+	generation := b.generation
+	var once sync.Once
+	return func(success bool) {
+		once.Do(func() { b.done(generation, success) })
+	}, nil
+}
+
+// Execute runs fn if the Breaker allows, and fn failed if returned a non-nil error.
+func (b *Breaker) Execute(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	success := false
+	defer func() { done(success) }()
+	err = fn()
+	success = err == nil
+	return err
+}
+
+func (b *Breaker) done(generation uint64, success bool) {
+	b.mu.Lock()
+	defer b.unlock()
+	now := time.Now()
+	state := b.currentStateLocked(now)
+	if generation != b.generation {
+		return
+	}
+	b.record(now, success)
+	switch state {
+	case StateClosed:
+		if !success && b.readyToTrip(b.countsLocked(now)) {
+			b.setStateLocked(StateOpen, now)
+		}
+	case StateHalfOpen:
+		if !success {
+			b.setStateLocked(StateOpen, now)
+		} else if b.counts.ConsecutiveSuccesses >= b.halfOpenMaxRequests {
+			b.setStateLocked(StateClosed, now)
+		}
+	}
+}
+
+func (b *Breaker) record(now time.Time, success bool) {
+	bk := b.bucketLocked(now)
+	bk.requests++
+	if success {
+		bk.successes++
+		b.counts.ConsecutiveSuccesses++
+		b.counts.ConsecutiveFailures = 0
+	} else {
+		bk.failures++
+		b.counts.ConsecutiveFailures++
+		b.counts.ConsecutiveSuccesses = 0
+	}
+}
+
+func (b *Breaker) slot(now time.Time) int64 {
+	width := b.window / time.Duration(len(b.buckets))
+	if width <= 0 {
+		width = 1
+	}
+	return now.UnixNano() / int64(width)
+}
+
+func (b *Breaker) bucketLocked(now time.Time) *bucket {
+	slot := b.slot(now)
+	bk := &b.buckets[int(slot%int64(len(b.buckets)))]
+	if bk.slot != slot {
+		*bk = bucket{slot: slot}
+	}
+	return bk
+}
+
+// countsLocked sums up buckets in the rolling window.
+func (b *Breaker) countsLocked(now time.Time) Counts {
+	counts := b.counts
+	counts.Requests, counts.Successes, counts.Failures = 0, 0, 0
+	slot := b.slot(now)
+	for _, bk := range b.buckets {
+		if bk.slot <= slot-int64(len(b.buckets)) || bk.slot > slot {
+			continue
+		}
+		counts.Requests += bk.requests
+		counts.Successes += bk.successes
+		counts.Failures += bk.failures
+	}
+	return counts
+}
+
+// currentStateLocked turns the open state to the half-open once expired.
+func (b *Breaker) currentStateLocked(now time.Time) State {
+	if b.state == StateOpen && !now.Before(b.expiry) {
+		b.setStateLocked(StateHalfOpen, now)
+	}
+	return b.state
+}
+
+func (b *Breaker) setStateLocked(state State, now time.Time) {
+	if b.state == state {
+		return
+	}
+	prev := b.state
+	b.state = state
+	b.generation++
+	b.counts = Counts{}
+	for i := range b.buckets {
+		b.buckets[i] = bucket{}
+	}
+	b.halfOpen = 0
+	b.expiry = time.Time{}
+	if state == StateOpen {
+		b.expiry = now.Add(b.openTimeout)
+	}
+	if b.onStateChange != nil {
+		b.changes = append(b.changes, stateChange{from: prev, to: state})
+	}
+}
+
+// unlock unlocks b.mu, then reports state changes to onStateChange in order, without the lock held,
+// so that onStateChange can call methods of the Breaker.
+func (b *Breaker) unlock() {
+	if b.reporting || len(b.changes) == 0 {
+		b.mu.Unlock()
+		return
+	}
+	b.reporting = true
+	for len(b.changes) > 0 {
+		changes := b.changes
+		b.changes = nil
+		b.mu.Unlock()
+		for _, c := range changes {
+			b.onStateChange(b.name, c.from, c.to)
+		}
+		b.mu.Lock()
+	}
+	b.reporting = false
+	b.mu.Unlock()
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package breaker
+
+import "time"
+
+// An Option sets options.
+type Option interface {
+	apply(*Breaker)
+}
+
+// OptionFunc wraps a function that modifies Breaker into an
+// implementation of the Option interface.
+type OptionFunc func(*Breaker)
+
+func (f OptionFunc) apply(do *Breaker) {
+	f(do)
+}
+
+// WithWindow counts requests in a rolling window of length d, split into buckets,
+// DefaultWindow and DefaultWindowBuckets if not positive.
+func WithWindow(d time.Duration, buckets int) Option {
+	return OptionFunc(func(b *Breaker) {
+		if d > 0 {
+			b.window = d
+		}
+		if buckets > 0 {
+			b.windowBuckets = buckets
+		}
+	})
+}
+
+// WithOpenTimeout turns an open Breaker into half-open after d, DefaultOpenTimeout if not positive.
+func WithOpenTimeout(d time.Duration) Option {
+	return OptionFunc(func(b *Breaker) {
+		if d > 0 {
+			b.openTimeout = d
+		}
+	})
+}
+
+// WithHalfOpenMaxRequests lets n trial requests through in the half-open state,
+// closed once all succeeded, DefaultHalfOpenMaxRequests if zero.
+func WithHalfOpenMaxRequests(n uint32) Option {
+	return OptionFunc(func(b *Breaker) {
+		if n > 0 {
+			b.halfOpenMaxRequests = n
+		}
+	})
+}
+
+// WithReadyToTrip trips a closed Breaker to open if readyToTrip returns true,
+// called with counts in the rolling window once a request failed.
+// DefaultReadyToTrip if nil.
+func WithReadyToTrip(readyToTrip func(counts Counts) bool) Option {
+	return OptionFunc(func(b *Breaker) {
+		if readyToTrip != nil {
+			b.readyToTrip = readyToTrip
+		}
+	})
+}
+
+// WithOnStateChange calls f every time the state of a Breaker changes, in order and one at a time,
+// without the lock of the Breaker held, so f can call methods of the Breaker.
+func WithOnStateChange(f func(name string, from, to State)) Option {
+	return OptionFunc(func(b *Breaker) {
+		b.onStateChange = f
+	})
+}
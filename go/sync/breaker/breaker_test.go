@@ -0,0 +1,141 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package breaker_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/sync/breaker"
+)
+
+var errFailed = errors.New("failed")
+
+func TestBreaker(t *testing.T) {
+	var changes []string
+	b := breaker.New("test",
+		breaker.WithReadyToTrip(breaker.ReadyToTripByConsecutiveFailures(3)),
+		breaker.WithOpenTimeout(50*time.Millisecond),
+		breaker.WithHalfOpenMaxRequests(2),
+		breaker.WithOnStateChange(func(name string, from, to breaker.State) {
+			changes = append(changes, from.String()+"->"+to.String())
+		}))
+
+	for i := 0; i < 3; i++ {
+		if got := b.State(); got != breaker.StateClosed {
+			t.Fatalf("#%d: state %s, want %s", i, got, breaker.StateClosed)
+		}
+		_ = b.Execute(func() error { return errFailed })
+	}
+	if got := b.State(); got != breaker.StateOpen {
+		t.Fatalf("state %s, want %s", got, breaker.StateOpen)
+	}
+	if err := b.Execute(func() error { return nil }); !errors.Is(err, breaker.ErrOpenState) {
+		t.Fatalf("open, got %v, want %v", err, breaker.ErrOpenState)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if got := b.State(); got != breaker.StateHalfOpen {
+		t.Fatalf("state %s, want %s", got, breaker.StateHalfOpen)
+	}
+	done1, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done2, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, breaker.ErrTooManyRequests) {
+		t.Fatalf("half-open, got %v, want %v", err, breaker.ErrTooManyRequests)
+	}
+	done1(true)
+	done2(true)
+	if got := b.State(); got != breaker.StateClosed {
+		t.Fatalf("state %s, want %s", got, breaker.StateClosed)
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if len(changes) != len(want) {
+		t.Fatalf("state changes %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("state changes %v, want %v", changes, want)
+		}
+	}
+}
+
+func TestBreaker_HalfOpenFailure(t *testing.T) {
+	b := breaker.New("test",
+		breaker.WithReadyToTrip(breaker.ReadyToTripByConsecutiveFailures(1)),
+		breaker.WithOpenTimeout(20*time.Millisecond))
+	_ = b.Execute(func() error { return errFailed })
+	time.Sleep(30 * time.Millisecond)
+	_ = b.Execute(func() error { return errFailed })
+	if got := b.State(); got != breaker.StateOpen {
+		t.Fatalf("state %s, want %s", got, breaker.StateOpen)
+	}
+}
+
+func TestBreaker_RollingWindow(t *testing.T) {
+	b := breaker.New("test",
+		breaker.WithReadyToTrip(breaker.ReadyToTripByFailureRate(4, 0.5)),
+		breaker.WithWindow(100*time.Millisecond, 4))
+	_ = b.Execute(func() error { return errFailed })
+	_ = b.Execute(func() error { return errFailed })
+	// failures slide out of the window
+	time.Sleep(150 * time.Millisecond)
+	if got := b.Counts().Requests; got != 0 {
+		t.Fatalf("requests in window %d, want %d", got, 0)
+	}
+	_ = b.Execute(func() error { return nil })
+	_ = b.Execute(func() error { return nil })
+	_ = b.Execute(func() error { return errFailed })
+	if got := b.State(); got != breaker.StateClosed {
+		t.Fatalf("state %s, want %s", got, breaker.StateClosed)
+	}
+	_ = b.Execute(func() error { return errFailed })
+	if got := b.State(); got != breaker.StateOpen {
+		t.Fatalf("state %s, want %s", got, breaker.StateOpen)
+	}
+}
+
+func TestBreaker_OnStateChangeUnlocked(t *testing.T) {
+	var b *breaker.Breaker
+	var changes []string
+	b = breaker.New("test",
+		breaker.WithReadyToTrip(breaker.ReadyToTripByConsecutiveFailures(1)),
+		breaker.WithOpenTimeout(time.Millisecond),
+		breaker.WithOnStateChange(func(name string, from, to breaker.State) {
+			// the lock of b is not held
+			changes = append(changes, from.String()+"->"+to.String()+"="+b.State().String())
+		}))
+	_ = b.Execute(func() error { return errFailed })
+	time.Sleep(2 * time.Millisecond)
+	if got := b.State(); got != breaker.StateHalfOpen {
+		t.Fatalf("state %s, want %s", got, breaker.StateHalfOpen)
+	}
+	want := []string{"closed->open=open", "open->half-open=half-open"}
+	if len(changes) != len(want) || changes[0] != want[0] || changes[1] != want[1] {
+		t.Errorf("changes %v, want %v", changes, want)
+	}
+}
+
+func TestGroup(t *testing.T) {
+	g := breaker.NewGroup(breaker.WithReadyToTrip(breaker.ReadyToTripByConsecutiveFailures(1)))
+	done, err := g.Allow("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done(false)
+	if _, err := g.Allow("a"); !errors.Is(err, breaker.ErrOpenState) {
+		t.Errorf("a, got %v, want %v", err, breaker.ErrOpenState)
+	}
+	if _, err := g.Allow("b"); err != nil {
+		t.Errorf("b, got %v, want nil", err)
+	}
+}
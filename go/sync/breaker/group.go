@@ -0,0 +1,48 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package breaker
+
+import "sync"
+
+// Group is a set of Breakers keyed, as per host or per method,
+// the Breaker of a key is created on first use, with the same options.
+type Group struct {
+	opts []Option
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewGroup returns a Group creating Breakers with opts.
+func NewGroup(opts ...Option) *Group {
+	return &Group{opts: opts, breakers: make(map[string]*Breaker)}
+}
+
+// Get returns the Breaker named key, created if not exist.
+func (g *Group) Get(key string) *Breaker {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.breakers == nil {
+		g.breakers = make(map[string]*Breaker)
+	}
+	b, ok := g.breakers[key]
+	if !ok {
+		b = New(key, g.opts...)
+		g.breakers[key] = b
+	}
+	return b
+}
+
+// Allow checks whether a request of key can proceed, as Breaker.Allow.
+func (g *Group) Allow(key string) (done func(success bool), err error) {
+	return g.Get(key).Allow()
+}
+
+// Remove forgets the Breaker named key.
+func (g *Group) Remove(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.breakers, key)
+}
@@ -0,0 +1,154 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package leaderelection
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	os_ "github.com/searKing/golang/go/os"
+)
+
+// ErrRecordNotFound is returned by a ResourceLocker's Get when no Record has been created yet.
+var ErrRecordNotFound = errors.New("leaderelection: record not found")
+
+// ErrRecordConflict is returned by a ResourceLocker's Create or Update when the Record
+// has been created or modified by another candidate since it was last observed.
+var ErrRecordConflict = errors.New("leaderelection: record modified concurrently")
+
+// NewFileLock returns a locker which serializes the Record into the file named path.
+// Every access to the file is guarded by an advisory lock (flock on unix, LockFileEx on windows),
+// so that processes on the same host can elect a leader among themselves.
+func NewFileLock(path string, identity string) *FileLock {
+	return &FileLock{path: path, identity: identity}
+}
+
+// FileLock is a ResourceLocker backed by a locked file.
+// Update succeeds only if the file content is still the one observed by the last Get,
+// Create or Update of this locker.
+type FileLock struct {
+	// EventRecorder is an optional callback to record events, RecordEvent is a noop if nil.
+	EventRecorder func(name, event string)
+
+	path     string
+	identity string
+
+	mu       sync.Mutex // guards observed
+	observed []byte     // raw record observed by last Get, Create or Update
+}
+
+// Get returns the Record stored in the file.
+// ErrRecordNotFound is returned if the file does not exist or is empty.
+func (fl *FileLock) Get(ctx context.Context) (record *Record, rawRecord []byte, err error) {
+	err = fl.withLockedFile(func(f *os.File) error {
+		rawRecord, err = io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		if len(rawRecord) == 0 {
+			return ErrRecordNotFound
+		}
+		var ler Record
+		if err := json.Unmarshal(rawRecord, &ler); err != nil {
+			return fmt.Errorf("leaderelection: malformed record in %s: %w", fl.path, err)
+		}
+		record = &ler
+		fl.setObserved(rawRecord)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return record, rawRecord, nil
+}
+
+// Create attempts to create the Record, ErrRecordConflict is returned if a Record exists already.
+func (fl *FileLock) Create(ctx context.Context, ler Record) error {
+	rawRecord, err := json.Marshal(ler)
+	if err != nil {
+		return err
+	}
+	return fl.withLockedFile(func(f *os.File) error {
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if fi.Size() > 0 {
+			return ErrRecordConflict
+		}
+		if err := os_.ReplaceFile(f, rawRecord); err != nil {
+			return err
+		}
+		fl.setObserved(rawRecord)
+		return nil
+	})
+}
+
+// Update will update an existing Record, ErrRecordConflict is returned
+// if the Record has been changed since last observed.
+func (fl *FileLock) Update(ctx context.Context, ler Record) error {
+	rawRecord, err := json.Marshal(ler)
+	if err != nil {
+		return err
+	}
+	return fl.withLockedFile(func(f *os.File) error {
+		current, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		if len(current) == 0 {
+			return ErrRecordNotFound
+		}
+		if !bytes.Equal(current, fl.getObserved()) {
+			return ErrRecordConflict
+		}
+		if err := os_.ReplaceFile(f, rawRecord); err != nil {
+			return err
+		}
+		fl.setObserved(rawRecord)
+		return nil
+	})
+}
+
+// RecordEvent is used to record events
+func (fl *FileLock) RecordEvent(name, event string) {
+	if fl.EventRecorder != nil {
+		fl.EventRecorder(name, event)
+	}
+}
+
+// Identity returns the Identity of the lock
+func (fl *FileLock) Identity() string {
+	return fl.identity
+}
+
+// Describe is used to convert details on current resource lock
+// into a string
+func (fl *FileLock) Describe() string {
+	return fmt.Sprintf("file://%s", fl.path)
+}
+
+// withLockedFile opens the file, holds an exclusive lock on it and calls f.
+func (fl *FileLock) withLockedFile(f func(f *os.File) error) error {
+	return os_.WithLockedFile(fl.path, f)
+}
+
+func (fl *FileLock) getObserved() []byte {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	return fl.observed
+}
+
+func (fl *FileLock) setObserved(rawRecord []byte) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	fl.observed = rawRecord
+}
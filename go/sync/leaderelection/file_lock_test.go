@@ -0,0 +1,54 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package leaderelection_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/sync/leaderelection"
+)
+
+func TestFileLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	a := leaderelection.NewFileLock(path, "a")
+	b := leaderelection.NewFileLock(path, "b")
+	ctx := context.Background()
+
+	if _, _, err := a.Get(ctx); !errors.Is(err, leaderelection.ErrRecordNotFound) {
+		t.Fatalf("Get on empty file: got %v, want %v", err, leaderelection.ErrRecordNotFound)
+	}
+	now := time.Now()
+	if err := a.Create(ctx, leaderelection.Record{HolderIdentity: "a", LeaseDuration: time.Second, AcquireTime: now, RenewTime: now}); err != nil {
+		t.Fatalf("Create: %s", err)
+	}
+	if err := b.Create(ctx, leaderelection.Record{HolderIdentity: "b"}); !errors.Is(err, leaderelection.ErrRecordConflict) {
+		t.Fatalf("Create twice: got %v, want %v", err, leaderelection.ErrRecordConflict)
+	}
+	record, _, err := b.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get: %s", err)
+	}
+	if record.HolderIdentity != "a" || !record.AcquireTime.Equal(now) {
+		t.Fatalf("Get: got %+v", record)
+	}
+	// a renews, b's view of the record is outdated then.
+	if err := a.Update(ctx, leaderelection.Record{HolderIdentity: "a", RenewTime: time.Now()}); err != nil {
+		t.Fatalf("Update: %s", err)
+	}
+	if err := b.Update(ctx, leaderelection.Record{HolderIdentity: "b", LeaderTransitions: 1}); !errors.Is(err, leaderelection.ErrRecordConflict) {
+		t.Fatalf("Update outdated: got %v, want %v", err, leaderelection.ErrRecordConflict)
+	}
+}
+
+func TestFileLock_ConcurrentElectors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	testConcurrentElectors(t, func(identity string) leaderelection.ResourceLocker {
+		return leaderelection.NewFileLock(path, identity)
+	})
+}
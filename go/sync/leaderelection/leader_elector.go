@@ -180,7 +180,11 @@ func (le *LeaderElector) acquire(ctx context.Context) bool {
 		le.config.Lock.RecordEvent(le.config.Name, EventBecameLeader)
 		le.logf("successfully acquired lease %v", desc)
 		cancel()
-	}, true, time_.WithExponentialBackOffOptionRandomizationFactor(JitterFactor))
+	}, true,
+		time_.WithExponentialBackOffOptionInitialInterval(le.config.RetryPeriod),
+		time_.WithExponentialBackOffOptionMultiplier(1),
+		time_.WithExponentialBackOffOptionMaxElapsedDuration(-1),
+		time_.WithExponentialBackOffOptionRandomizationFactor(JitterFactor))
 	return succeeded
 }
 
@@ -198,7 +202,7 @@ func (le *LeaderElector) renew(ctx context.Context) {
 				leader = true
 				timeoutCancel()
 			}
-		}, le.config.RenewTimeout)
+		}, le.config.RetryPeriod)
 		// leader elector finished
 
 		// maybe report leader changed
@@ -355,7 +359,15 @@ func (le *LeaderElector) getObservedRecord() Record {
 }
 
 // observedRecordExpired returns true if observersRecord expired.
+// The LeaseDuration carried by the record takes precedence over the configured one,
+// so that a lease released with a short LeaseDuration can be taken over soon.
 // Protect critical sections with lock.
 func (le *LeaderElector) observedRecordExpired(now time.Time) bool {
-	return le.observedTime.Add(le.config.LeaseDuration).After(now)
+	le.observedRecordLock.Lock()
+	defer le.observedRecordLock.Unlock()
+	leaseDuration := le.observedRecord.LeaseDuration
+	if leaseDuration <= 0 {
+		leaseDuration = le.config.LeaseDuration
+	}
+	return !le.observedTime.Add(leaseDuration).After(now)
 }
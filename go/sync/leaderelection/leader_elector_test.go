@@ -0,0 +1,141 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package leaderelection_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/sync/leaderelection"
+)
+
+// testConcurrentElectors runs several electors against locks returned by newLock, and checks that
+// there is at most one leader at any time, and that leadership moves on once the leader is gone.
+func testConcurrentElectors(t *testing.T, newLock func(identity string) leaderelection.ResourceLocker) {
+	const n = 3
+	var leading int32
+	var overlapped int32
+
+	type candidate struct {
+		identity string
+		lock     leaderelection.ResourceLocker
+		elector  *leaderelection.LeaderElector
+		cancel   context.CancelFunc
+		done     chan struct{}
+	}
+	var candidates []*candidate
+	for i := 0; i < n; i++ {
+		identity := fmt.Sprintf("candidate-%d", i)
+		lock := newLock(identity)
+		le, err := leaderelection.NewLeaderElector(leaderelection.Config{
+			Lock:          lock,
+			LeaseDuration: time.Second,
+			RenewTimeout:  500 * time.Millisecond,
+			RetryPeriod:   100 * time.Millisecond,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) {
+					if atomic.AddInt32(&leading, 1) > 1 {
+						atomic.StoreInt32(&overlapped, 1)
+					}
+				},
+			},
+			Name: identity,
+		})
+		if err != nil {
+			t.Fatalf("NewLeaderElector: %s", err)
+		}
+		le.ErrorLog = log.New(io.Discard, "", 0)
+		c := &candidate{identity: identity, lock: lock, elector: le, done: make(chan struct{})}
+		candidates = append(candidates, c)
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range candidates {
+		c := c
+		ctx, cancel := context.WithCancel(context.Background())
+		c.cancel = cancel
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(c.done)
+			c.elector.Run(ctx)
+			if c.elector.IsLeader() {
+				atomic.AddInt32(&leading, -1)
+			}
+		}()
+	}
+	defer func() {
+		for _, c := range candidates {
+			c.cancel()
+		}
+		wg.Wait()
+	}()
+
+	waitLeader := func(exclude string) *candidate {
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			var leaders []*candidate
+			for _, c := range candidates {
+				if c.identity != exclude && c.elector.IsLeader() {
+					leaders = append(leaders, c)
+				}
+			}
+			if len(leaders) > 1 {
+				t.Fatalf("got %d leaders at the same time", len(leaders))
+			}
+			if len(leaders) == 1 {
+				return leaders[0]
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		t.Fatalf("no leader elected in time")
+		return nil
+	}
+
+	first := waitLeader("")
+	record, _, err := first.lock.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get: %s", err)
+	}
+	if record.HolderIdentity != first.identity {
+		t.Fatalf("HolderIdentity = %q, want %q", record.HolderIdentity, first.identity)
+	}
+	transitions := record.LeaderTransitions
+
+	// stop the leader without releasing the lease, followers take over once the lease expired.
+	stoppedAt := time.Now()
+	first.cancel()
+	<-first.done
+
+	second := waitLeader(first.identity)
+	if elapsed := time.Since(stoppedAt); elapsed < 500*time.Millisecond {
+		t.Errorf("leadership taken over after %s, before the lease expired", elapsed)
+	}
+	record, _, err = second.lock.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get: %s", err)
+	}
+	if record.HolderIdentity != second.identity {
+		t.Errorf("HolderIdentity = %q, want %q", record.HolderIdentity, second.identity)
+	}
+	if record.LeaderTransitions != transitions+1 {
+		t.Errorf("LeaderTransitions = %d, want %d", record.LeaderTransitions, transitions+1)
+	}
+	if !record.RenewTime.After(stoppedAt) {
+		t.Errorf("RenewTime %s is not renewed after %s", record.RenewTime, stoppedAt)
+	}
+	if record.LeaseDuration != time.Second {
+		t.Errorf("LeaseDuration = %s, want %s", record.LeaseDuration, time.Second)
+	}
+	if atomic.LoadInt32(&overlapped) != 0 {
+		t.Errorf("more than one candidate started leading at the same time")
+	}
+}
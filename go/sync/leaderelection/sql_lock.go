@@ -0,0 +1,161 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package leaderelection
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SQLLockSchema is the DDL of the table used by SQLLock, %s is replaced by the table name.
+// It is portable across sqlite and MySQL.
+const SQLLockSchema = `CREATE TABLE IF NOT EXISTS %s (
+	name VARCHAR(255) NOT NULL PRIMARY KEY,
+	holder_identity VARCHAR(255) NOT NULL,
+	lease_duration BIGINT NOT NULL,
+	acquire_time BIGINT NOT NULL,
+	renew_time BIGINT NOT NULL,
+	leader_transitions BIGINT NOT NULL,
+	version BIGINT NOT NULL
+)`
+
+// NewSQLLock returns a locker which stores the Record named name as a row of table in db.
+// Updates are optimistic: a row is only overwritten if its version column is still the one
+// observed by the last Get, Create or Update of this locker.
+// Queries use "?" placeholders, as sqlite and MySQL do.
+func NewSQLLock(db *sql.DB, table, name, identity string) *SQLLock {
+	return &SQLLock{db: db, table: table, name: name, identity: identity}
+}
+
+// SQLLock is a ResourceLocker backed by a row of a database/sql table, see SQLLockSchema.
+type SQLLock struct {
+	// EventRecorder is an optional callback to record events, RecordEvent is a noop if nil.
+	EventRecorder func(name, event string)
+
+	db       *sql.DB
+	table    string
+	name     string
+	identity string
+
+	mu       sync.Mutex // guards observed
+	observed int64      // version observed by last Get, Create or Update
+}
+
+// sqlRecord is the rawRecord of a SQLLock, version is carried so that every update changes the rawRecord.
+type sqlRecord struct {
+	Record
+	Version int64
+}
+
+// CreateTable creates the table of SQLLockSchema if not exists.
+func (sl *SQLLock) CreateTable(ctx context.Context) error {
+	_, err := sl.db.ExecContext(ctx, fmt.Sprintf(SQLLockSchema, sl.table))
+	return err
+}
+
+// Get returns the Record stored in the row.
+// ErrRecordNotFound is returned if the row does not exist.
+func (sl *SQLLock) Get(ctx context.Context) (record *Record, rawRecord []byte, err error) {
+	query := fmt.Sprintf(`SELECT holder_identity, lease_duration, acquire_time, renew_time, leader_transitions, version
+FROM %s WHERE name = ?`, sl.table)
+
+	var r sqlRecord
+	var leaseDuration, acquireTime, renewTime int64
+	err = sl.db.QueryRowContext(ctx, query, sl.name).Scan(&r.HolderIdentity, &leaseDuration,
+		&acquireTime, &renewTime, &r.LeaderTransitions, &r.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	r.LeaseDuration = time.Duration(leaseDuration)
+	r.AcquireTime = time.Unix(0, acquireTime)
+	r.RenewTime = time.Unix(0, renewTime)
+
+	rawRecord, err = json.Marshal(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	sl.setObserved(r.Version)
+	return &r.Record, rawRecord, nil
+}
+
+// Create attempts to insert the row, ErrRecordConflict is returned if the row exists already.
+func (sl *SQLLock) Create(ctx context.Context, ler Record) error {
+	query := fmt.Sprintf(`INSERT INTO %s (name, holder_identity, lease_duration, acquire_time, renew_time, leader_transitions, version)
+VALUES (?, ?, ?, ?, ?, ?, ?)`, sl.table)
+
+	const version = 1
+	_, err := sl.db.ExecContext(ctx, query, sl.name, ler.HolderIdentity, int64(ler.LeaseDuration),
+		ler.AcquireTime.UnixNano(), ler.RenewTime.UnixNano(), ler.LeaderTransitions, version)
+	if err != nil {
+		// a duplicate primary key is reported differently by every driver, tell it by looking the row up.
+		if _, _, getErr := sl.Get(ctx); getErr == nil {
+			return ErrRecordConflict
+		}
+		return err
+	}
+	sl.setObserved(version)
+	return nil
+}
+
+// Update will update the existing row, ErrRecordConflict is returned
+// if the row has been changed since last observed.
+func (sl *SQLLock) Update(ctx context.Context, ler Record) error {
+	query := fmt.Sprintf(`UPDATE %s SET holder_identity = ?, lease_duration = ?, acquire_time = ?, renew_time = ?,
+leader_transitions = ?, version = version + 1 WHERE name = ? AND version = ?`, sl.table)
+
+	version := sl.getObserved()
+	result, err := sl.db.ExecContext(ctx, query, ler.HolderIdentity, int64(ler.LeaseDuration),
+		ler.AcquireTime.UnixNano(), ler.RenewTime.UnixNano(), ler.LeaderTransitions, sl.name, version)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrRecordConflict
+	}
+	sl.setObserved(version + 1)
+	return nil
+}
+
+// RecordEvent is used to record events
+func (sl *SQLLock) RecordEvent(name, event string) {
+	if sl.EventRecorder != nil {
+		sl.EventRecorder(name, event)
+	}
+}
+
+// Identity returns the Identity of the lock
+func (sl *SQLLock) Identity() string {
+	return sl.identity
+}
+
+// Describe is used to convert details on current resource lock
+// into a string
+func (sl *SQLLock) Describe() string {
+	return fmt.Sprintf("sql://%s/%s", sl.table, sl.name)
+}
+
+func (sl *SQLLock) getObserved() int64 {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	return sl.observed
+}
+
+func (sl *SQLLock) setObserved(version int64) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	sl.observed = version
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package leaderelection_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/sync/leaderelection"
+)
+
+// fakeSQLDriver is a database/sql driver understanding the statements of SQLLock only,
+// backed by memory shared by all connections opened with the same name.
+type fakeSQLDriver struct {
+	mu  sync.Mutex
+	dbs map[string]*fakeSQLDB
+}
+
+// fakeSQLDB is a database holding a single table of SQLLock rows.
+type fakeSQLDB struct {
+	mu      sync.Mutex
+	created bool
+	rows    map[string][]driver.Value // holder_identity, lease_duration, acquire_time, renew_time, leader_transitions, version by name
+}
+
+type fakeSQLConn struct{ db *fakeSQLDB }
+
+var sqlDriver = &fakeSQLDriver{dbs: make(map[string]*fakeSQLDB)}
+
+func init() { sql.Register("leaderelection-fake", sqlDriver) }
+
+func (d *fakeSQLDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	db, ok := d.dbs[name]
+	if !ok {
+		db = &fakeSQLDB{rows: make(map[string][]driver.Value)}
+		d.dbs[name] = db
+	}
+	return &fakeSQLConn{db: db}, nil
+}
+
+func (c *fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fake sql: prepared statements not supported")
+}
+func (c *fakeSQLConn) Close() error { return nil }
+func (c *fakeSQLConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("fake sql: transactions not supported")
+}
+
+func (c *fakeSQLConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	db := c.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	query = strings.TrimSpace(query)
+	if strings.HasPrefix(query, "CREATE TABLE") {
+		db.created = true
+		return driver.RowsAffected(0), nil
+	}
+	if !db.created {
+		return nil, errors.New("fake sql: no such table")
+	}
+	switch {
+	case strings.HasPrefix(query, "INSERT"):
+		// name, holder_identity, lease_duration, acquire_time, renew_time, leader_transitions, version
+		name := args[0].Value.(string)
+		if _, ok := db.rows[name]; ok {
+			return nil, fmt.Errorf("fake sql: duplicate primary key %q", name)
+		}
+		db.rows[name] = values(args[1:])
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(query, "UPDATE"):
+		// holder_identity, lease_duration, acquire_time, renew_time, leader_transitions, name, version
+		name := args[5].Value.(string)
+		row, ok := db.rows[name]
+		if !ok || row[5].(int64) != args[6].Value.(int64) {
+			return driver.RowsAffected(0), nil
+		}
+		db.rows[name] = append(values(args[:5]), row[5].(int64)+1)
+		return driver.RowsAffected(1), nil
+	default:
+		return nil, fmt.Errorf("fake sql: unexpected statement %q", query)
+	}
+}
+
+func (c *fakeSQLConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	db := c.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if !db.created {
+		return nil, errors.New("fake sql: no such table")
+	}
+	if !strings.HasPrefix(strings.TrimSpace(query), "SELECT") {
+		return nil, fmt.Errorf("fake sql: unexpected query %q", query)
+	}
+	rows := &fakeSQLRows{}
+	if row, ok := db.rows[args[0].Value.(string)]; ok {
+		rows.rows = [][]driver.Value{append([]driver.Value(nil), row...)}
+	}
+	return rows, nil
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	vs := make([]driver.Value, 0, len(args))
+	for _, arg := range args {
+		vs = append(vs, arg.Value)
+	}
+	return vs
+}
+
+type fakeSQLRows struct{ rows [][]driver.Value }
+
+func (r *fakeSQLRows) Columns() []string {
+	return []string{"holder_identity", "lease_duration", "acquire_time", "renew_time", "leader_transitions", "version"}
+}
+func (r *fakeSQLRows) Close() error { return nil }
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func openSQL(t *testing.T, name string) *sql.DB {
+	db, err := sql.Open("leaderelection-fake", name)
+	if err != nil {
+		t.Fatalf("open sql: %s", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestSQLLock(t *testing.T) {
+	db := openSQL(t, t.Name())
+	ctx := context.Background()
+	a := leaderelection.NewSQLLock(db, "leader_election", "test", "a")
+	b := leaderelection.NewSQLLock(db, "leader_election", "test", "b")
+	if err := a.CreateTable(ctx); err != nil {
+		t.Fatalf("CreateTable: %s", err)
+	}
+
+	if _, _, err := a.Get(ctx); !errors.Is(err, leaderelection.ErrRecordNotFound) {
+		t.Fatalf("Get on empty table: got %v, want %v", err, leaderelection.ErrRecordNotFound)
+	}
+	now := time.Now()
+	if err := a.Create(ctx, leaderelection.Record{HolderIdentity: "a", LeaseDuration: time.Second, AcquireTime: now, RenewTime: now}); err != nil {
+		t.Fatalf("Create: %s", err)
+	}
+	if err := b.Create(ctx, leaderelection.Record{HolderIdentity: "b"}); !errors.Is(err, leaderelection.ErrRecordConflict) {
+		t.Fatalf("Create twice: got %v, want %v", err, leaderelection.ErrRecordConflict)
+	}
+	record, raw, err := b.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get: %s", err)
+	}
+	if record.HolderIdentity != "a" || !record.AcquireTime.Equal(now) || record.LeaseDuration != time.Second {
+		t.Fatalf("Get: got %+v", record)
+	}
+	if err := a.Update(ctx, leaderelection.Record{HolderIdentity: "a", RenewTime: now}); err != nil {
+		t.Fatalf("Update: %s", err)
+	}
+	if err := b.Update(ctx, leaderelection.Record{HolderIdentity: "b", LeaderTransitions: 1}); !errors.Is(err, leaderelection.ErrRecordConflict) {
+		t.Fatalf("Update outdated: got %v, want %v", err, leaderelection.ErrRecordConflict)
+	}
+	_, raw2, err := b.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get: %s", err)
+	}
+	if string(raw) == string(raw2) {
+		t.Fatalf("rawRecord is not changed by Update: %s", raw2)
+	}
+}
+
+func TestSQLLock_ConcurrentElectors(t *testing.T) {
+	if err := leaderelection.NewSQLLock(openSQL(t, t.Name()), "leader_election", "test", "").CreateTable(context.Background()); err != nil {
+		t.Fatalf("CreateTable: %s", err)
+	}
+	testConcurrentElectors(t, func(identity string) leaderelection.ResourceLocker {
+		// a pool per candidate, as if every candidate is a process of its own.
+		return leaderelection.NewSQLLock(openSQL(t, t.Name()), "leader_election", "test", identity)
+	})
+}
@@ -0,0 +1,131 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rate
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// A GCRALimiter controls how frequently events are allowed to happen.
+// It implements the Generic Cell Rate Algorithm, a "token bucket" tracked by the theoretical
+// arrival time of the next event only: one event is allowed every interval, with bursts of
+// at most burst events.
+// See https://en.wikipedia.org/wiki/Generic_cell_rate_algorithm for more about GCRA.
+//
+// Reservations are scheduled in order, so a Reservation never acts before the ones made earlier.
+// The zero value is a valid GCRALimiter, but it will reject all events.
+// A non-positive interval allows burst events at once, at any time.
+type GCRALimiter struct {
+	mu       sync.Mutex
+	interval time.Duration // emission interval, period per event
+	burst    int
+
+	tat time.Time // theoretical arrival time of the next event
+}
+
+// NewGCRALimiter returns a new GCRALimiter that allows one event every interval,
+// and permits bursts of at most burst events.
+func NewGCRALimiter(interval time.Duration, burst int) *GCRALimiter {
+	return &GCRALimiter{interval: interval, burst: burst}
+}
+
+// Interval returns the emission interval, the period per event.
+func (lim *GCRALimiter) Interval() time.Duration {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	return lim.interval
+}
+
+// Burst returns the maximum burst size.
+func (lim *GCRALimiter) Burst() int {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	return lim.burst
+}
+
+// Allow is shorthand for AllowN(1).
+func (lim *GCRALimiter) Allow() bool {
+	return lim.AllowN(1)
+}
+
+// AllowN reports whether n events may happen now.
+// Use this method if you intend to drop / skip events that exceed the rate limit.
+// Otherwise, use Reserve or Wait.
+func (lim *GCRALimiter) AllowN(n int) bool {
+	return allowN(lim, n)
+}
+
+// Reserve is shorthand for ReserveN(ctx, 1).
+func (lim *GCRALimiter) Reserve(ctx context.Context) *Reservation {
+	return lim.ReserveN(ctx, 1)
+}
+
+// ReserveN returns a Reservation that indicates how long the caller must wait before n events happen.
+// The GCRALimiter takes this Reservation into account when allowing future events.
+// The Reservation is not OK if n exceeds the burst size.
+// Cancel of the Reservation before the time to act permits additional events.
+func (lim *GCRALimiter) ReserveN(ctx context.Context, n int) *Reservation {
+	return reserveN(lim, n, math.MaxInt64)
+}
+
+// Wait is shorthand for WaitN(ctx, 1).
+func (lim *GCRALimiter) Wait(ctx context.Context) error {
+	return lim.WaitN(ctx, 1)
+}
+
+// WaitN blocks until lim permits n events to happen.
+// It returns an error if n exceeds the burst size, the Context is
+// canceled, or the expected wait time exceeds the Context's Deadline.
+func (lim *GCRALimiter) WaitN(ctx context.Context, n int) error {
+	return waitN(lim, ctx, n)
+}
+
+func (lim *GCRALimiter) capacity() int {
+	return lim.Burst()
+}
+
+func (lim *GCRALimiter) schedule(now time.Time, n int, maxWait time.Duration) (time.Time, func(now time.Time), bool) {
+	if n <= 0 {
+		return now, nil, true
+	}
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	if n > lim.burst {
+		return now, nil, false
+	}
+	if lim.interval <= 0 {
+		return now, nil, true
+	}
+
+	tat := lim.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	tat = tat.Add(time.Duration(n) * lim.interval)
+	// burst events may arrive ahead of the theoretical arrival time
+	t := tat.Add(-time.Duration(lim.burst) * lim.interval)
+	if t.Before(now) {
+		t = now
+	}
+	if t.Sub(now) > maxWait {
+		return now, nil, false
+	}
+
+	lim.tat = tat
+	return t, func(now time.Time) { lim.cancel(now, t, n) }, true
+}
+
+// cancel takes the theoretical arrival time of n events to act at t back, if not acted at now.
+func (lim *GCRALimiter) cancel(now, t time.Time, n int) {
+	if !now.Before(t) {
+		return
+	}
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	lim.tat = lim.tat.Add(-time.Duration(n) * lim.interval)
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rate
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Limiter controls how frequently events are allowed to happen.
+// BurstLimiter, SlidingLogLimiter, SlidingWindowLimiter and GCRALimiter are Limiters,
+// so that the algorithm can be chosen per endpoint.
+//
+// Tokens taken from a BurstLimiter must be put back by PutToken or PutTokenN,
+// tokens taken from the other Limiters are refilled as time goes by.
+type Limiter interface {
+	// Allow is shorthand for AllowN(1).
+	Allow() bool
+	// AllowN reports whether n events may happen now.
+	AllowN(n int) bool
+	// Reserve is shorthand for ReserveN(ctx, 1).
+	Reserve(ctx context.Context) *Reservation
+	// ReserveN returns a Reservation that indicates how long the caller must wait before n events happen.
+	ReserveN(ctx context.Context, n int) *Reservation
+	// Wait is shorthand for WaitN(ctx, 1).
+	Wait(ctx context.Context) error
+	// WaitN blocks until lim permits n events to happen.
+	WaitN(ctx context.Context, n int) error
+}
+
+var (
+	_ Limiter = (*BurstLimiter)(nil)
+	_ Limiter = (*SlidingLogLimiter)(nil)
+	_ Limiter = (*SlidingWindowLimiter)(nil)
+	_ Limiter = (*GCRALimiter)(nil)
+)
+
+// scheduler is a Limiter which schedules events to act at a time, tokens are refilled as time goes by.
+type scheduler interface {
+	// capacity returns the maximum events may happen at once.
+	capacity() int
+	// schedule reserves n events to act at the earliest time not before now, maxWait later at most.
+	// cancel reverses the reservation if not acted at its now.
+	schedule(now time.Time, n int, maxWait time.Duration) (timeToAct time.Time, cancel func(now time.Time), ok bool)
+}
+
+// allowN reports whether n events may happen now.
+func allowN(s scheduler, n int) bool {
+	_, _, ok := s.schedule(time.Now(), n, 0)
+	return ok
+}
+
+// reserveN returns a Reservation of n events, maxWait later at most.
+func reserveN(s scheduler, n int, maxWait time.Duration) *Reservation {
+	r := newReservation(false)
+	r.burst = n
+	r.timeToAct, r.cancelAt, r.ok = s.schedule(time.Now(), n, maxWait)
+	return r
+}
+
+// waitN blocks until s permits n events to happen.
+func waitN(s scheduler, ctx context.Context, n int) error {
+	if c := s.capacity(); n > c {
+		return fmt.Errorf("rate: Wait(n=%d) exceeds limiter's burst %d", n, c)
+	}
+	// Check if ctx is already cancelled
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	maxWait := time.Duration(math.MaxInt64)
+	if deadline, has := ctx.Deadline(); has {
+		maxWait = time.Until(deadline)
+	}
+	r := reserveN(s, n, maxWait)
+	if !r.ok {
+		return fmt.Errorf("rate: Wait(n=%d) would exceed context deadline", n)
+	}
+	return r.Wait(ctx)
+}
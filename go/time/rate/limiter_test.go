@@ -0,0 +1,144 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rate
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+type schedule struct {
+	at      time.Duration // time to schedule, since epoch
+	n       int
+	maxWait time.Duration
+	delay   time.Duration // delay expected if ok
+	ok      bool
+}
+
+const inf = time.Duration(math.MaxInt64)
+
+// epoch is aligned to fixed windows of a second.
+var epoch = time.Unix(1700000000, 0)
+
+func runSchedule(t *testing.T, s scheduler, schedules []schedule) {
+	t.Helper()
+	for i, sc := range schedules {
+		now := epoch.Add(sc.at)
+		timeToAct, _, ok := s.schedule(now, sc.n, sc.maxWait)
+		if ok != sc.ok {
+			t.Errorf("step %d: schedule(%v, %d, %v) ok = %t, want %t", i, sc.at, sc.n, sc.maxWait, ok, sc.ok)
+			continue
+		}
+		if ok && timeToAct.Sub(now) != sc.delay {
+			t.Errorf("step %d: schedule(%v, %d, %v) delay = %v, want %v", i, sc.at, sc.n, sc.maxWait, timeToAct.Sub(now), sc.delay)
+		}
+	}
+}
+
+func TestSlidingLogLimiter(t *testing.T) {
+	runSchedule(t, NewSlidingLogLimiter(3, time.Second), []schedule{
+		{0, 2, 0, 0, true},
+		{100 * time.Millisecond, 1, 0, 0, true},
+		{200 * time.Millisecond, 1, 0, 0, false},
+		{200 * time.Millisecond, 1, inf, 800 * time.Millisecond, true},  // the event at 0 is out of the window
+		{300 * time.Millisecond, 1, inf, 700 * time.Millisecond, true},  // in order
+		{1100 * time.Millisecond, 1, 0, 0, true},                        // events at 0 and 100ms are out of the window
+		{1100 * time.Millisecond, 1, inf, 900 * time.Millisecond, true}, // the event at 1s is out of the window
+		{1100 * time.Millisecond, 4, inf, 0, false},                     // exceeds limit
+		{3 * time.Second, 3, 0, 0, true},
+	})
+}
+
+func TestSlidingWindowLimiter(t *testing.T) {
+	runSchedule(t, NewSlidingWindowLimiter(4, time.Second), []schedule{
+		{0, 4, 0, 0, true},
+		{500 * time.Millisecond, 1, 0, 0, false},
+		{500 * time.Millisecond, 1, inf, 750 * time.Millisecond, true},  // 0 + 4*(1-0.25) + 1 <= 4
+		{1500 * time.Millisecond, 2, inf, 250 * time.Millisecond, true}, // 1 + 4*(1-0.75) + 2 <= 4
+		{1500 * time.Millisecond, 5, inf, 0, false},                     // exceeds limit
+		{3 * time.Second, 4, 0, 0, true},
+	})
+}
+
+func TestGCRALimiter(t *testing.T) {
+	runSchedule(t, NewGCRALimiter(100*time.Millisecond, 3), []schedule{
+		{0, 1, 0, 0, true},
+		{0, 2, 0, 0, true},
+		{0, 1, 0, 0, false},
+		{0, 1, inf, 100 * time.Millisecond, true},
+		{0, 4, inf, 0, false}, // exceeds burst
+		{time.Second, 3, 0, 0, true},
+		{time.Second, 1, inf, 100 * time.Millisecond, true},
+	})
+}
+
+func TestSchedulerZeroValue(t *testing.T) {
+	for _, s := range []scheduler{&SlidingLogLimiter{}, &SlidingWindowLimiter{}, &GCRALimiter{}} {
+		runSchedule(t, s, []schedule{
+			{0, 1, inf, 0, false},
+			{0, 0, inf, 0, true},
+		})
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	for _, s := range []scheduler{
+		NewSlidingLogLimiter(2, time.Second),
+		NewSlidingWindowLimiter(2, time.Second),
+		NewGCRALimiter(500*time.Millisecond, 2),
+	} {
+		if _, _, ok := s.schedule(epoch, 2, 0); !ok {
+			t.Fatalf("%T: schedule(2) = false, want true", s)
+		}
+		timeToAct, cancel, ok := s.schedule(epoch, 1, inf)
+		if !ok || !timeToAct.After(epoch) {
+			t.Fatalf("%T: schedule(1) = %v, %t, want delayed", s, timeToAct.Sub(epoch), ok)
+		}
+		cancel(epoch)
+		if got, _, _ := s.schedule(epoch, 1, inf); !got.Equal(timeToAct) {
+			t.Errorf("%T: schedule(1) after Cancel delay = %v, want %v", s, got.Sub(epoch), timeToAct.Sub(epoch))
+		}
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	for _, lim := range []Limiter{
+		NewSlidingLogLimiter(1, d),
+		NewSlidingWindowLimiter(1, d),
+		NewGCRALimiter(d, 1),
+	} {
+		ctx := context.Background()
+		if err := lim.Wait(ctx); err != nil {
+			t.Fatalf("%T: Wait() = %v, want nil", lim, err)
+		}
+		if err := lim.WaitN(ctx, 2); err == nil {
+			t.Errorf("%T: WaitN(2) = nil, want exceeds burst", lim)
+		}
+		func() {
+			ctx, cancel := context.WithTimeout(ctx, d/10)
+			defer cancel()
+			if err := lim.Wait(ctx); err == nil {
+				t.Errorf("%T: Wait() = nil, want exceeds context deadline", lim)
+			}
+		}()
+
+		r := lim.Reserve(ctx)
+		if !r.OK() || r.Ready() {
+			t.Errorf("%T: Reserve() OK = %t, Ready = %t, want true, false", lim, r.OK(), r.Ready())
+		}
+		start := time.Now()
+		if err := r.Wait(ctx); err != nil {
+			t.Fatalf("%T: Reservation.Wait() = %v, want nil", lim, err)
+		}
+		if elapsed := time.Since(start); elapsed < d/2 {
+			t.Errorf("%T: Reservation.Wait() returned after %v, want about %v", lim, elapsed, d)
+		}
+		if !r.Ready() {
+			t.Errorf("%T: Ready() = false after Wait, want true", lim)
+		}
+	}
+}
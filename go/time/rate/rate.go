@@ -16,6 +16,9 @@ import (
 
 const (
 	starvationThresholdNs = 1e6
+
+	// storePollInterval is the interval Reservations waiting poll the Store for tokens put back by other limiters.
+	storePollInterval = 10 * time.Millisecond
 )
 
 type expectKeyType struct{}
@@ -62,7 +65,10 @@ type BurstLimiter struct {
 	burst                  int // bucket size, Put Must be called after Get
 	tokensChangedListeners []context.Context
 
-	tokens int // unconsumed tokens
+	tokens int   // unconsumed tokens, if store is nil
+	store  Store // unconsumed tokens, shared with other limiters
+
+	storeErrorHandler func(err error) // called with errors of store not returned to callers
 }
 
 // Burst returns the maximum burst size. Burst is the maximum number of tokens
@@ -78,8 +84,17 @@ func (lim *BurstLimiter) Burst() int {
 // Tokens returns the token nums unconsumed.
 func (lim *BurstLimiter) Tokens() int {
 	lim.mu.Lock()
-	defer lim.mu.Unlock()
-	return lim.tokens
+	if lim.store == nil {
+		defer lim.mu.Unlock()
+		return lim.tokens
+	}
+	tokens, err := lim.store.Tokens(context.Background())
+	lim.mu.Unlock()
+	if err != nil {
+		lim.handleStoreError(err)
+		return 0
+	}
+	return tokens
 }
 
 // NewFullBurstLimiter returns a new BurstLimiter inited with full tokens that allows
@@ -99,6 +114,21 @@ func NewEmptyBurstLimiter(b int) *BurstLimiter {
 	}
 }
 
+// NewBurstLimiterWithStore returns a new BurstLimiter whose unconsumed tokens live in store,
+// that allows events up to burst b and permits bursts of at most b tokens.
+// Limiters sharing one store share one bucket, across processes if the store is,
+// as NewFileStore is; Reservations are served in order among the limiter's own, and
+// poll the store for tokens put back by other limiters.
+// Errors of store are returned by Wait and the Wait of Reservations, and passed to
+// the handler set by SetStoreErrorHandler otherwise.
+// store is not initialized by the limiter, see NewMemoryStore.
+func NewBurstLimiterWithStore(b int, store Store) *BurstLimiter {
+	return &BurstLimiter{
+		burst: b,
+		store: store,
+	}
+}
+
 // SetBurst sets a new burst size for the limiter.
 func (lim *BurstLimiter) SetBurst(newBurst int) {
 	lim.mu.Lock()
@@ -106,6 +136,26 @@ func (lim *BurstLimiter) SetBurst(newBurst int) {
 	lim.burst = newBurst
 }
 
+// SetStoreErrorHandler sets h to be called with the errors of the Store which cannot be
+// returned to the caller, such as by AllowN, PutTokenN or Tokens; tokens are taken as
+// unavailable then, and tokens put back are lost. Such errors are dropped if h is nil.
+func (lim *BurstLimiter) SetStoreErrorHandler(h func(err error)) {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	lim.storeErrorHandler = h
+}
+
+// handleStoreError passes err to the handler set by SetStoreErrorHandler.
+// handleStoreError requires that lim.mu is not held, so that h may call lim.
+func (lim *BurstLimiter) handleStoreError(err error) {
+	lim.mu.Lock()
+	h := lim.storeErrorHandler
+	lim.mu.Unlock()
+	if h != nil {
+		h(err)
+	}
+}
+
 // Allow is shorthand for AllowN(time.Now(), 1).
 // 当没有可用或足够的事件时，返回false
 func (lim *BurstLimiter) Allow() bool {
@@ -203,15 +253,29 @@ func (lim *BurstLimiter) PutToken() {
 	lim.PutTokenN(1)
 }
 
+// PutTokenN refills n tokens into the bucket, tokens overflowing the burst are dropped.
+// Reservations waiting are served in order before events allowed later.
 func (lim *BurstLimiter) PutTokenN(n int) {
 	lim.mu.Lock()
-	defer lim.mu.Unlock()
-	lim.tokens += n
-	// drop if overflowed
-	if lim.tokens > lim.burst {
-		lim.tokens = lim.burst
+	err := lim.putTokenNLocked(n)
+	lim.notifyTokensChangedLocked()
+	lim.mu.Unlock()
+	if err != nil {
+		lim.handleStoreError(err)
 	}
+}
+
+// notifyTokensChanged serves Reservations waiting with tokens unconsumed, such as tokens
+// put back to the store by other limiters.
+func (lim *BurstLimiter) notifyTokensChanged() {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	lim.notifyTokensChangedLocked()
+}
 
+// notifyTokensChangedLocked serves Reservations waiting in order with tokens unconsumed.
+// notifyTokensChangedLocked requires that lim.mu is held.
+func (lim *BurstLimiter) notifyTokensChangedLocked() {
 	for i := 0; i < len(lim.tokensChangedListeners); i++ {
 		tokensGot := lim.tokensChangedListeners[i]
 		r := tokensGot.Value(expectTokensKey).(*reservation)
@@ -223,21 +287,28 @@ func (lim *BurstLimiter) PutTokenN(n int) {
 				lim.tokensChangedListeners = append(lim.tokensChangedListeners[:i], lim.tokensChangedListeners[i+1:]...)
 			}
 			r.notifyTokensReady()
+			// the Reservation removed, i refers to the next one
+			i--
 			continue
 		}
 
 		tokensWait := r.burst - r.tokens
 
 		// tokens in the Bucket is not enough for the Reservation
-		if lim.tokens < tokensWait {
-			r.tokens += lim.tokens
-			lim.tokens = 0
+		got, err := lim.takeTokensLocked(0, tokensWait)
+		if err != nil {
+			// fail the Reservation, its Wait returns err
+			r.err = err
+			lim.tokensChangedListeners = append(lim.tokensChangedListeners[:i], lim.tokensChangedListeners[i+1:]...)
+			r.notifyTokensReady()
+			break
+		}
+		r.tokens += got
+		if got < tokensWait {
 			break
 		}
 
 		// enough
-		r.tokens = r.burst
-		lim.tokens -= tokensWait
 		// remove notified
 		if i == len(lim.tokensChangedListeners)-1 {
 			lim.tokensChangedListeners = lim.tokensChangedListeners[:i]
@@ -245,6 +316,8 @@ func (lim *BurstLimiter) PutTokenN(n int) {
 			lim.tokensChangedListeners = append(lim.tokensChangedListeners[:i], lim.tokensChangedListeners[i+1:]...)
 		}
 		r.notifyTokensReady()
+		// the Reservation removed, i refers to the next one
+		i--
 		continue
 	}
 }
@@ -256,6 +329,15 @@ func (lim *BurstLimiter) GetToken() (ok bool) {
 
 // GetTokenN returns true if token is got
 func (lim *BurstLimiter) GetTokenN(n int) (ok bool) {
+	ok, err := lim.getTokenN(n)
+	if err != nil {
+		lim.handleStoreError(err)
+	}
+	return ok
+}
+
+// getTokenN returns true if token is got, and the error of the Store if failed.
+func (lim *BurstLimiter) getTokenN(n int) (ok bool, err error) {
 	lim.mu.Lock()
 	defer lim.mu.Unlock()
 	return lim.getTokenNLocked(n)
@@ -265,15 +347,37 @@ func (lim *BurstLimiter) GetTokenN(n int) (ok bool) {
 // advance calculates and returns an updated state for lim resulting from the passage of time.
 // lim is not changed.
 // getTokenNLocked requires that lim.mu is held.
-func (lim *BurstLimiter) getTokenNLocked(n int) (ok bool) {
+func (lim *BurstLimiter) getTokenNLocked(n int) (ok bool, err error) {
 	if n <= 0 {
-		return true
+		return true, nil
+	}
+	got, err := lim.takeTokensLocked(n, n)
+	return got == n, err
+}
+
+// takeTokensLocked consumes tokens from the bucket, n at most and min at least,
+// and returns the tokens consumed, 0 if less than min tokens are unconsumed.
+// takeTokensLocked requires that lim.mu is held.
+func (lim *BurstLimiter) takeTokensLocked(min, n int) (int, error) {
+	if lim.store != nil {
+		got, err := lim.store.TakeTokens(context.Background(), min, n)
+		if err != nil {
+			return 0, err
+		}
+		return got, nil
 	}
-	if lim.tokens >= n {
-		lim.tokens -= n
-		return true
+	return takeTokens(&lim.tokens, min, n), nil
+}
+
+// putTokenNLocked refills n tokens into the bucket, tokens overflowing the burst are dropped.
+// putTokenNLocked requires that lim.mu is held.
+func (lim *BurstLimiter) putTokenNLocked(n int) error {
+	if lim.store != nil {
+		// tokens are lost if the store fails, as if they overflowed
+		return lim.store.PutTokens(context.Background(), n, lim.burst)
 	}
-	return false
+	putTokens(&lim.tokens, n, lim.burst)
+	return nil
 }
 
 // reserveN is a helper method for AllowN, ReserveN, and WaitN.
@@ -292,13 +396,17 @@ func (lim *BurstLimiter) reserveN(ctx context.Context, n int, wait bool, gc bool
 	defer lim.mu.Unlock()
 
 	// tokens are enough
-	if lim.tokens >= n && len(lim.tokensChangedListeners) == 0 {
+	if len(lim.tokensChangedListeners) == 0 {
 		// get n tokens from lim
-		if lim.getTokenNLocked(n) {
+		ok, err := lim.getTokenNLocked(n)
+		if ok || err != nil {
 			r := newReservation(gc)
 			r.lim = lim
 			r.burst = n
-			r.tokens = n
+			if ok {
+				r.tokens = n
+			}
+			r.err = err // returned by Wait
 			return r
 		}
 	}
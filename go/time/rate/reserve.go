@@ -11,8 +11,8 @@ import (
 	"time"
 )
 
-// A Reservation holds information about events that are permitted by a BurstLimiter to happen after a delay.
-// A Reservation may be canceled, which may enable the BurstLimiter to permit additional events.
+// A Reservation holds information about events that are permitted by a Limiter to happen after a delay.
+// A Reservation may be canceled, which may enable the Limiter to permit additional events.
 type Reservation struct {
 	*reservation
 }
@@ -23,16 +23,18 @@ type Reservation struct {
 // guaranteed to be garbage collected
 // https://tip.golang.org/doc/gc-guide#Where_Go_Values_Live
 type reservation struct {
-	ok  bool
-	lim *BurstLimiter
+	ok  bool          // whether the scheduler can provide the tokens, if lim is nil
+	lim *BurstLimiter // nil if the Reservation is made by a scheduler, such as GCRALimiter
 
 	// [0, tokens, burst]
 	burst  int // reservation bucket size
 	tokens int // tokens got(reserved) from BurstLimiter, Cancel(put back) must be called to the BurstLimiter after Wait
 
-	// timeToAct time.Time       // now + wait, wait if bucket is not enough
-	tokensGot         context.Context // chan to notify tokens is put, check if enough
+	timeToAct         time.Time           // now + wait, time to act of the Reservation made by a scheduler
+	cancelAt          func(now time.Time) // reverses the Reservation made by a scheduler, if not acted at now
+	tokensGot         context.Context     // chan to notify tokens is put, check if enough
 	notifyTokensReady context.CancelFunc
+	err               error // the Store failed serving the Reservation, returned by Wait
 
 	// test only
 	canceled context.CancelFunc
@@ -56,6 +58,9 @@ func (r *Reservation) removeGC() *Reservation {
 // within the maximum wait time. If OK is false, Delay returns InfDuration, and
 // Cancel does nothing.
 func (r *Reservation) OK() bool {
+	if r.lim == nil {
+		return r.ok
+	}
 	return r.burst <= r.lim.Burst()
 }
 
@@ -64,12 +69,18 @@ func (r *Reservation) OK() bool {
 // Cancel or GC does put back the token reserved in the Reservation.
 // If Ready is false, WaitN blocks until lim permits n events to happen.
 func (r *Reservation) Ready() bool {
+	if r.lim == nil {
+		return r.ok && !time.Now().Before(r.timeToAct)
+	}
 	return r.tokens >= r.burst
 }
 
 // Wait blocks before taking the reserved action
 // Wait 当没有可用或足够的事件时，将阻塞等待
 func (r *Reservation) Wait(ctx context.Context) error {
+	if r.lim == nil {
+		return r.waitTimeToAct(ctx)
+	}
 	if r.burst <= 0 {
 		r.burst = 0
 		r.tokens = r.burst
@@ -79,6 +90,10 @@ func (r *Reservation) Wait(ctx context.Context) error {
 	if r.Ready() {
 		return nil
 	}
+	if r.err != nil {
+		r.Cancel()
+		return r.err
+	}
 
 	var burst = r.lim.Burst()
 	if r.burst > burst {
@@ -86,11 +101,23 @@ func (r *Reservation) Wait(ctx context.Context) error {
 	}
 	timer := time.NewTimer(starvationThresholdNs)
 	defer timer.Stop()
+	var pollC <-chan time.Time
+	if r.lim.store != nil {
+		// tokens may be put back to the store by other limiters
+		ticker := time.NewTicker(storePollInterval)
+		defer ticker.Stop()
+		pollC = ticker.C
+	}
 	for {
 		// fast path
 		if r.tokensGot == nil {
 			// We can proceed.
-			if r.lim.GetTokenN(r.burst - r.tokens) {
+			ok, err := r.lim.getTokenN(r.burst - r.tokens)
+			if err != nil {
+				r.Cancel()
+				return err
+			}
+			if ok {
 				r.tokens = r.burst
 				return nil
 			}
@@ -115,8 +142,16 @@ func (r *Reservation) Wait(ctx context.Context) error {
 		// Wait if necessary
 		select {
 		case <-r.tokensGot.Done():
+			if r.err != nil {
+				// the Store failed serving the Reservation
+				r.Cancel()
+				return r.err
+			}
 			// We can proceed.
 			return nil
+		case <-pollC:
+			r.lim.notifyTokensChanged()
+			continue
 		case <-ctx.Done():
 			// Context was canceled before we could proceed.  Cancel the
 			// reservation, which may permit other events to proceed sooner.
@@ -137,6 +172,13 @@ func (r *Reservation) Cancel() {
 	if r.canceled != nil {
 		r.canceled()
 	}
+	if r.lim == nil {
+		if r.cancelAt != nil {
+			r.cancelAt(time.Now())
+			r.cancelAt = nil
+		}
+		return
+	}
 	if r.burst <= 0 {
 		return
 	}
@@ -152,3 +194,30 @@ func (r *Reservation) Cancel() {
 func (r *Reservation) PutToken() {
 	r.Cancel()
 }
+
+// waitTimeToAct blocks until the time to act of the Reservation made by a scheduler.
+func (r *Reservation) waitTimeToAct(ctx context.Context) error {
+	if !r.ok {
+		return fmt.Errorf("rate: Wait(n=%d) exceeds limiter's burst", r.burst)
+	}
+	delay := time.Until(r.timeToAct)
+	if delay <= 0 {
+		return nil
+	}
+	if deadline, has := ctx.Deadline(); has && deadline.Before(r.timeToAct) {
+		r.Cancel()
+		return fmt.Errorf("rate: Wait(n=%d) would exceed context deadline", r.burst)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		// We can proceed.
+		return nil
+	case <-ctx.Done():
+		// Context was canceled before we could proceed.  Cancel the
+		// reservation, which may permit other events to proceed sooner.
+		r.Cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rate
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// A SlidingLogLimiter controls how frequently events are allowed to happen.
+// It logs the time of every event, and allows at most limit events in any window of time.
+// It's exact, at the cost of memory in proportion to limit.
+//
+// Reservations are scheduled in order, so a Reservation never acts before the ones made earlier.
+// The zero value is a valid SlidingLogLimiter, but it will reject all events.
+// A non-positive window allows limit events at once, at any time.
+type SlidingLogLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+
+	log []*logEntry // events sorted by time to act, old ones in the window only
+}
+
+type logEntry struct {
+	at time.Time // time to act
+	n  int       // events
+}
+
+// NewSlidingLogLimiter returns a new SlidingLogLimiter that allows at most limit events in any window.
+func NewSlidingLogLimiter(limit int, window time.Duration) *SlidingLogLimiter {
+	return &SlidingLogLimiter{limit: limit, window: window}
+}
+
+// Limit returns the maximum events allowed in any window.
+func (lim *SlidingLogLimiter) Limit() int {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	return lim.limit
+}
+
+// Window returns the window of time.
+func (lim *SlidingLogLimiter) Window() time.Duration {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	return lim.window
+}
+
+// Allow is shorthand for AllowN(1).
+func (lim *SlidingLogLimiter) Allow() bool {
+	return lim.AllowN(1)
+}
+
+// AllowN reports whether n events may happen now.
+// Use this method if you intend to drop / skip events that exceed the rate limit.
+// Otherwise, use Reserve or Wait.
+func (lim *SlidingLogLimiter) AllowN(n int) bool {
+	return allowN(lim, n)
+}
+
+// Reserve is shorthand for ReserveN(ctx, 1).
+func (lim *SlidingLogLimiter) Reserve(ctx context.Context) *Reservation {
+	return lim.ReserveN(ctx, 1)
+}
+
+// ReserveN returns a Reservation that indicates how long the caller must wait before n events happen.
+// The SlidingLogLimiter takes this Reservation into account when allowing future events.
+// The Reservation is not OK if n exceeds the limit.
+// Cancel of the Reservation before the time to act permits additional events.
+func (lim *SlidingLogLimiter) ReserveN(ctx context.Context, n int) *Reservation {
+	return reserveN(lim, n, math.MaxInt64)
+}
+
+// Wait is shorthand for WaitN(ctx, 1).
+func (lim *SlidingLogLimiter) Wait(ctx context.Context) error {
+	return lim.WaitN(ctx, 1)
+}
+
+// WaitN blocks until lim permits n events to happen.
+// It returns an error if n exceeds the limit, the Context is
+// canceled, or the expected wait time exceeds the Context's Deadline.
+func (lim *SlidingLogLimiter) WaitN(ctx context.Context, n int) error {
+	return waitN(lim, ctx, n)
+}
+
+func (lim *SlidingLogLimiter) capacity() int {
+	return lim.Limit()
+}
+
+func (lim *SlidingLogLimiter) schedule(now time.Time, n int, maxWait time.Duration) (time.Time, func(now time.Time), bool) {
+	if n <= 0 {
+		return now, nil, true
+	}
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	if n > lim.limit {
+		return now, nil, false
+	}
+
+	// forget events out of the window
+	var expired int
+	for expired < len(lim.log) && !lim.log[expired].at.After(now.Add(-lim.window)) {
+		expired++
+	}
+	lim.log = lim.log[expired:]
+
+	t := now
+	if len(lim.log) > 0 && lim.log[len(lim.log)-1].at.After(t) {
+		// in order
+		t = lim.log[len(lim.log)-1].at
+	}
+	if lim.window > 0 {
+		// the latest event which makes the window overflowed must be out of the window
+		var events int
+		for i := len(lim.log) - 1; i >= 0; i-- {
+			events += lim.log[i].n
+			if events+n > lim.limit {
+				if at := lim.log[i].at.Add(lim.window); at.After(t) {
+					t = at
+				}
+				break
+			}
+		}
+	}
+	if t.Sub(now) > maxWait {
+		return now, nil, false
+	}
+
+	e := &logEntry{at: t, n: n}
+	lim.log = append(lim.log, e)
+	return t, func(now time.Time) { lim.cancel(now, e) }, true
+}
+
+// cancel removes the event e from the log, if not acted at now.
+func (lim *SlidingLogLimiter) cancel(now time.Time, e *logEntry) {
+	if !now.Before(e.at) {
+		return
+	}
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	for i, entry := range lim.log {
+		if entry == e {
+			lim.log = append(lim.log[:i], lim.log[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rate
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// A SlidingWindowLimiter controls how frequently events are allowed to happen.
+// It counts events in fixed windows of time, and allows at most limit events in the sliding window
+// ending now, whose events are estimated as the events in the current fixed window, plus the events
+// in the previous fixed window weighted by its part overlapped by the sliding window.
+// It's approximate, but costs constant memory, independent of limit.
+//
+// Reservations are scheduled in order, so a Reservation never acts before the ones made earlier.
+// The zero value is a valid SlidingWindowLimiter, but it will reject all events.
+// A non-positive window allows limit events at once, at any time.
+type SlidingWindowLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+
+	counts map[int64]int // events by index of fixed window, the previous, current and future ones only
+	last   time.Time     // time to act of the latest Reservation
+}
+
+// NewSlidingWindowLimiter returns a new SlidingWindowLimiter that allows at most limit events in any window.
+func NewSlidingWindowLimiter(limit int, window time.Duration) *SlidingWindowLimiter {
+	return &SlidingWindowLimiter{limit: limit, window: window}
+}
+
+// Limit returns the maximum events allowed in any window.
+func (lim *SlidingWindowLimiter) Limit() int {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	return lim.limit
+}
+
+// Window returns the window of time.
+func (lim *SlidingWindowLimiter) Window() time.Duration {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	return lim.window
+}
+
+// Allow is shorthand for AllowN(1).
+func (lim *SlidingWindowLimiter) Allow() bool {
+	return lim.AllowN(1)
+}
+
+// AllowN reports whether n events may happen now.
+// Use this method if you intend to drop / skip events that exceed the rate limit.
+// Otherwise, use Reserve or Wait.
+func (lim *SlidingWindowLimiter) AllowN(n int) bool {
+	return allowN(lim, n)
+}
+
+// Reserve is shorthand for ReserveN(ctx, 1).
+func (lim *SlidingWindowLimiter) Reserve(ctx context.Context) *Reservation {
+	return lim.ReserveN(ctx, 1)
+}
+
+// ReserveN returns a Reservation that indicates how long the caller must wait before n events happen.
+// The SlidingWindowLimiter takes this Reservation into account when allowing future events.
+// The Reservation is not OK if n exceeds the limit.
+// Cancel of the Reservation before the time to act permits additional events.
+func (lim *SlidingWindowLimiter) ReserveN(ctx context.Context, n int) *Reservation {
+	return reserveN(lim, n, math.MaxInt64)
+}
+
+// Wait is shorthand for WaitN(ctx, 1).
+func (lim *SlidingWindowLimiter) Wait(ctx context.Context) error {
+	return lim.WaitN(ctx, 1)
+}
+
+// WaitN blocks until lim permits n events to happen.
+// It returns an error if n exceeds the limit, the Context is
+// canceled, or the expected wait time exceeds the Context's Deadline.
+func (lim *SlidingWindowLimiter) WaitN(ctx context.Context, n int) error {
+	return waitN(lim, ctx, n)
+}
+
+func (lim *SlidingWindowLimiter) capacity() int {
+	return lim.Limit()
+}
+
+func (lim *SlidingWindowLimiter) schedule(now time.Time, n int, maxWait time.Duration) (time.Time, func(now time.Time), bool) {
+	if n <= 0 {
+		return now, nil, true
+	}
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	if n > lim.limit {
+		return now, nil, false
+	}
+	if lim.window <= 0 {
+		return now, nil, true
+	}
+	if lim.counts == nil {
+		lim.counts = make(map[int64]int)
+	}
+
+	// forget fixed windows out of the sliding window
+	for w := range lim.counts {
+		if w < lim.index(now)-1 {
+			delete(lim.counts, w)
+		}
+	}
+
+	t := now
+	if lim.last.After(t) {
+		// in order
+		t = lim.last
+	}
+	for {
+		w := lim.index(t)
+		current, previous := lim.counts[w], lim.counts[w-1]
+		if current+n > lim.limit {
+			t = lim.start(w + 1)
+			continue
+		}
+		// previous * (window - elapsed) / window <= limit - current - n
+		var elapsed time.Duration
+		if previous > 0 {
+			elapsed = lim.window - time.Duration(float64(lim.window)*float64(lim.limit-current-n)/float64(previous))
+		}
+		if elapsed >= lim.window {
+			t = lim.start(w + 1)
+			continue
+		}
+		if at := lim.start(w).Add(elapsed); at.After(t) {
+			t = at
+		}
+		break
+	}
+	if t.Sub(now) > maxWait {
+		return now, nil, false
+	}
+
+	w := lim.index(t)
+	lim.counts[w] += n
+	lim.last = t
+	return t, func(now time.Time) { lim.cancel(now, t, w, n) }, true
+}
+
+// cancel takes n events to act at t back from the fixed window w, if not acted at now.
+func (lim *SlidingWindowLimiter) cancel(now, t time.Time, w int64, n int) {
+	if !now.Before(t) {
+		return
+	}
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	if lim.counts[w] <= n {
+		delete(lim.counts, w)
+		return
+	}
+	lim.counts[w] -= n
+}
+
+// index returns the index of the fixed window containing t.
+func (lim *SlidingWindowLimiter) index(t time.Time) int64 {
+	return t.UnixNano() / int64(lim.window)
+}
+
+// start returns the start time of the fixed window w.
+func (lim *SlidingWindowLimiter) start(w int64) time.Time {
+	return time.Unix(0, w*int64(lim.window))
+}
@@ -0,0 +1,150 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+
+	os_ "github.com/searKing/golang/go/os"
+)
+
+// Store holds the unconsumed tokens of a bucket, which may be shared by several BurstLimiters,
+// even in several processes, as by NewFileStore.
+// Every method must be atomic with regard to every other user of the bucket.
+type Store interface {
+	// Tokens returns the unconsumed tokens in the bucket.
+	Tokens(ctx context.Context) (int, error)
+	// TakeTokens consumes tokens from the bucket, n at most and min at least,
+	// and returns the tokens consumed, 0 if less than min tokens are unconsumed.
+	TakeTokens(ctx context.Context, min, n int) (int, error)
+	// PutTokens refills n tokens into the bucket, tokens overflowing burst are dropped.
+	PutTokens(ctx context.Context, n, burst int) error
+}
+
+// NewMemoryStore returns a Store in memory inited with tokens, it's the reference implementation of Store,
+// and can be shared by BurstLimiters in one process.
+func NewMemoryStore(tokens int) Store {
+	return &memoryStore{tokens: tokens}
+}
+
+type memoryStore struct {
+	mu     sync.Mutex
+	tokens int
+}
+
+func (s *memoryStore) Tokens(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens, nil
+}
+
+func (s *memoryStore) TakeTokens(ctx context.Context, min, n int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return takeTokens(&s.tokens, min, n), nil
+}
+
+func (s *memoryStore) PutTokens(ctx context.Context, n, burst int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	putTokens(&s.tokens, n, burst)
+	return nil
+}
+
+// takeTokens consumes tokens from *tokens, n at most and min at least,
+// and returns the tokens consumed, 0 if less than min tokens are unconsumed.
+func takeTokens(tokens *int, min, n int) int {
+	if *tokens < min || *tokens <= 0 || n <= 0 {
+		return 0
+	}
+	if *tokens < n {
+		n = *tokens
+	}
+	*tokens -= n
+	return n
+}
+
+// putTokens refills n tokens into *tokens, tokens overflowing burst are dropped.
+func putTokens(tokens *int, n, burst int) {
+	*tokens += n
+	// drop if overflowed
+	if *tokens > burst {
+		*tokens = burst
+	}
+}
+
+// NewFileStore returns a Store of the bucket in the file named path, inited with tokens
+// if the file does not exist or is empty.
+// Every method opens the file and holds an exclusive advisory lock on it (flock on unix,
+// LockFileEx on windows), so that BurstLimiters of several processes on the same host
+// share one bucket, which survives restarts too.
+func NewFileStore(path string, tokens int) Store {
+	return &fileStore{path: path, tokens: tokens}
+}
+
+type fileStore struct {
+	path   string
+	tokens int // tokens of the bucket if the file is empty
+}
+
+func (s *fileStore) Tokens(ctx context.Context) (int, error) {
+	var tokens int
+	err := s.update(ctx, func(t int) (int, bool) {
+		tokens = t
+		return t, false
+	})
+	return tokens, err
+}
+
+func (s *fileStore) TakeTokens(ctx context.Context, min, n int) (int, error) {
+	var took int
+	err := s.update(ctx, func(tokens int) (int, bool) {
+		took = takeTokens(&tokens, min, n)
+		return tokens, took > 0
+	})
+	if err != nil {
+		return 0, err
+	}
+	return took, nil
+}
+
+func (s *fileStore) PutTokens(ctx context.Context, n, burst int) error {
+	return s.update(ctx, func(tokens int) (int, bool) {
+		putTokens(&tokens, n, burst)
+		return tokens, true
+	})
+}
+
+// update reads the tokens and stores the tokens returned by f if changed, under the file lock.
+func (s *fileStore) update(ctx context.Context, f func(tokens int) (int, bool)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return os_.WithLockedFile(s.path, func(file *os.File) error {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		tokens := s.tokens
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			tokens, err = strconv.Atoi(string(data))
+			if err != nil {
+				return fmt.Errorf("rate: malformed tokens in %s: %w", s.path, err)
+			}
+		}
+
+		tokens, changed := f(tokens)
+		if !changed {
+			return nil
+		}
+		return os_.ReplaceFile(file, []byte(strconv.Itoa(tokens)))
+	})
+}
@@ -0,0 +1,235 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStoreSharedQuota(t *testing.T) {
+	store := NewMemoryStore(3)
+	lim1 := NewBurstLimiterWithStore(3, store)
+	lim2 := NewBurstLimiterWithStore(3, store)
+
+	if !lim1.AllowN(2) {
+		t.Fatalf("lim1.AllowN(2) = false, want true")
+	}
+	if lim2.AllowN(2) {
+		t.Fatalf("lim2.AllowN(2) = true, want false, quota is shared")
+	}
+	if !lim2.AllowN(1) {
+		t.Fatalf("lim2.AllowN(1) = false, want true")
+	}
+	if got := lim1.Tokens(); got != 0 {
+		t.Fatalf("lim1.Tokens() = %d, want %d", got, 0)
+	}
+	lim1.PutTokenN(5)
+	if got := lim2.Tokens(); got != 3 {
+		t.Fatalf("lim2.Tokens() = %d, want %d, overflowed tokens are dropped", got, 3)
+	}
+}
+
+func TestStoreWaitPutByOtherLimiter(t *testing.T) {
+	store := NewMemoryStore(1)
+	lim1 := NewBurstLimiterWithStore(1, store)
+	lim2 := NewBurstLimiterWithStore(1, store)
+
+	if !lim1.Allow() {
+		t.Fatalf("lim1.Allow() = false, want true")
+	}
+	go func() {
+		time.Sleep(d)
+		lim1.PutToken()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := lim2.Wait(ctx); err != nil {
+		t.Fatalf("lim2.Wait() = %v, want nil", err)
+	}
+}
+
+func TestStoreReservationOrder(t *testing.T) {
+	store := NewMemoryStore(0)
+	other := NewBurstLimiterWithStore(2, store)
+	lim := NewBurstLimiterWithStore(2, store)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	r1 := lim.ReserveN(ctx, 2)
+	r2 := lim.ReserveN(ctx, 1)
+	if r1.Ready() || r2.Ready() {
+		t.Fatalf("Reservations are ready on an empty bucket")
+	}
+
+	// one token put back by another limiter goes to the first Reservation, not the ready-able second one
+	other.PutToken()
+	lim.notifyTokensChanged()
+	if r1.Ready() || r2.Ready() {
+		t.Fatalf("r1.Ready() = %t, r2.Ready() = %t, want false, false", r1.Ready(), r2.Ready())
+	}
+
+	other.PutTokenN(2)
+	done := make(chan error, 2)
+	go func() { done <- r1.Wait(ctx) }()
+	go func() { done <- r2.Wait(ctx) }()
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("Wait() = %v, want nil", err)
+		}
+	}
+	if !r1.Ready() || !r2.Ready() {
+		t.Fatalf("r1.Ready() = %t, r2.Ready() = %t, want true, true", r1.Ready(), r2.Ready())
+	}
+	r1.PutToken()
+	r2.PutToken()
+	if got := lim.Tokens(); got != 2 {
+		t.Fatalf("Tokens() = %d, want %d", got, 2)
+	}
+}
+
+type errStore struct{ err error }
+
+func (s errStore) Tokens(ctx context.Context) (int, error)                 { return 0, s.err }
+func (s errStore) TakeTokens(ctx context.Context, min, n int) (int, error) { return 0, s.err }
+func (s errStore) PutTokens(ctx context.Context, n, burst int) error       { return s.err }
+
+func TestStoreErrors(t *testing.T) {
+	errBroken := errors.New("broken store")
+	lim := NewBurstLimiterWithStore(1, errStore{err: errBroken})
+	var mu sync.Mutex
+	var handled []error
+	lim.SetStoreErrorHandler(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, err)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := lim.Wait(ctx); !errors.Is(err, errBroken) {
+		t.Fatalf("Wait() = %v, want %v", err, errBroken)
+	}
+	if err := lim.Reserve(ctx).Wait(ctx); !errors.Is(err, errBroken) {
+		t.Fatalf("Reserve().Wait() = %v, want %v", err, errBroken)
+	}
+
+	mu.Lock()
+	handled = nil
+	mu.Unlock()
+	if lim.Allow() {
+		t.Fatalf("Allow() = true, want false")
+	}
+	lim.PutToken()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) != 2 {
+		t.Fatalf("store errors handled = %v, want 2 of %v", handled, errBroken)
+	}
+	for _, err := range handled {
+		if !errors.Is(err, errBroken) {
+			t.Fatalf("store error handled = %v, want %v", err, errBroken)
+		}
+	}
+}
+
+func TestFileStoreSharedQuota(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bucket")
+	lim1 := NewBurstLimiterWithStore(3, NewFileStore(path, 3))
+	lim2 := NewBurstLimiterWithStore(3, NewFileStore(path, 3))
+
+	if !lim1.AllowN(2) {
+		t.Fatalf("lim1.AllowN(2) = false, want true")
+	}
+	if lim2.AllowN(2) {
+		t.Fatalf("lim2.AllowN(2) = true, want false, quota is shared")
+	}
+	if !lim2.AllowN(1) {
+		t.Fatalf("lim2.AllowN(1) = false, want true")
+	}
+	lim1.PutTokenN(5)
+	if got := lim2.Tokens(); got != 3 {
+		t.Fatalf("lim2.Tokens() = %d, want %d, overflowed tokens are dropped", got, 3)
+	}
+
+	// the bucket survives the limiters
+	lim3 := NewBurstLimiterWithStore(3, NewFileStore(path, 0))
+	if got := lim3.Tokens(); got != 3 {
+		t.Fatalf("lim3.Tokens() = %d, want %d", got, 3)
+	}
+}
+
+const fileStoreHelperEnv = "RATE_FILE_STORE_HELPER"
+
+// TestFileStoreHelperProcess is not a real test, it drains the bucket in a child process
+// of TestFileStoreSharedAcrossProcesses, and prints the tokens taken.
+func TestFileStoreHelperProcess(t *testing.T) {
+	path := os.Getenv(fileStoreHelperEnv)
+	if path == "" {
+		return
+	}
+	fmt.Println(drainFileStore(path))
+}
+
+func drainFileStore(path string) int {
+	lim := NewBurstLimiterWithStore(1, NewFileStore(path, 0))
+	var took int
+	for lim.Allow() {
+		took++
+	}
+	return took
+}
+
+func TestFileStoreSharedAcrossProcesses(t *testing.T) {
+	const tokens, procs = 200, 3
+	path := filepath.Join(t.TempDir(), "bucket")
+	if err := os.WriteFile(path, []byte(strconv.Itoa(tokens)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var took int
+	for i := 0; i < procs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^TestFileStoreHelperProcess$")
+			cmd.Env = append(os.Environ(), fileStoreHelperEnv+"="+path)
+			out, err := cmd.Output()
+			if err != nil {
+				t.Errorf("helper process: %v", err)
+				return
+			}
+			lines := strings.Fields(string(out))
+			if len(lines) == 0 {
+				t.Errorf("helper process printed %q, want tokens taken", out)
+				return
+			}
+			n, err := strconv.Atoi(lines[0])
+			if err != nil {
+				t.Errorf("helper process printed %q, want tokens taken", out)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			took += n
+		}()
+	}
+	n := drainFileStore(path)
+	wg.Wait()
+	if took += n; took != tokens {
+		t.Fatalf("tokens taken by %d processes = %d, want %d", procs+1, took, tokens)
+	}
+}
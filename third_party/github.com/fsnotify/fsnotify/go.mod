@@ -0,0 +1,14 @@
+module github.com/searKing/golang/third_party/github.com/fsnotify/fsnotify
+
+go 1.18
+
+require (
+	github.com/fsnotify/fsnotify v1.6.0
+	github.com/searKing/golang/go v1.2.68
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	golang.org/x/sys v0.7.0 // indirect
+	golang.org/x/time v0.3.0 // indirect
+)
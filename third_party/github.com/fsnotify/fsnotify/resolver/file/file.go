@@ -0,0 +1,266 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package file implements a resolver for file targets, as "file:///etc/backends.yaml",
+// endpoints are read from the JSON or YAML file, and updated whenever the file changes.
+//
+// The file lists endpoints, as:
+//
+//	endpoints:
+//	  - addr: 10.0.0.1:8080
+//	    weight: 3
+//	    metadata:
+//	      zone: a
+//	  - addr: 10.0.0.2:8080
+//
+// Files ending with ".json" are read as JSON, others as YAML.
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/searKing/golang/go/net/resolver"
+	"gopkg.in/yaml.v3"
+)
+
+const fileScheme = "file"
+
+func init() {
+	resolver.Register(NewBuilderWithScheme(fileScheme))
+}
+
+// Endpoint is an endpoint listed in the file.
+type Endpoint struct {
+	Addr string `json:"addr" yaml:"addr"`
+	// Weight is carried in the Address Metadata as "weight", see resolver.AddressWeight.
+	Weight   *int           `json:"weight,omitempty" yaml:"weight,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// Endpoints is the content of the file.
+type Endpoints struct {
+	Endpoints []Endpoint `json:"endpoints" yaml:"endpoints"`
+}
+
+// Addresses returns Addresses of endpoints, Metadata is a map[string]any of metadata and weight.
+func (e Endpoints) Addresses() []resolver.Address {
+	var addrs []resolver.Address
+	for _, ep := range e.Endpoints {
+		addr := resolver.Address{Addr: ep.Addr}
+		if ep.Weight != nil || len(ep.Metadata) > 0 {
+			md := make(map[string]any, len(ep.Metadata)+1)
+			for k, v := range ep.Metadata {
+				md[k] = v
+			}
+			if ep.Weight != nil {
+				md["weight"] = *ep.Weight
+			}
+			addr.Metadata = md
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// ReadEndpoints reads Endpoints from the file named name, as JSON if name ends with ".json", or as YAML.
+func ReadEndpoints(name string) (Endpoints, error) {
+	var e Endpoints
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return e, err
+	}
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		err = json.Unmarshal(data, &e)
+	} else {
+		err = yaml.Unmarshal(data, &e)
+	}
+	if err != nil {
+		return e, fmt.Errorf("resolver: parse endpoints in %s: %w", name, err)
+	}
+	for i, ep := range e.Endpoints {
+		if ep.Addr == "" {
+			return e, fmt.Errorf("resolver: endpoint #%d in %s: empty addr", i, name)
+		}
+	}
+	return e, nil
+}
+
+type builder struct {
+	scheme string
+	opts   []resolver.BuildOption
+}
+
+// NewBuilderWithScheme returns a builder of file resolvers for scheme,
+// which read the addresses from the file of the target and watch it for updates.
+func NewBuilderWithScheme(scheme string, opts ...resolver.BuildOption) resolver.Builder {
+	return &builder{scheme: scheme, opts: opts}
+}
+
+// Build reads the file of target, and watches it for updates.
+// Both "file:///abs/path" and "file:rel/path" are accepted.
+func (b *builder) Build(ctx context.Context, target resolver.Target, opts ...resolver.BuildOption) (resolver.Resolver, error) {
+	var opt resolver.Build
+	opt.ApplyOptions(b.opts...).ApplyOptions(opts...)
+	if target.Authority != "" {
+		return nil, fmt.Errorf("invalid (non-empty) authority: %v", target.Authority)
+	}
+	name := target.URL.Path
+	if name == "" {
+		name = target.URL.Opaque
+	}
+	if name == "" {
+		return nil, fmt.Errorf("resolver: empty file in target %q", target.URL.String())
+	}
+
+	r := &fileResolver{
+		name: filepath.Clean(name),
+		cc:   opt.ClientConn,
+		done: make(chan struct{}),
+	}
+	if opt.NewPicker != nil {
+		r.picker = opt.NewPicker()
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// watch the dir, as the file may be replaced by rename, or by symlink as ConfigMap of kubernetes does
+	if err := watcher.Add(filepath.Dir(r.name)); err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+	r.watcher = watcher
+	r.wg.Add(1)
+	go r.watch()
+	return r, nil
+}
+
+func (b *builder) Scheme() string {
+	return b.scheme
+}
+
+type fileResolver struct {
+	name    string
+	cc      resolver.ClientConn
+	picker  resolver.Picker
+	watcher *fsnotify.Watcher
+
+	mu       sync.Mutex
+	addrs    []resolver.Address
+	realName string // file linked to, to tell a symlink updated
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func (r *fileResolver) ResolveOneAddr(ctx context.Context, opts ...resolver.ResolveOneAddrOption) (resolver.Address, error) {
+	return resolver.PickOneAddr(ctx, r.picker, r.addresses(), opts...)
+}
+
+func (r *fileResolver) ResolveAddr(ctx context.Context, opts ...resolver.ResolveAddrOption) ([]resolver.Address, error) {
+	return r.addresses(), nil
+}
+
+// ResolveNow reads the file again.
+func (r *fileResolver) ResolveNow(ctx context.Context, opts ...resolver.ResolveNowOption) {
+	if err := r.reload(); err != nil && r.cc != nil {
+		r.cc.ReportError(err)
+	}
+}
+
+func (r *fileResolver) ResolveDone(ctx context.Context, doneInfo resolver.DoneInfo, opts ...resolver.ResolveDoneOption) {
+	if p, ok := r.picker.(resolver.DonePicker); ok {
+		p.Done(ctx, doneInfo)
+	}
+}
+
+func (r *fileResolver) Close() {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		_ = r.watcher.Close()
+	})
+	r.wg.Wait()
+}
+
+func (r *fileResolver) addresses() []resolver.Address {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.addrs
+}
+
+// reload reads the file, and updates the ClientConn with addresses read.
+// Addresses read last are kept if failed, or if the file is empty, as truncated to be written.
+func (r *fileResolver) reload() error {
+	if fi, err := os.Stat(r.name); err == nil && fi.Size() == 0 && r.addresses() != nil {
+		return nil
+	}
+	endpoints, err := ReadEndpoints(r.name)
+	if err != nil {
+		return err
+	}
+	addrs := endpoints.Addresses()
+	realName, _ := filepath.EvalSymlinks(r.name)
+	r.mu.Lock()
+	r.addrs = addrs
+	r.realName = realName
+	r.mu.Unlock()
+	if r.cc != nil {
+		return r.cc.UpdateState(resolver.State{Addresses: addrs})
+	}
+	return nil
+}
+
+func (r *fileResolver) watch() {
+	defer r.wg.Done()
+	for {
+		select {
+		case <-r.done:
+			return
+		case event, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			if !r.changed(event) {
+				continue
+			}
+			if err := r.reload(); err != nil && r.cc != nil {
+				r.cc.ReportError(err)
+			}
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			if r.cc != nil {
+				r.cc.ReportError(err)
+			}
+		}
+	}
+}
+
+// changed reports whether event changes the file, written or replaced, or linked to another file.
+func (r *fileResolver) changed(event fsnotify.Event) bool {
+	if filepath.Clean(event.Name) == r.name {
+		// removed, wait for the file to be created again
+		return event.Has(fsnotify.Write) || event.Has(fsnotify.Create)
+	}
+	realName, err := filepath.EvalSymlinks(r.name)
+	if err != nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return realName != r.realName
+}
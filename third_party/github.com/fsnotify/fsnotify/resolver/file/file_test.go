@@ -0,0 +1,153 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package file_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/net/resolver"
+	_ "github.com/searKing/golang/third_party/github.com/fsnotify/fsnotify/resolver/file"
+)
+
+type testClientConn struct {
+	states chan resolver.State
+	errs   chan error
+}
+
+func newTestClientConn() *testClientConn {
+	return &testClientConn{states: make(chan resolver.State, 10), errs: make(chan error, 10)}
+}
+
+func (cc *testClientConn) UpdateState(s resolver.State) error {
+	cc.states <- s
+	return nil
+}
+
+func (cc *testClientConn) ReportError(err error) {
+	cc.errs <- err
+}
+
+func (cc *testClientConn) waitState(t *testing.T) resolver.State {
+	t.Helper()
+	select {
+	case s := <-cc.states:
+		return s
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for UpdateState")
+	}
+	return resolver.State{}
+}
+
+func addrsOf(addrs []resolver.Address) []string {
+	var s []string
+	for _, addr := range addrs {
+		s = append(s, addr.Addr)
+	}
+	return s
+}
+
+func TestFileResolver(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "backends.yaml")
+	if err := os.WriteFile(name, []byte(`
+endpoints:
+  - addr: 127.0.0.1:8080
+    weight: 3
+    metadata:
+      zone: a
+  - addr: 127.0.0.1:8081
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cc := newTestClientConn()
+	r, err := resolver.NewResolver(context.Background(), "file://"+name, resolver.WithBuildClientConn(cc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	s := cc.waitState(t)
+	if got := addrsOf(s.Addresses); len(got) != 2 || got[0] != "127.0.0.1:8080" || got[1] != "127.0.0.1:8081" {
+		t.Fatalf("addresses %v", got)
+	}
+	if w := resolver.AddressWeight(s.Addresses[0]); w != 3 {
+		t.Errorf("weight %d, want 3", w)
+	}
+	if zone := s.Addresses[0].Metadata.(map[string]any)["zone"]; zone != "a" {
+		t.Errorf("zone %v, want a", zone)
+	}
+	if w := resolver.AddressWeight(s.Addresses[1]); w != 1 {
+		t.Errorf("weight %d, want default 1", w)
+	}
+
+	// written in place
+	if err := os.WriteFile(name, []byte("endpoints: [{addr: 127.0.0.1:9090}]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		s = cc.waitState(t)
+		// a write may be observed as truncated and written
+		if got := addrsOf(s.Addresses); len(got) == 1 && got[0] == "127.0.0.1:9090" {
+			break
+		}
+	}
+
+	// replaced by rename, as editors do
+	tmp := filepath.Join(dir, ".backends.yaml.tmp")
+	if err := os.WriteFile(tmp, []byte("endpoints: [{addr: 127.0.0.1:9091}]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		s = cc.waitState(t)
+		if got := addrsOf(s.Addresses); len(got) == 1 && got[0] == "127.0.0.1:9091" {
+			break
+		}
+	}
+
+	// malformed, addresses are kept
+	if err := os.WriteFile(name, []byte("endpoints: [{weight: 1}]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-cc.errs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for ReportError")
+	}
+	addr, err := r.ResolveOneAddr(context.Background())
+	if err != nil || addr.Addr != "127.0.0.1:9091" {
+		t.Errorf("ResolveOneAddr() = %v, %v, want addresses kept", addr, err)
+	}
+}
+
+func TestFileResolverJSON(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "backends.json")
+	if err := os.WriteFile(name, []byte(`{"endpoints": [{"addr": "127.0.0.1:8080", "weight": 2}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := resolver.NewResolver(context.Background(), "file://"+name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	addrs, err := r.ResolveAddr(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || addrs[0].Addr != "127.0.0.1:8080" || resolver.AddressWeight(addrs[0]) != 2 {
+		t.Errorf("ResolveAddr() = %+v", addrs)
+	}
+
+	if _, err := resolver.NewResolver(context.Background(), "file://"+name+".not-exist"); err == nil {
+		t.Errorf("expect error of a file not exist")
+	}
+}
@@ -0,0 +1,7 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package compress
+
+// PlaceHolder file, so this can be seen as a module.
@@ -0,0 +1,5 @@
+module github.com/searKing/golang/third_party/github.com/klauspost/compress
+
+go 1.18
+
+require github.com/klauspost/compress v1.16.7
@@ -0,0 +1,30 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressor compresses in zstd format,
+// it's a Compressor of RotateFile in github.com/searKing/golang/go/os.
+type Compressor struct {
+	// Level is the compression level, zstd.SpeedDefault if 0.
+	Level zstd.EncoderLevel
+}
+
+// Ext returns ".zst"
+func (c Compressor) Ext() string { return ".zst" }
+
+// NewWriter returns a zstd writer of w.
+func (c Compressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	level := c.Level
+	if level == 0 {
+		level = zstd.SpeedDefault
+	}
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(level))
+}
@@ -0,0 +1,47 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+
+	zstd_ "github.com/searKing/golang/third_party/github.com/klauspost/compress/zstd"
+)
+
+func TestCompressor(t *testing.T) {
+	want := strings.Repeat("hello zstd\n", 100)
+	var buf bytes.Buffer
+	w, err := zstd_.Compressor{}.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, want); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() >= len(want) {
+		t.Errorf("compressed %d bytes into %d bytes", len(want), buf.Len())
+	}
+
+	r, err := zstd.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("decompressed %q, want %q", got, want)
+	}
+}
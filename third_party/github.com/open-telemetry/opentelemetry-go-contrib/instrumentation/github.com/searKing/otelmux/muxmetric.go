@@ -0,0 +1,38 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package otelmux
+
+import (
+	otelcontrib "go.opentelemetry.io/contrib"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/global"
+)
+
+var (
+	// InstrumentationName is the name of this instrumentation package.
+	InstrumentationName = "github.com/searKing/golang/third_party/github.com/open-telemetry/opentelemetry-go-contrib/instrumentation/github.com/searKing/otelmux"
+	// InstrumentationVersion is the version of this instrumentation package.
+	InstrumentationVersion = otelcontrib.SemVersion()
+
+	// AttrsFilter is a filter before Report
+	AttrsFilter = func(attrs ...attribute.KeyValue) []attribute.KeyValue {
+		return attrs
+	}
+)
+
+const (
+	// KeyMuxRoute is the name of the route matched, "" if no route matched.
+	KeyMuxRoute = attribute.Key("mux.route")
+	// KeyMuxMatched reports whether a route matched.
+	KeyMuxMatched = attribute.Key("mux.matched")
+	// KeyMuxConnState is the state of a connection, as mux.ConnState.String() of mux.ConnStateNew, mux.ConnStateActive,
+	// mux.ConnStateIdle, mux.ConnStateHijacked and mux.ConnStateClosed.
+	KeyMuxConnState = attribute.Key("mux.conn_state")
+)
+
+func Meter() metric.Meter {
+	return global.MeterProvider().Meter(InstrumentationName, metric.WithInstrumentationVersion(InstrumentationVersion))
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package otelmux
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/searKing/golang/go/net/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/instrument"
+)
+
+const uBytes = "By"
+
+var _ mux.Observer = (*Observer)(nil)
+
+// Observer reports metrics of connections multiplexed by OpenTelemetry,
+// it's a Observer of github.com/searKing/golang/go/net/mux, for both mux.Server and mux.ServeMux.
+type Observer struct {
+	// Attrs are attributes added to every metric reported, as the port served.
+	Attrs []attribute.KeyValue
+
+	connsActive       instrument.Int64UpDownCounter
+	connStatesCounter instrument.Int64Counter
+	routedCounter     instrument.Int64Counter
+	sniffTimeouts     instrument.Int64Counter
+	sniffDuration     instrument.Float64Histogram
+	sniffedBytes      instrument.Int64Histogram
+}
+
+// NewObserver returns an Observer with instruments created by Meter.
+func NewObserver(attrs ...attribute.KeyValue) (*Observer, error) {
+	o := &Observer{Attrs: attrs}
+	var err error
+	// "mux.conn_state"
+	o.connsActive, err = Meter().Int64UpDownCounter("mux_server_conns_active",
+		instrument.WithDescription("Number of connections in a non-terminal state, as New, Active and Idle, on the server."))
+	if err != nil {
+		return nil, err
+	}
+	// "mux.conn_state"
+	o.connStatesCounter, err = Meter().Int64Counter("mux_server_conn_states_total",
+		instrument.WithDescription("Total number of connections changed to a state on the server."))
+	if err != nil {
+		return nil, err
+	}
+	// "mux.route", "mux.matched"
+	o.routedCounter, err = Meter().Int64Counter("mux_sniffed_total",
+		instrument.WithDescription("Total number of connections sniffed, matched by a route or not."))
+	if err != nil {
+		return nil, err
+	}
+	o.sniffTimeouts, err = Meter().Int64Counter("mux_sniff_timeouts_total",
+		instrument.WithDescription("Total number of connections not matched as the read timeout of sniffing exceeded."))
+	if err != nil {
+		return nil, err
+	}
+	// "mux.route", "mux.matched"
+	o.sniffDuration, err = Meter().Float64Histogram("mux_sniff_duration_seconds",
+		instrument.WithDescription("Histogram of latency (seconds) of sniffing until a route matched or all failed."),
+		instrument.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	// "mux.route", "mux.matched"
+	o.sniffedBytes, err = Meter().Int64Histogram("mux_sniffed_bytes",
+		instrument.WithDescription("Histogram of bytes read by matchers before a route matched or all failed."),
+		instrument.WithUnit(uBytes))
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// ConnStateChanged reports connections of states, from is to for a new connection.
+func (o *Observer) ConnStateChanged(c net.Conn, from, to mux.ConnState) {
+	ctx := context.Background()
+	if from != to && !isTerminalConnState(from) {
+		o.connsActive.Add(ctx, -1, o.attrs(KeyMuxConnState.String(from.String()))...)
+	}
+	if !isTerminalConnState(to) {
+		o.connsActive.Add(ctx, 1, o.attrs(KeyMuxConnState.String(to.String()))...)
+	}
+	o.connStatesCounter.Add(ctx, 1, o.attrs(KeyMuxConnState.String(to.String()))...)
+}
+
+// Sniffed reports the route matched, bytes and latency of sniffing.
+func (o *Observer) Sniffed(c net.Conn, route string, sniffed int, cost time.Duration, timeout bool) {
+	ctx := context.Background()
+	attrs := o.attrs(KeyMuxRoute.String(route), KeyMuxMatched.Bool(route != ""))
+	o.routedCounter.Add(ctx, 1, attrs...)
+	o.sniffDuration.Record(ctx, cost.Seconds(), attrs...)
+	o.sniffedBytes.Record(ctx, int64(sniffed), attrs...)
+	if timeout {
+		o.sniffTimeouts.Add(ctx, 1, o.attrs()...)
+	}
+}
+
+func (o *Observer) attrs(attrs ...attribute.KeyValue) []attribute.KeyValue {
+	attrs = append(append([]attribute.KeyValue(nil), o.Attrs...), attrs...)
+	filter := AttrsFilter
+	if filter != nil {
+		return filter(attrs...)
+	}
+	return attrs
+}
+
+// isTerminalConnState reports whether state is terminal, as mux.ConnStateHijacked and mux.ConnStateClosed.
+func isTerminalConnState(state mux.ConnState) bool {
+	return state == mux.ConnStateHijacked || state == mux.ConnStateClosed
+}
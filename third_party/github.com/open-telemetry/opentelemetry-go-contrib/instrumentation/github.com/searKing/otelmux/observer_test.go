@@ -0,0 +1,83 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package otelmux_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/net/mux"
+	"github.com/searKing/golang/third_party/github.com/open-telemetry/opentelemetry-go-contrib/instrumentation/github.com/searKing/otelmux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/global"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestObserver(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	global.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	o, err := otelmux.NewObserver(attribute.String("port", "8080"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// two connections, one matched, one timed out
+	o.ConnStateChanged(nil, mux.ConnStateNew, mux.ConnStateNew)
+	o.ConnStateChanged(nil, mux.ConnStateNew, mux.ConnStateActive)
+	o.ConnStateChanged(nil, mux.ConnStateNew, mux.ConnStateNew)
+	o.Sniffed(nil, "ssh", 4, time.Millisecond, false)
+	o.Sniffed(nil, "", 2, time.Second, true)
+	o.ConnStateChanged(nil, mux.ConnStateActive, mux.ConnStateHijacked)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	sums := map[string]map[string]int64{}
+	histograms := map[string]uint64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				sums[m.Name] = map[string]int64{}
+				for _, dp := range data.DataPoints {
+					if v, ok := dp.Attributes.Value("port"); !ok || v.AsString() != "8080" {
+						t.Errorf("%s: expect attribute port", m.Name)
+					}
+					var key string
+					if v, ok := dp.Attributes.Value(otelmux.KeyMuxConnState); ok {
+						key = v.AsString()
+					}
+					if v, ok := dp.Attributes.Value(otelmux.KeyMuxRoute); ok {
+						key = "route:" + v.AsString()
+					}
+					sums[m.Name][key] += dp.Value
+				}
+			case metricdata.Histogram:
+				for _, dp := range data.DataPoints {
+					histograms[m.Name] += dp.Count
+				}
+			}
+		}
+	}
+
+	if got := sums["mux_server_conns_active"]; got["New"] != 1 || got["Active"] != 0 || got["Hijacked"] != 0 {
+		t.Errorf("mux_server_conns_active = %v, want New 1 and others 0", got)
+	}
+	if got := sums["mux_server_conn_states_total"]; got["New"] != 2 || got["Active"] != 1 || got["Hijacked"] != 1 {
+		t.Errorf("mux_server_conn_states_total = %v", got)
+	}
+	if got := sums["mux_sniffed_total"]; got["route:ssh"] != 1 || got["route:"] != 1 {
+		t.Errorf("mux_sniffed_total = %v", got)
+	}
+	if got := sums["mux_sniff_timeouts_total"]; got[""] != 1 {
+		t.Errorf("mux_sniff_timeouts_total = %v", got)
+	}
+	if histograms["mux_sniff_duration_seconds"] != 2 || histograms["mux_sniffed_bytes"] != 2 {
+		t.Errorf("histograms = %v", histograms)
+	}
+}
@@ -3,6 +3,6 @@ module github.com/searKing/golang/third_party/github.com/syndtr/goleveldb
 go 1.16
 
 require (
-	github.com/searKing/golang/go v1.2.29
+	github.com/searKing/golang/go v1.2.68
 	github.com/syndtr/goleveldb v1.0.0
 )
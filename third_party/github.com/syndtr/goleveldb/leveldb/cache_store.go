@@ -0,0 +1,57 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package leveldb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// CacheStore holds responses cached under keys prefixed of a LevelDB,
+// it implements the Storage of github.com/searKing/golang/go/net/http/httpcache,
+// so that responses cached survive restarts.
+type CacheStore struct {
+	db     *leveldb.DB
+	prefix string
+}
+
+// NewCacheStore returns a CacheStore stores responses in db, with keys prefixed by prefix,
+// so that a db can be shared.
+func NewCacheStore(db *leveldb.DB, prefix string) *CacheStore {
+	return &CacheStore{db: db, prefix: prefix}
+}
+
+// Get returns the value of key, false if not found.
+func (s *CacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	v, err := s.db.Get([]byte(s.prefix+key), nil)
+	if err != nil {
+		if errors.Is(err, leveldb.ErrNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return v, true, nil
+}
+
+// Set sets the value of key.
+func (s *CacheStore) Set(ctx context.Context, key string, value []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.db.Put([]byte(s.prefix+key), value, nil)
+}
+
+// Delete deletes the value of key, no error if not found.
+func (s *CacheStore) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.db.Delete([]byte(s.prefix+key), nil)
+}
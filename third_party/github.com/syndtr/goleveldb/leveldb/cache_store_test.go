@@ -0,0 +1,45 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package leveldb_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/syndtr/goleveldb/leveldb"
+
+	leveldb_ "github.com/searKing/golang/third_party/github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestCacheStore(t *testing.T) {
+	db, err := leveldb.OpenFile(filepath.Join(t.TempDir(), "cache"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	s := leveldb_.NewCacheStore(db, "httpcache/")
+	other := leveldb_.NewCacheStore(db, "other/")
+
+	if _, ok, err := s.Get(ctx, "a"); ok || err != nil {
+		t.Fatalf("Get(a) = %t, %v, want false, nil", ok, err)
+	}
+	if err := s.Set(ctx, "a", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, err := s.Get(ctx, "a"); !ok || err != nil || string(v) != "1" {
+		t.Fatalf("Get(a) = %q, %t, %v, want %q, true, nil", v, ok, err, "1")
+	}
+	if _, ok, err := other.Get(ctx, "a"); ok || err != nil {
+		t.Fatalf("Get(a) of other prefix = %t, %v, want false, nil", ok, err)
+	}
+	if err := s.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := s.Get(ctx, "a"); ok || err != nil {
+		t.Fatalf("Get(a) = %t, %v, want false, nil", ok, err)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package leveldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// RateStore holds the unconsumed tokens of a token bucket under a key of a LevelDB,
+// it implements the Store of github.com/searKing/golang/go/time/rate,
+// so that BurstLimiters sharing a DB share the same bucket, and the bucket survives restarts.
+// Every method runs in a LevelDB transaction.
+// A LevelDB is opened by one process at a time, so the bucket is shared within that process only,
+// use rate.NewFileStore to share a bucket among processes.
+type RateStore struct {
+	db     *leveldb.DB
+	key    []byte
+	tokens int // tokens of the bucket if key is absent
+}
+
+// NewRateStore returns a RateStore of the bucket stored as key in db,
+// the bucket is inited with tokens if key is absent.
+func NewRateStore(db *leveldb.DB, key []byte, tokens int) *RateStore {
+	return &RateStore{db: db, key: key, tokens: tokens}
+}
+
+// Tokens returns the unconsumed tokens in the bucket.
+func (s *RateStore) Tokens(ctx context.Context) (int, error) {
+	var tokens int
+	err := s.update(ctx, func(t int) (int, bool) {
+		tokens = t
+		return t, false
+	})
+	return tokens, err
+}
+
+// TakeTokens consumes tokens from the bucket, n at most and min at least,
+// and returns the tokens consumed, 0 if less than min tokens are unconsumed.
+func (s *RateStore) TakeTokens(ctx context.Context, min, n int) (int, error) {
+	var took int
+	err := s.update(ctx, func(tokens int) (int, bool) {
+		if tokens < min || tokens <= 0 || n <= 0 {
+			return tokens, false
+		}
+		took = n
+		if tokens < took {
+			took = tokens
+		}
+		return tokens - took, true
+	})
+	if err != nil {
+		return 0, err
+	}
+	return took, nil
+}
+
+// PutTokens refills n tokens into the bucket, tokens overflowing burst are dropped.
+func (s *RateStore) PutTokens(ctx context.Context, n, burst int) error {
+	return s.update(ctx, func(tokens int) (int, bool) {
+		tokens += n
+		// drop if overflowed
+		if tokens > burst {
+			tokens = burst
+		}
+		return tokens, true
+	})
+}
+
+// update reads the tokens and stores the tokens returned by f if changed, atomically.
+func (s *RateStore) update(ctx context.Context, f func(tokens int) (int, bool)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	tr, err := s.db.OpenTransaction()
+	if err != nil {
+		return err
+	}
+	defer tr.Discard()
+
+	tokens := s.tokens
+	v, err := tr.Get(s.key, nil)
+	if err == nil {
+		tokens, err = strconv.Atoi(string(v))
+		if err != nil {
+			return fmt.Errorf("leveldb: malformed tokens of %s: %w", s.key, err)
+		}
+	} else if !errors.Is(err, leveldb.ErrNotFound) {
+		return err
+	}
+
+	tokens, changed := f(tokens)
+	if !changed {
+		return nil
+	}
+	if err := tr.Put(s.key, []byte(strconv.Itoa(tokens)), nil); err != nil {
+		return err
+	}
+	return tr.Commit()
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package leveldb_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/searKing/golang/go/time/rate"
+	"github.com/syndtr/goleveldb/leveldb"
+
+	leveldb_ "github.com/searKing/golang/third_party/github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestRateStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rate")
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key := []byte("bucket")
+	s := leveldb_.NewRateStore(db, key, 3)
+
+	if got, err := s.TakeTokens(ctx, 2, 2); err != nil || got != 2 {
+		t.Fatalf("TakeTokens(2, 2) = %d, %v, want %d, nil", got, err, 2)
+	}
+	if got, err := s.TakeTokens(ctx, 2, 2); err != nil || got != 0 {
+		t.Fatalf("TakeTokens(2, 2) = %d, %v, want %d, nil", got, err, 0)
+	}
+	if got, err := s.TakeTokens(ctx, 0, 2); err != nil || got != 1 {
+		t.Fatalf("TakeTokens(0, 2) = %d, %v, want %d, nil", got, err, 1)
+	}
+	if err := s.PutTokens(ctx, 5, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	// tokens survive reopen of the db
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db, err = leveldb.OpenFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	s = leveldb_.NewRateStore(db, key, 0)
+	if got, err := s.Tokens(ctx); err != nil || got != 3 {
+		t.Fatalf("Tokens() = %d, %v, want %d, nil", got, err, 3)
+	}
+}
+
+func TestRateStoreConcurrent(t *testing.T) {
+	db, err := leveldb.OpenFile(filepath.Join(t.TempDir(), "rate"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	const n = 100
+	key := []byte("bucket")
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var took int
+	for i := 0; i < 2*n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// every goroutine owns a store, as every BurstLimiter does
+			got, err := leveldb_.NewRateStore(db, key, n).TakeTokens(ctx, 1, 1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			took += got
+		}()
+	}
+	wg.Wait()
+	if took != n {
+		t.Fatalf("took %d tokens, want %d", took, n)
+	}
+}
+
+func TestRateStoreBurstLimiters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rate")
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	key := []byte("bucket")
+	lim1 := rate.NewBurstLimiterWithStore(3, leveldb_.NewRateStore(db, key, 3))
+	lim2 := rate.NewBurstLimiterWithStore(3, leveldb_.NewRateStore(db, key, 3))
+
+	if !lim1.AllowN(2) {
+		t.Fatalf("lim1.AllowN(2) = false, want true")
+	}
+	if lim2.AllowN(2) {
+		t.Fatalf("lim2.AllowN(2) = true, want false, quota is shared")
+	}
+	if !lim2.AllowN(1) {
+		t.Fatalf("lim2.AllowN(1) = false, want true")
+	}
+
+	// lim2 waits for the tokens put back by lim1
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		lim1.PutTokenN(3)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := lim2.WaitN(ctx, 3); err != nil {
+		t.Fatalf("lim2.WaitN(3) = %v, want nil", err)
+	}
+	if got := lim1.Tokens(); got != 0 {
+		t.Fatalf("lim1.Tokens() = %d, want %d", got, 0)
+	}
+}
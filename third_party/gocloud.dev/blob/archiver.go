@@ -0,0 +1,73 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"gocloud.dev/blob"
+)
+
+// Archiver uploads files into a blob bucket,
+// it's a RotateFileArchiver of RotateFile in github.com/searKing/golang/go/os.
+type Archiver struct {
+	Bucket *blob.Bucket
+	// Prefix is prepended to the base name of the file, as the key of the blob.
+	Prefix string
+	// RemoveAfterArchived removes the file once uploaded.
+	RemoveAfterArchived bool
+	// WriterOptions is passed to Bucket.NewWriter, optional.
+	WriterOptions *blob.WriterOptions
+}
+
+// NewArchiver returns an Archiver uploads files into bucket, keyed by prefix + base name of the file.
+func NewArchiver(bucket *blob.Bucket, prefix string) *Archiver {
+	return &Archiver{Bucket: bucket, Prefix: prefix}
+}
+
+// Key returns the key of the blob the file named name is uploaded as.
+func (a *Archiver) Key(name string) string {
+	return path.Join(a.Prefix, filepath.Base(name))
+}
+
+// Archive uploads the file named name into the bucket.
+func (a *Archiver) Archive(ctx context.Context, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	key := a.Key(name)
+	if err := a.upload(ctx, key, f); err != nil {
+		return fmt.Errorf("blob: upload %s as %s: %w", name, key, err)
+	}
+	if a.RemoveAfterArchived {
+		_ = f.Close()
+		return os.Remove(name)
+	}
+	return nil
+}
+
+func (a *Archiver) upload(ctx context.Context, key string, r io.Reader) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w, err := a.Bucket.NewWriter(ctx, key, a.WriterOptions)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		// abort the write, instead of committing a partial blob
+		cancel()
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
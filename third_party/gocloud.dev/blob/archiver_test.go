@@ -0,0 +1,52 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blob_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/fileblob"
+	"gocloud.dev/blob/memblob"
+
+	blob_ "github.com/searKing/golang/third_party/gocloud.dev/blob"
+)
+
+func TestArchiver(t *testing.T) {
+	bucketDir := t.TempDir()
+	fileBucket, err := fileblob.OpenBucket(bucketDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, bucket := range map[string]*blob.Bucket{"memblob": memblob.OpenBucket(nil), "fileblob": fileBucket} {
+		t.Run(name, func(t *testing.T) {
+			defer bucket.Close()
+			ctx := context.Background()
+			file := filepath.Join(t.TempDir(), "test.log.1.gz")
+			if err := os.WriteFile(file, []byte("segment"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			a := blob_.NewArchiver(bucket, "logs")
+			a.RemoveAfterArchived = true
+			if err := a.Archive(ctx, file); err != nil {
+				t.Fatal(err)
+			}
+			got, err := bucket.ReadAll(ctx, "logs/test.log.1.gz")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "segment" {
+				t.Errorf("archived %q, want %q", got, "segment")
+			}
+			if _, err := os.Stat(file); !os.IsNotExist(err) {
+				t.Errorf("file is not removed after archived: %v", err)
+			}
+		})
+	}
+}
@@ -3,9 +3,9 @@ module github.com/searKing/golang/third_party/google.golang.org/grpc
 go 1.16
 
 require (
-	github.com/searKing/golang/go v1.2.43
-	golang.org/x/net v0.1.0
-	golang.org/x/time v0.2.0
+	github.com/searKing/golang/go v1.2.68
+	golang.org/x/net v0.9.0
+	golang.org/x/time v0.3.0
 	google.golang.org/genproto v0.0.0-20221107162902-2d387536bcdd
 	google.golang.org/grpc v1.50.1
 )
@@ -72,3 +72,31 @@ func StreamClientInterceptor(b int, timeout time.Duration) grpc.StreamClientInte
 		return streamer(ctx, desc, cc, method, opts...)
 	}
 }
+
+// UnaryClientInterceptorWithLimiter returns a new unary client interceptor that performs request burst limiting by limiter.
+// timeout ResourceExhausted if cost more than timeout to get a token, take effect if timeout > 0
+func UnaryClientInterceptorWithLimiter(limiter Limiter, timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		done, err := waitLimiter(ctx, limiter, timeout)
+		if err != nil {
+			return status.Errorf(codes.ResourceExhausted,
+				"%s is rejected by burstlimit unary client middleware, please retry later: %s", method, err)
+		}
+		defer done()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptorWithLimiter returns a new streaming client interceptor that performs burst limiting on the request by limiter.
+// timeout ResourceExhausted if cost more than timeout to get a token, take effect if timeout > 0
+func StreamClientInterceptorWithLimiter(limiter Limiter, timeout time.Duration) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		done, err := waitLimiter(ctx, limiter, timeout)
+		if err != nil {
+			return nil, status.Errorf(codes.ResourceExhausted,
+				"%s is rejected by burstlimit stream client middleware, please retry later: %s", method, err)
+		}
+		defer done()
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
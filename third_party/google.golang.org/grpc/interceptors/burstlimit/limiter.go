@@ -0,0 +1,34 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package burstlimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limiter is what the interceptors require of a Limiter of github.com/searKing/golang/go/time/rate.
+// Wait is called before the request, PutToken is called once the request is done if implemented, as by BurstLimiter.
+type Limiter interface {
+	// Wait blocks until an event is permitted to happen.
+	Wait(ctx context.Context) error
+}
+
+// waitLimiter blocks until limiter permits the request,
+// timeout returns error if cost more than timeout to get a token, take effect if timeout > 0
+func waitLimiter(ctx context.Context, limiter Limiter, timeout time.Duration) (done func(), err error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if err := limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	if lim, ok := limiter.(interface{ PutToken() }); ok {
+		return lim.PutToken, nil
+	}
+	return func() {}, nil
+}
@@ -72,3 +72,31 @@ func StreamServerInterceptor(b int, timeout time.Duration) grpc.StreamServerInte
 		return handler(srv, stream)
 	}
 }
+
+// UnaryServerInterceptorWithLimiter returns a new unary server interceptors that performs request burst limiting by limiter.
+// timeout ResourceExhausted if cost more than timeout to get a token, take effect if timeout > 0
+func UnaryServerInterceptorWithLimiter(limiter Limiter, timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		done, err := waitLimiter(ctx, limiter, timeout)
+		if err != nil {
+			return nil, status.Errorf(codes.ResourceExhausted,
+				"%s is rejected by burstlimit unary server middleware, please retry later: %s", info.FullMethod, err)
+		}
+		defer done()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptorWithLimiter returns a new streaming server interceptor that performs burst limiting on the request by limiter.
+// timeout ResourceExhausted if cost more than timeout to get a token, take effect if timeout > 0
+func StreamServerInterceptorWithLimiter(limiter Limiter, timeout time.Duration) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		done, err := waitLimiter(stream.Context(), limiter, timeout)
+		if err != nil {
+			return status.Errorf(codes.ResourceExhausted,
+				"%s is rejected by burstlimit stream server middleware, please retry later: %s", info.FullMethod, err)
+		}
+		defer done()
+		return handler(srv, stream)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package circuitbreaker
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Breaker is a group of circuit breakers keyed, such as a Group of
+// github.com/searKing/golang/go/sync/breaker, so that the same breakers can be shared with http clients.
+type Breaker interface {
+	// Allow checks whether a request of key can proceed, an error returned if not.
+	// If allowed, done must be called once the request is done, reporting whether it succeeded.
+	Allow(key string) (done func(success bool), err error)
+}
+
+// KeyFunc returns the key of the circuit breaker for the call.
+type KeyFunc func(ctx context.Context, method string) string
+
+// SuccessFunc reports whether the call succeeded, as the circuit breaker counts.
+type SuccessFunc func(err error) bool
+
+// KeyByMethod returns the full method name of the call as the key, a circuit breaker per method.
+func KeyByMethod(ctx context.Context, method string) string {
+	return method
+}
+
+// Successful reports a call failed if the code of err is one of a server side failure,
+// as Unknown, DeadlineExceeded, ResourceExhausted, Internal, Unavailable or DataLoss.
+// Client side failures, as InvalidArgument or NotFound, are not counted as failures.
+func Successful(err error) bool {
+	switch status.Code(err) {
+	case codes.Unknown, codes.DeadlineExceeded, codes.ResourceExhausted,
+		codes.Internal, codes.Unavailable, codes.DataLoss:
+		return false
+	}
+	return true
+}
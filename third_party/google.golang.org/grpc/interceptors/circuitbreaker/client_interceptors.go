@@ -0,0 +1,34 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package circuitbreaker
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor returns a new unary client interceptor failing fast by breaker with Unavailable,
+// keyed by key, KeyByMethod if nil, and counted by success, Successful if nil.
+func UnaryClientInterceptor(breaker Breaker, key KeyFunc, success SuccessFunc) grpc.UnaryClientInterceptor {
+	if key == nil {
+		key = KeyByMethod
+	}
+	if success == nil {
+		success = Successful
+	}
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		done, err := breaker.Allow(key(ctx, method))
+		if err != nil {
+			return status.Errorf(codes.Unavailable,
+				"%s is rejected by circuitbreaker unary client middleware, please retry later: %s", method, err)
+		}
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		done(success(err))
+		return err
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package circuitbreaker_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/searKing/golang/go/sync/breaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/searKing/golang/third_party/google.golang.org/grpc/interceptors/circuitbreaker"
+)
+
+var _ circuitbreaker.Breaker = (*breaker.Group)(nil)
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	g := breaker.NewGroup(breaker.WithReadyToTrip(breaker.ReadyToTripByConsecutiveFailures(3)))
+	interceptor := circuitbreaker.UnaryClientInterceptor(g, nil, nil)
+
+	var invoked int
+	unavailable := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		invoked++
+		return status.Error(codes.Unavailable, "unavailable")
+	}
+	const method = "/test.Service/Method"
+	for i := 0; i < 3; i++ {
+		if err := interceptor(context.Background(), method, nil, nil, nil, unavailable); status.Code(err) != codes.Unavailable {
+			t.Fatalf("#%d: got %v, want code %s", i, err, codes.Unavailable)
+		}
+	}
+	if invoked != 3 {
+		t.Fatalf("invoked %d times, want %d", invoked, 3)
+	}
+
+	// tripped, fail fast without invoking
+	err := interceptor(context.Background(), method, nil, nil, nil, unavailable)
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("tripped, got %v, want code %s", err, codes.Unavailable)
+	}
+	if invoked != 3 {
+		t.Errorf("invoked %d times after tripped, want %d", invoked, 3)
+	}
+	if got := g.Get(method).State(); got != breaker.StateOpen {
+		t.Errorf("state %s, want %s", got, breaker.StateOpen)
+	}
+
+	// breakers are keyed by method
+	ok := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return nil
+	}
+	if err := interceptor(context.Background(), "/test.Service/Other", nil, nil, nil, ok); err != nil {
+		t.Errorf("other method, got %v, want nil", err)
+	}
+}
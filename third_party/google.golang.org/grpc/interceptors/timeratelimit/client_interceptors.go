@@ -15,7 +15,11 @@ import (
 
 // UnaryClientInterceptor returns a new unary client interceptor that performs request rate limiting.
 func UnaryClientInterceptor(r rate.Limit, b int) grpc.UnaryClientInterceptor {
-	limiter := rate.NewLimiter(r, b)
+	return UnaryClientInterceptorWithLimiter(rate.NewLimiter(r, b))
+}
+
+// UnaryClientInterceptorWithLimiter returns a new unary client interceptor that performs request rate limiting by limiter.
+func UnaryClientInterceptorWithLimiter(limiter Limiter) grpc.UnaryClientInterceptor {
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
 		if limiter.Allow() {
 			return invoker(ctx, method, req, reply, cc, opts...)
@@ -27,7 +31,11 @@ func UnaryClientInterceptor(r rate.Limit, b int) grpc.UnaryClientInterceptor {
 
 // StreamClientInterceptor returns a new streaming client interceptor that performs rate limiting on the request.
 func StreamClientInterceptor(r rate.Limit, b int) grpc.StreamClientInterceptor {
-	limiter := rate.NewLimiter(r, b)
+	return StreamClientInterceptorWithLimiter(rate.NewLimiter(r, b))
+}
+
+// StreamClientInterceptorWithLimiter returns a new streaming client interceptor that performs rate limiting on the request by limiter.
+func StreamClientInterceptorWithLimiter(limiter Limiter) grpc.StreamClientInterceptor {
 	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
 		if limiter.Allow() {
 			return streamer(ctx, desc, cc, method, opts...)
@@ -0,0 +1,12 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package timeratelimit
+
+// Limiter is what the interceptors require of *rate.Limiter of golang.org/x/time/rate,
+// or of a Limiter of github.com/searKing/golang/go/time/rate: Allow is called before the request.
+type Limiter interface {
+	// Allow reports whether an event may happen now.
+	Allow() bool
+}
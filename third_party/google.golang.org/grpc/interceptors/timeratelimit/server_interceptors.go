@@ -15,7 +15,11 @@ import (
 
 // UnaryServerInterceptor returns a new unary server interceptors that performs request rate limiting.
 func UnaryServerInterceptor(r rate.Limit, b int) grpc.UnaryServerInterceptor {
-	limiter := rate.NewLimiter(r, b)
+	return UnaryServerInterceptorWithLimiter(rate.NewLimiter(r, b))
+}
+
+// UnaryServerInterceptorWithLimiter returns a new unary server interceptors that performs request rate limiting by limiter.
+func UnaryServerInterceptorWithLimiter(limiter Limiter) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		if limiter.Allow() {
 			return handler(ctx, req)
@@ -27,7 +31,11 @@ func UnaryServerInterceptor(r rate.Limit, b int) grpc.UnaryServerInterceptor {
 
 // StreamServerInterceptor returns a new streaming server interceptor that performs rate limiting on the request.
 func StreamServerInterceptor(r rate.Limit, b int) grpc.StreamServerInterceptor {
-	limiter := rate.NewLimiter(r, b)
+	return StreamServerInterceptorWithLimiter(rate.NewLimiter(r, b))
+}
+
+// StreamServerInterceptorWithLimiter returns a new streaming server interceptor that performs rate limiting on the request by limiter.
+func StreamServerInterceptorWithLimiter(limiter Limiter) grpc.StreamServerInterceptor {
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		if limiter.Allow() {
 			return handler(srv, stream)
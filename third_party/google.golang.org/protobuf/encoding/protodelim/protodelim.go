@@ -0,0 +1,160 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package protodelim marshals and unmarshals size-delimited protobuf messages,
+// each message being prefixed with its size as a uvarint, as Java's
+// writeDelimitedTo and parseDelimitedFrom do.
+//
+// The framing is the one of github.com/searKing/golang/go/net/tcp's UvarintCodec, so
+// messages can be exchanged over a tcp Server either with MarshalTo and UnmarshalFrom
+// on the raw stream, or with UvarintCodec splitting frames and OnMsgRead and Write
+// handling one message per frame.
+package protodelim
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrMessageTooLarge is returned when a message exceeds the max size.
+var ErrMessageTooLarge = errors.New("protodelim: message too large")
+
+// Reader is the interface expected by UnmarshalFrom, as implemented by *bufio.Reader.
+type Reader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// maxPooledBufSize bounds buffers kept in bufPool, so that a rare huge message
+// does not pin its memory.
+const maxPooledBufSize = 64 << 10
+
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 4<<10)
+		return &b
+	},
+}
+
+func marshal(w io.Writer, m proto.Message, delimited bool) (int, error) {
+	bufp := bufPool.Get().(*[]byte)
+	buf := (*bufp)[:0]
+	if delimited {
+		var size [binary.MaxVarintLen64]byte
+		buf = append(buf, size[:binary.PutUvarint(size[:], uint64(proto.Size(m)))]...)
+	}
+	// the size computed above is reused
+	buf, err := proto.MarshalOptions{UseCachedSize: delimited}.MarshalAppend(buf, m)
+	if err == nil {
+		var n int
+		n, err = w.Write(buf)
+		if err == nil && n != len(buf) {
+			err = io.ErrShortWrite
+		}
+	}
+	n := len(buf)
+	if cap(buf) <= maxPooledBufSize {
+		*bufp = buf
+		bufPool.Put(bufp)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// MarshalTo writes m to w prefixed by its size, with a single Write.
+// It returns the number of bytes written.
+func MarshalTo(w io.Writer, m proto.Message) (int, error) {
+	return marshal(w, m, true)
+}
+
+// Write writes m to w without any size prefix, with a single Write, for w
+// framing every Write on its own, such as the Writer given to OnMsgHandle by
+// a tcp frame Handler.
+func Write(w io.Writer, m proto.Message) error {
+	_, err := marshal(w, m, false)
+	return err
+}
+
+// UnmarshalFrom reads a size-prefixed message from r into m.
+// Messages larger than maxSize bytes are rejected with ErrMessageTooLarge,
+// unless maxSize is non-positive, in which case the buffer grows as the message arrives.
+// It returns io.EOF if r is at the end of stream before a message.
+func UnmarshalFrom(r Reader, m proto.Message, maxSize int) error {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		if err == io.EOF {
+			return err
+		}
+		return fmt.Errorf("protodelim: read message size: %w", err)
+	}
+	if maxSize > 0 && size > uint64(maxSize) {
+		return ErrMessageTooLarge
+	}
+
+	var b []byte
+	if br, ok := r.(*bufio.Reader); ok && size <= uint64(br.Size()) {
+		// zero-copy out of br's buffer
+		if b, err = br.Peek(int(size)); err == nil {
+			_, _ = br.Discard(int(size))
+		}
+	} else {
+		b, err = readFull(r, size)
+	}
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return proto.Unmarshal(b, m)
+}
+
+// readFull reads n bytes from r, growing the buffer as data arrives rather than trusting n upfront,
+// so that a bogus size from the peer does not allocate more than what is actually sent.
+func readFull(r io.Reader, n uint64) ([]byte, error) {
+	var buf []byte
+	for uint64(len(buf)) < n {
+		if len(buf) == cap(buf) {
+			buf = append(buf, 0)[:len(buf)]
+		}
+		m := cap(buf)
+		if uint64(m) > n {
+			m = int(n)
+		}
+		k, err := io.ReadFull(r, buf[len(buf):m])
+		buf = buf[:len(buf)+k]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// NewMsgReadHandlerFunc returns a func unmarshaling the whole of r into a message
+// made by newMessage, to be used as an OnMsgReadHandlerFunc of a tcp frame Handler,
+// handing one frame per call.
+// Frames exposing their payload with Bytes, as tcp Frame does, are not copied.
+func NewMsgReadHandlerFunc(newMessage func() proto.Message) func(r io.Reader) (msg interface{}, err error) {
+	return func(r io.Reader) (msg interface{}, err error) {
+		var b []byte
+		if f, ok := r.(interface{ Bytes() []byte }); ok {
+			b = f.Bytes()
+		} else if b, err = io.ReadAll(r); err != nil {
+			return nil, err
+		}
+		m := newMessage()
+		if err := proto.Unmarshal(b, m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 The searKing Author. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package protodelim_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/searKing/golang/third_party/google.golang.org/protobuf/encoding/protodelim"
+)
+
+func TestMarshalToUnmarshalFrom(t *testing.T) {
+	var buf bytes.Buffer
+	msgs := []string{"hello", "", strings.Repeat("x", 5000)}
+	for _, s := range msgs {
+		if _, err := protodelim.MarshalTo(&buf, wrapperspb.String(s)); err != nil {
+			t.Fatalf("MarshalTo: %v", err)
+		}
+	}
+
+	r := bufio.NewReader(&buf)
+	for _, s := range msgs {
+		var got wrapperspb.StringValue
+		if err := protodelim.UnmarshalFrom(r, &got, 0); err != nil {
+			t.Fatalf("UnmarshalFrom: %v", err)
+		}
+		if got.GetValue() != s {
+			t.Errorf("UnmarshalFrom = %.10q, want %.10q", got.GetValue(), s)
+		}
+	}
+	if err := protodelim.UnmarshalFrom(r, &wrapperspb.StringValue{}, 0); err != io.EOF {
+		t.Errorf("UnmarshalFrom at end of stream = %v, want io.EOF", err)
+	}
+}
+
+func TestUnmarshalFromTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := protodelim.MarshalTo(&buf, wrapperspb.String(strings.Repeat("x", 100))); err != nil {
+		t.Fatalf("MarshalTo: %v", err)
+	}
+	err := protodelim.UnmarshalFrom(bufio.NewReader(&buf), &wrapperspb.StringValue{}, 10)
+	if !errors.Is(err, protodelim.ErrMessageTooLarge) {
+		t.Errorf("UnmarshalFrom = %v, want ErrMessageTooLarge", err)
+	}
+}
+
+func TestUnmarshalFromUnbuffered(t *testing.T) {
+	// bytes.Buffer is a Reader, but not a *bufio.Reader
+	var buf bytes.Buffer
+	want := strings.Repeat("x", 100<<10)
+	if _, err := protodelim.MarshalTo(&buf, wrapperspb.String(want)); err != nil {
+		t.Fatalf("MarshalTo: %v", err)
+	}
+	var got wrapperspb.StringValue
+	if err := protodelim.UnmarshalFrom(&buf, &got, 0); err != nil {
+		t.Fatalf("UnmarshalFrom: %v", err)
+	}
+	if got.GetValue() != want {
+		t.Errorf("UnmarshalFrom got %d bytes, want %d bytes", len(got.GetValue()), len(want))
+	}
+
+	// a bogus size is not trusted upfront
+	var size [binary.MaxVarintLen64]byte
+	buf.Reset()
+	buf.Write(size[:binary.PutUvarint(size[:], 1<<50)])
+	buf.WriteString("short")
+	if err := protodelim.UnmarshalFrom(&buf, &got, 0); err != io.ErrUnexpectedEOF {
+		t.Errorf("UnmarshalFrom with a bogus size = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestNewMsgReadHandlerFunc(t *testing.T) {
+	var buf bytes.Buffer
+	if err := protodelim.Write(&buf, wrapperspb.String("frame")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	read := protodelim.NewMsgReadHandlerFunc(func() proto.Message { return &wrapperspb.StringValue{} })
+	msg, err := read(&buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if got := msg.(*wrapperspb.StringValue).GetValue(); got != "frame" {
+		t.Errorf("read = %q, want %q", got, "frame")
+	}
+}